@@ -50,6 +50,16 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 	return NewClient(c), nil
 }
 
+// DialOptions connects a client to the given URL, configuring the underlying RPC
+// client with the given options (e.g. authentication).
+func DialOptions(ctx context.Context, rawurl string, opts ...rpc.ClientOption) (*Client, error) {
+	c, err := rpc.DialOptions(ctx, rawurl, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
 // NewClient creates a client that uses the given RPC client.
 func NewClient(c *rpc.Client) *Client {
 	return &Client{c}
@@ -285,6 +295,16 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// BlockReceipts returns the receipts of all the transactions in the given block.
+func (ec *Client) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var r []*types.Receipt
+	err := ec.c.CallContext(ctx, &r, "ccm_getBlockReceipts", blockNrOrHash)
+	if err == nil && r == nil {
+		return nil, ccmchain.NotFound
+	}
+	return r, err
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -293,6 +313,12 @@ func toBlockNumArg(number *big.Int) string {
 	if number.Cmp(pending) == 0 {
 		return "pending"
 	}
+	if number.Cmp(big.NewInt(int64(rpc.SafeBlockNumber))) == 0 {
+		return "safe"
+	}
+	if number.Cmp(big.NewInt(int64(rpc.FinalizedBlockNumber))) == 0 {
+		return "finalized"
+	}
 	return hexutil.EncodeBig(number)
 }
 
@@ -528,6 +554,17 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	return ec.c.CallContext(ctx, nil, "ccm_sendRawTransaction", hexutil.Encode(data))
 }
 
+// SendPrivateTransaction injects a signed transaction into the private pool of
+// the node, withholding it from the network until the node mines it, or until it
+// expires and gets published.
+func (ec *Client) SendPrivateTransaction(ctx context.Context, tx *types.Transaction) error {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	return ec.c.CallContext(ctx, nil, "ccm_sendPrivateTransaction", hexutil.Encode(data))
+}
+
 func toCallArg(msg ccmchain.CallMsg) interface{} {
 	arg := map[string]interface{}{
 		"from": msg.From,
@@ -27,6 +27,7 @@ import (
 
 	"github.com/ccm-chain/ccmchain"
 	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/common/hexutil"
 	"github.com/ccm-chain/ccmchain/consensus/ethash"
 	"github.com/ccm-chain/ccmchain/core"
 	"github.com/ccm-chain/ccmchain/core/rawdb"
@@ -35,6 +36,7 @@ import (
 	"github.com/ccm-chain/ccmchain/node"
 	"github.com/ccm-chain/ccmchain/params"
 	"github.com/ccm-chain/ccmchain/protocol"
+	"github.com/ccm-chain/ccmchain/rpc"
 )
 
 // Verify that Client implements the ethereum interfaces.
@@ -187,6 +189,10 @@ var (
 func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 	// Generate test chain.
 	genesis, blocks := generateTestChain()
+	return newTestBackendWithChain(t, genesis, blocks)
+}
+
+func newTestBackendWithChain(t *testing.T, genesis *core.Genesis, blocks []*types.Block) (*node.Node, []*types.Block) {
 	// Create node
 	n, err := node.New(&node.Config{})
 	if err != nil {
@@ -368,3 +374,246 @@ func TestBlockNumber(t *testing.T) {
 		t.Fatalf("BlockNumber returned wrong number: %d", blockNumber)
 	}
 }
+
+func TestBlockReceipts(t *testing.T) {
+	// Generate a chain with a couple of transfers in each block
+	db := rawdb.NewMemoryDatabase()
+	config := params.AllEthashProtocolChanges
+	genesis := &core.Genesis{
+		Config: config,
+		Alloc:  core.GenesisAlloc{testAddr: {Balance: testBalance}},
+	}
+	signer := types.NewEIP155Signer(config.ChainID)
+	generate := func(i int, g *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, err := types.SignTx(types.NewTransaction(g.TxNonce(testAddr), common.Address{byte(j)}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, testKey)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			g.AddTx(tx)
+		}
+	}
+	gblock := genesis.ToBlock(db)
+	blocks, _ := core.GenerateChain(config, gblock, ethash.NewFaker(), db, 2, generate)
+	blocks = append([]*types.Block{gblock}, blocks...)
+
+	backend, chain := newTestBackendWithChain(t, genesis, blocks)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+	ec := NewClient(client)
+
+	tests := map[string]rpc.BlockNumberOrHash{
+		"number": rpc.BlockNumberOrHashWithNumber(1),
+		"hash":   rpc.BlockNumberOrHashWithHash(chain[2].Hash(), true),
+		"latest": rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber),
+	}
+	for name, blockNrOrHash := range tests {
+		t.Run(name, func(t *testing.T) {
+			receipts, err := ec.BlockReceipts(context.Background(), blockNrOrHash)
+			if err != nil {
+				t.Fatalf("BlockReceipts failed: %v", err)
+			}
+			block := chain[1]
+			if name != "number" {
+				block = chain[2]
+			}
+			if len(receipts) != len(block.Transactions()) {
+				t.Fatalf("receipt count mismatch: have %d, want %d", len(receipts), len(block.Transactions()))
+			}
+			for i, receipt := range receipts {
+				want, err := ec.TransactionReceipt(context.Background(), block.Transactions()[i].Hash())
+				if err != nil {
+					t.Fatalf("TransactionReceipt failed: %v", err)
+				}
+				if !reflect.DeepEqual(receipt, want) {
+					t.Errorf("receipt %d mismatch:\nhave %+v\nwant %+v", i, receipt, want)
+				}
+				if receipt.BlockHash != block.Hash() || receipt.TxHash != block.Transactions()[i].Hash() || receipt.TransactionIndex != uint(i) {
+					t.Errorf("receipt %d derived fields mismatch: %+v", i, receipt)
+				}
+			}
+		})
+	}
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(1000)); err != ccmchain.NotFound {
+		t.Fatalf("unknown block error mismatch: have %v, want %v", err, ccmchain.NotFound)
+	}
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(common.Hash{0x01}, false)); err == nil || err == ccmchain.NotFound {
+		t.Fatalf("block resolution error not reported: %v", err)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	backend, chain := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	// The counter increments its storage slot, logs and returns the new count
+	var (
+		counter  = common.HexToAddress("0xc0ffee")
+		reverter = common.HexToAddress("0xdead")
+		code     = map[string]interface{}{
+			counter.Hex():  map[string]interface{}{"code": "0x60005460010180600055600052600060006000a060206000f3"},
+			reverter.Hex(): map[string]interface{}{"code": "0x60006000fd"},
+		}
+		call = map[string]interface{}{"from": testAddr, "to": counter}
+	)
+	type simCall struct {
+		ReturnData hexutil.Bytes  `json:"returnData"`
+		Logs       []*types.Log   `json:"logs"`
+		GasUsed    hexutil.Uint64 `json:"gasUsed"`
+		Status     hexutil.Uint64 `json:"status"`
+		Error      string         `json:"error"`
+	}
+	type simBlock struct {
+		Number  *hexutil.Big   `json:"number"`
+		Time    hexutil.Uint64 `json:"timestamp"`
+		GasUsed hexutil.Uint64 `json:"gasUsed"`
+		Calls   []simCall      `json:"calls"`
+	}
+	var result []simBlock
+	err := client.Call(&result, "ccm_simulate", map[string]interface{}{
+		"blocks": []interface{}{
+			map[string]interface{}{
+				"stateOverrides": code,
+				"calls":          []interface{}{call, call, map[string]interface{}{"from": testAddr, "to": reverter}},
+			},
+			map[string]interface{}{
+				"blockOverrides": map[string]interface{}{"time": hexutil.Uint64(chain[1].Time() + 100)},
+				"calls":          []interface{}{call},
+			},
+		},
+	}, "latest")
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("block count mismatch: have %d, want 2", len(result))
+	}
+	// Check the headers derived from the base block and the overrides
+	if have, want := result[0].Number.ToInt().Uint64(), chain[1].NumberU64()+1; have != want {
+		t.Errorf("block 0 number mismatch: have %d, want %d", have, want)
+	}
+	if have, want := uint64(result[0].Time), chain[1].Time()+1; have != want {
+		t.Errorf("block 0 timestamp mismatch: have %d, want %d", have, want)
+	}
+	if have, want := uint64(result[1].Time), chain[1].Time()+100; have != want {
+		t.Errorf("block 1 timestamp mismatch: have %d, want %d", have, want)
+	}
+	// Check the calls executed cumulatively, with the reverting one not aborting
+	counts := []struct{ block, call int }{{0, 0}, {0, 1}, {1, 0}}
+	for i, c := range counts {
+		res := result[c.block].Calls[c.call]
+		if res.Status != 1 || res.Error != "" {
+			t.Errorf("block %d call %d failed: %s", c.block, c.call, res.Error)
+		}
+		if have := new(big.Int).SetBytes(res.ReturnData).Int64(); have != int64(i+1) {
+			t.Errorf("block %d call %d count mismatch: have %d, want %d", c.block, c.call, have, i+1)
+		}
+		if len(res.Logs) != 1 || res.Logs[0].Address != counter || res.Logs[0].Index != uint(c.call) || res.Logs[0].BlockNumber != result[c.block].Number.ToInt().Uint64() {
+			t.Errorf("block %d call %d logs mismatch: %+v", c.block, c.call, res.Logs)
+		}
+	}
+	if res := result[0].Calls[2]; res.Status != 0 || res.Error != "execution reverted" || len(res.Logs) != 0 {
+		t.Errorf("reverting call mismatch: %+v", res)
+	}
+	if have, want := result[0].GasUsed, result[0].Calls[0].GasUsed+result[0].Calls[1].GasUsed+result[0].Calls[2].GasUsed; have != want {
+		t.Errorf("block 0 gas used mismatch: have %d, want %d", have, want)
+	}
+	// Check that invalid simulations are rejected
+	invalid := map[string]map[string]interface{}{
+		"timestamp": {
+			"blocks": []interface{}{map[string]interface{}{
+				"blockOverrides": map[string]interface{}{"time": hexutil.Uint64(chain[1].Time())},
+			}},
+		},
+		"nonce": {
+			"validation": true,
+			"blocks": []interface{}{map[string]interface{}{
+				"calls": []interface{}{map[string]interface{}{"from": testAddr, "to": counter, "gas": hexutil.Uint64(params.TxGas), "nonce": hexutil.Uint64(5)}},
+			}},
+		},
+	}
+	for name, opts := range invalid {
+		if err := client.Call(&result, "ccm_simulate", opts, "latest"); err == nil {
+			t.Errorf("%s: invalid simulation succeeded", name)
+		}
+	}
+}
+
+func TestStateAccess(t *testing.T) {
+	// The counter increments its storage slot and returns the new count
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		counter = common.HexToAddress("0xc0ffee")
+		gassy   = common.HexToAddress("0x9a55") // Reads slot 1 if given over 1M gas
+		genesis = &core.Genesis{
+			Config: params.AllEthashProtocolChanges,
+			Alloc: core.GenesisAlloc{
+				testAddr: {Balance: testBalance},
+				counter:  {Code: common.FromHex("0x60005460010180600055600052602060006000f3"), Balance: new(big.Int)},
+				gassy:    {Code: common.FromHex("0x5a620f424010600a57005b60015450"), Balance: new(big.Int)},
+			},
+		}
+		gblock    = genesis.ToBlock(db)
+		blocks, _ = core.GenerateChain(genesis.Config, gblock, ethash.NewFaker(), db, 1, func(int, *core.BlockGen) {})
+	)
+	backend, _ := newTestBackendWithChain(t, genesis, append([]*types.Block{gblock}, blocks...))
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	type accessEntry struct {
+		Address     common.Address `json:"address"`
+		Code        bool           `json:"code"`
+		StorageKeys []common.Hash  `json:"storageKeys"`
+	}
+	type accessResult struct {
+		Reads      []accessEntry  `json:"reads"`
+		Writes     []accessEntry  `json:"writes"`
+		GasUsed    hexutil.Uint64 `json:"gasUsed"`
+		Iterations hexutil.Uint64 `json:"iterations"`
+		Stable     bool           `json:"stable"`
+		Error      string         `json:"error"`
+	}
+	var (
+		call   = map[string]interface{}{"from": testAddr, "to": counter}
+		reads  = []accessEntry{{Address: counter, Code: true, StorageKeys: []common.Hash{{}}}}
+		writes = []accessEntry{{Address: counter, StorageKeys: []common.Hash{{}}}, {Address: testAddr, StorageKeys: []common.Hash{}}} // Sorted by address
+	)
+	for _, iterate := range []bool{false, true} {
+		var result accessResult
+		if err := client.Call(&result, "ccm_getStateAccess", call, "latest", iterate); err != nil {
+			t.Fatalf("iterate %v: state access failed: %v", iterate, err)
+		}
+		if !reflect.DeepEqual(result.Reads, reads) {
+			t.Errorf("iterate %v: reads mismatch: have %+v, want %+v", iterate, result.Reads, reads)
+		}
+		if !reflect.DeepEqual(result.Writes, writes) {
+			t.Errorf("iterate %v: writes mismatch: have %+v, want %+v", iterate, result.Writes, writes)
+		}
+		if result.GasUsed <= hexutil.Uint64(params.TxGas) || result.Error != "" {
+			t.Errorf("iterate %v: unexpected outcome: gas used %d, error %q", iterate, result.GasUsed, result.Error)
+		}
+		// Iterating executes the call again with its estimated gas limit
+		if want := map[bool]hexutil.Uint64{false: 1, true: 2}[iterate]; result.Iterations != want {
+			t.Errorf("iterate %v: iterations mismatch: have %d, want %d", iterate, result.Iterations, want)
+		}
+		if !result.Stable {
+			t.Errorf("iterate %v: access of counter reported unstable", iterate)
+		}
+	}
+	// The access of a call depending on its gas is reported from the execution
+	// with the estimated gas limit, and flagged unstable
+	var result accessResult
+	if err := client.Call(&result, "ccm_getStateAccess", map[string]interface{}{"from": testAddr, "to": gassy}, "latest", true); err != nil {
+		t.Fatalf("state access failed: %v", err)
+	}
+	if want := []accessEntry{{Address: gassy, Code: true, StorageKeys: []common.Hash{}}}; !reflect.DeepEqual(result.Reads, want) {
+		t.Errorf("reads mismatch: have %+v, want %+v", result.Reads, want)
+	}
+	if result.Iterations != 2 || result.Stable {
+		t.Errorf("outcome mismatch: iterations %d, stable %v", result.Iterations, result.Stable)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/ccm-chain/ccmchain/cmd/utils"
+	"github.com/ccm-chain/ccmchain/consensus/clique"
+	"github.com/ccm-chain/ccmchain/consensus/transition"
+	"github.com/ccm-chain/ccmchain/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	cliqueCommand = cli.Command{
+		Name:      "clique",
+		Usage:     "Manage the clique proof-of-authority database",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+    gccm clique rebuild-snapshots
+
+deletes all the persisted clique vote snapshots and regenerates them from the
+checkpoint headers of the canonical chain.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "rebuild-snapshots",
+				Usage:     "Delete and regenerate all clique vote snapshots",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(rebuildCliqueSnapshots),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+				},
+				Description: `
+    gccm clique rebuild-snapshots
+
+deletes all the persisted clique vote snapshots, including stale ones left
+behind on side chains, and regenerates the snapshot of every checkpoint of
+the canonical chain. The node must not be running.`,
+			},
+		},
+	}
+)
+
+// rebuildCliqueSnapshots deletes all persisted clique snapshots and regenerates
+// them from the checkpoints of the local canonical chain.
+func rebuildCliqueSnapshots(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack, false)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	var engine *clique.Clique
+	switch e := chain.Engine().(type) {
+	case *clique.Clique:
+		engine = e
+	case *transition.Engine:
+		if _, after := e.Engines(); after != nil {
+			engine, _ = after.(*clique.Clique)
+		}
+	}
+	if engine == nil {
+		utils.Fatalf("Chain is not running the clique consensus engine")
+	}
+	deleted, err := clique.DeleteSnapshots(chainDb)
+	if err != nil {
+		utils.Fatalf("Failed to delete voting snapshots: %v", err)
+	}
+	log.Info("Deleted voting snapshots", "count", deleted)
+
+	if _, err := engine.RebuildSnapshots(chain, chain.CurrentHeader()); err != nil {
+		utils.Fatalf("Failed to rebuild voting snapshots: %v", err)
+	}
+	return nil
+}
@@ -75,10 +75,18 @@ var (
 		utils.EthashDatasetsInMemoryFlag,
 		utils.EthashDatasetsOnDiskFlag,
 		utils.EthashDatasetsLockMmapFlag,
+		utils.EthashSafeDepthFlag,
+		utils.EthashFinalizedDepthFlag,
+		utils.CliqueSafeSignersFlag,
+		utils.CliqueFinalizedSignersFlag,
+		utils.CliqueSignerFlag,
+		utils.CliqueSnapshotRetentionFlag,
 		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolSnapshotFlag,
+		utils.TxPoolResnapshotFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -86,6 +94,10 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrivateLifetimeFlag,
+		utils.TxPoolPeerRateLimitFlag,
+		utils.TxPoolSenderRateLimitFlag,
+		utils.TxPoolClientRateLimitFlag,
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
@@ -109,6 +121,7 @@ var (
 		utils.CacheGCFlag,
 		utils.CacheSnapshotFlag,
 		utils.CacheNoPrefetchFlag,
+		utils.ParallelProcessingFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
@@ -122,6 +135,9 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerOrderingFlag,
+		utils.MinerSenderCapFlag,
+		utils.MinerPriorityFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -154,12 +170,26 @@ var (
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GraphQLMaxDepthFlag,
+		utils.GraphQLMaxComplexityFlag,
+		utils.GraphQLMaxResultSizeFlag,
+		utils.GraphQLTimeoutFlag,
 		utils.HTTPApiFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.HTTPJWTAuthFlag,
+		utils.WSJWTAuthFlag,
+		utils.JWTSecretFlag,
+		utils.JWTClockSkewFlag,
+		utils.RPCPolicyFlag,
+		utils.RPCBatchItemLimitFlag,
+		utils.RPCBatchResponseSizeLimitFlag,
+		utils.RPCCallTimeoutFlag,
+		utils.RPCRequestSizeLimitFlag,
+		utils.RPCResponseCacheFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
@@ -198,6 +228,8 @@ func init() {
 		dumpCommand,
 		dumpGenesisCommand,
 		inspectCommand,
+		// See cliquecmd.go:
+		cliqueCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -82,6 +82,17 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.EthashDatasetsInMemoryFlag,
 			utils.EthashDatasetsOnDiskFlag,
 			utils.EthashDatasetsLockMmapFlag,
+			utils.EthashSafeDepthFlag,
+			utils.EthashFinalizedDepthFlag,
+		},
+	},
+	{
+		Name: "CLIQUE",
+		Flags: []cli.Flag{
+			utils.CliqueSafeSignersFlag,
+			utils.CliqueFinalizedSignersFlag,
+			utils.CliqueSignerFlag,
+			utils.CliqueSnapshotRetentionFlag,
 		},
 	},
 	{
@@ -91,6 +102,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolSnapshotFlag,
+			utils.TxPoolResnapshotFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -98,6 +111,10 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolPrivateLifetimeFlag,
+			utils.TxPoolPeerRateLimitFlag,
+			utils.TxPoolSenderRateLimitFlag,
+			utils.TxPoolClientRateLimitFlag,
 		},
 	},
 	{
@@ -111,6 +128,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.CacheGCFlag,
 			utils.CacheSnapshotFlag,
 			utils.CacheNoPrefetchFlag,
+			utils.ParallelProcessingFlag,
 		},
 	},
 	{
@@ -138,9 +156,23 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.HTTPJWTAuthFlag,
+			utils.WSJWTAuthFlag,
+			utils.JWTSecretFlag,
+			utils.JWTClockSkewFlag,
+			utils.RPCPolicyFlag,
+			utils.RPCBatchItemLimitFlag,
+			utils.RPCBatchResponseSizeLimitFlag,
+			utils.RPCCallTimeoutFlag,
+			utils.RPCRequestSizeLimitFlag,
+			utils.RPCResponseCacheFlag,
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
+			utils.GraphQLMaxDepthFlag,
+			utils.GraphQLMaxComplexityFlag,
+			utils.GraphQLMaxResultSizeFlag,
+			utils.GraphQLTimeoutFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
 			utils.JSpathFlag,
@@ -177,6 +209,9 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerOrderingFlag,
+			utils.MinerSenderCapFlag,
+			utils.MinerPriorityFlag,
 		},
 	},
 	{
@@ -38,6 +38,7 @@ import (
 	"github.com/ccm-chain/ccmchain/consensus"
 	"github.com/ccm-chain/ccmchain/consensus/clique"
 	"github.com/ccm-chain/ccmchain/consensus/ethash"
+	"github.com/ccm-chain/ccmchain/consensus/transition"
 	"github.com/ccm-chain/ccmchain/core"
 	"github.com/ccm-chain/ccmchain/core/rawdb"
 	"github.com/ccm-chain/ccmchain/core/vm"
@@ -277,6 +278,31 @@ var (
 		Name:  "ethash.dagslockmmap",
 		Usage: "Lock memory maps for recent ethash mining DAGs",
 	}
+	EthashSafeDepthFlag = cli.Uint64Flag{
+		Name:  "ethash.safedepth",
+		Usage: "Number of blocks on top of a block before it is considered safe (0 = default)",
+	}
+	EthashFinalizedDepthFlag = cli.Uint64Flag{
+		Name:  "ethash.finalizeddepth",
+		Usage: "Number of blocks on top of a block before it is considered finalized (0 = default)",
+	}
+	// Clique settings
+	CliqueSafeSignersFlag = cli.IntFlag{
+		Name:  "clique.safesigners",
+		Usage: "Number of distinct signers sealing on top of a block before it is considered safe (0 = default)",
+	}
+	CliqueFinalizedSignersFlag = cli.IntFlag{
+		Name:  "clique.finalizedsigners",
+		Usage: "Number of distinct signers sealing on top of a block before it is considered finalized (0 = majority)",
+	}
+	CliqueSignerFlag = cli.StringFlag{
+		Name:  "clique.signer",
+		Usage: "External signer (url or path to ipc file) holding the clique sealing key",
+	}
+	CliqueSnapshotRetentionFlag = cli.Uint64Flag{
+		Name:  "clique.snapshotretention",
+		Usage: "Number of blocks below the latest checkpoint to retain vote snapshots for (0 = keep all)",
+	}
 	// Transaction pool settings
 	TxPoolLocalsFlag = cli.StringFlag{
 		Name:  "txpool.locals",
@@ -296,6 +322,16 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolSnapshotFlag = cli.StringFlag{
+		Name:  "txpool.snapshot",
+		Usage: "Disk snapshot of remote transactions to warm restart the pool with (empty = disabled)",
+		Value: core.DefaultTxPoolConfig.Snapshot,
+	}
+	TxPoolResnapshotFlag = cli.DurationFlag{
+		Name:  "txpool.resnapshot",
+		Usage: "Time interval to regenerate the remote transaction snapshot",
+		Value: core.DefaultTxPoolConfig.Resnapshot,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -331,6 +367,26 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: protocol.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolPrivateLifetimeFlag = cli.Uint64Flag{
+		Name:  "txpool.privatelifetime",
+		Usage: "Number of blocks private transactions are withheld before being published",
+		Value: protocol.DefaultConfig.TxPool.PrivateLifetime,
+	}
+	TxPoolPeerRateLimitFlag = cli.Float64Flag{
+		Name:  "txpool.peerratelimit",
+		Usage: "Maximum transactions per second accepted from a single peer (0 = unlimited)",
+		Value: protocol.DefaultConfig.TxPool.PeerRateLimit,
+	}
+	TxPoolSenderRateLimitFlag = cli.Float64Flag{
+		Name:  "txpool.senderratelimit",
+		Usage: "Maximum transactions per second accepted from a single remote sender account (0 = unlimited)",
+		Value: protocol.DefaultConfig.TxPool.SenderRateLimit,
+	}
+	TxPoolClientRateLimitFlag = cli.Float64Flag{
+		Name:  "txpool.clientratelimit",
+		Usage: "Maximum raw transactions per second accepted from a single RPC client IP (0 = unlimited)",
+		Value: protocol.DefaultConfig.TxPool.ClientRateLimit,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -371,6 +427,10 @@ var (
 		Name:  "cache.noprefetch",
 		Usage: "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
 	}
+	ParallelProcessingFlag = cli.IntFlag{
+		Name:  "processing.parallel",
+		Usage: "Number of threads executing block transactions optimistically in parallel during import (0 = sequential)",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -418,6 +478,19 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerOrderingFlag = cli.StringFlag{
+		Name:  "miner.ordering",
+		Usage: `Transaction ordering policy for mined blocks ("price" or "fifo")`,
+		Value: miner.OrderingPriceNonce,
+	}
+	MinerSenderCapFlag = cli.IntFlag{
+		Name:  "miner.sendercap",
+		Usage: "Maximum number of transactions per sender in a mined block (0 = unlimited)",
+	}
+	MinerPriorityFlag = cli.StringFlag{
+		Name:  "miner.priority",
+		Usage: "Comma separated contract addresses whose transactions are mined ahead of all others",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -517,6 +590,26 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GraphQLMaxDepthFlag = cli.IntFlag{
+		Name:  "graphql.maxdepth",
+		Usage: "Maximum nesting depth of GraphQL queries (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLMaxDepth,
+	}
+	GraphQLMaxComplexityFlag = cli.IntFlag{
+		Name:  "graphql.maxcomplexity",
+		Usage: "Maximum number of fields and list elements resolved by a GraphQL query (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLMaxComplexity,
+	}
+	GraphQLMaxResultSizeFlag = cli.IntFlag{
+		Name:  "graphql.maxresultsize",
+		Usage: "Maximum size in bytes of the result of a GraphQL query (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLMaxResultSize,
+	}
+	GraphQLTimeoutFlag = cli.DurationFlag{
+		Name:  "graphql.timeout",
+		Usage: "Maximum execution time of a GraphQL query (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLTimeout,
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -541,6 +634,52 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	HTTPJWTAuthFlag = cli.BoolFlag{
+		Name:  "http.jwtauth",
+		Usage: "Require JWT authentication for the HTTP-RPC server",
+	}
+	WSJWTAuthFlag = cli.BoolFlag{
+		Name:  "ws.jwtauth",
+		Usage: "Require JWT authentication for the WS-RPC server",
+	}
+	JWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex encoded 32 byte secret for JWT authentication (generated if missing, default = inside the datadir)",
+	}
+	JWTClockSkewFlag = cli.DurationFlag{
+		Name:  "rpc.jwtclockskew",
+		Usage: "Maximum difference tolerated between the issuance time of JWT tokens and the local clock",
+		Value: node.DefaultConfig.JWTClockSkew,
+	}
+	RPCPolicyFlag = cli.StringFlag{
+		Name:  "rpc.policy",
+		Usage: "Path to a JSON file restricting the methods callable over the RPC endpoints and their call rates",
+	}
+	RPCBatchItemLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchitemlimit",
+		Usage: "Maximum number of messages in a JSON-RPC batch (0 = no limit)",
+		Value: node.DefaultConfig.RPCBatchItemLimit,
+	}
+	RPCBatchResponseSizeLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchresponsesizelimit",
+		Usage: "Maximum total size in bytes of the results of a JSON-RPC batch (0 = no limit)",
+		Value: node.DefaultConfig.RPCBatchResponseSizeLimit,
+	}
+	RPCCallTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.calltimeout",
+		Usage: "Maximum time spent executing a JSON-RPC method call (0 = no limit)",
+		Value: node.DefaultConfig.RPCCallTimeout,
+	}
+	RPCRequestSizeLimitFlag = cli.IntFlag{
+		Name:  "rpc.requestsizelimit",
+		Usage: "Maximum size in bytes of JSON-RPC requests over HTTP and messages over WebSocket",
+		Value: node.DefaultConfig.RPCRequestSizeLimit,
+	}
+	RPCResponseCacheFlag = cli.IntFlag{
+		Name:  "rpc.cache",
+		Usage: "Megabytes of memory allocated to caching RPC responses which only change on chain reorgs (0 = disabled)",
+		Value: node.DefaultConfig.RPCResponseCache,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -845,6 +984,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(HTTPVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = SplitAndTrim(ctx.GlobalString(HTTPVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(HTTPJWTAuthFlag.Name) {
+		cfg.HTTPJWTAuth = ctx.GlobalBool(HTTPJWTAuthFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -856,6 +998,18 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(GraphQLMaxDepthFlag.Name) {
+		cfg.GraphQLMaxDepth = ctx.GlobalInt(GraphQLMaxDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxComplexityFlag.Name) {
+		cfg.GraphQLMaxComplexity = ctx.GlobalInt(GraphQLMaxComplexityFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxResultSizeFlag.Name) {
+		cfg.GraphQLMaxResultSize = ctx.GlobalInt(GraphQLMaxResultSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLTimeoutFlag.Name) {
+		cfg.GraphQLTimeout = ctx.GlobalDuration(GraphQLTimeoutFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -876,6 +1030,52 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = SplitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSJWTAuthFlag.Name) {
+		cfg.WSJWTAuth = ctx.GlobalBool(WSJWTAuthFlag.Name)
+	}
+}
+
+// setJWT configures the JWT authentication of the RPC endpoints from the set
+// command line flags.
+func setJWT(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(JWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(JWTClockSkewFlag.Name) {
+		cfg.JWTClockSkew = ctx.GlobalDuration(JWTClockSkewFlag.Name)
+	}
+}
+
+// setRPCPolicy configures the access policy of the RPC endpoints from the set
+// command line flags.
+func setRPCPolicy(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCPolicyFlag.Name) {
+		cfg.RPCPolicy = ctx.GlobalString(RPCPolicyFlag.Name)
+	}
+}
+
+// setRPCLimits configures the resource limits of the RPC endpoints from the set
+// command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchItemLimitFlag.Name) {
+		cfg.RPCBatchItemLimit = ctx.GlobalInt(RPCBatchItemLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseSizeLimitFlag.Name) {
+		cfg.RPCBatchResponseSizeLimit = ctx.GlobalInt(RPCBatchResponseSizeLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCallTimeoutFlag.Name) {
+		cfg.RPCCallTimeout = ctx.GlobalDuration(RPCCallTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRequestSizeLimitFlag.Name) {
+		cfg.RPCRequestSizeLimit = ctx.GlobalInt(RPCRequestSizeLimitFlag.Name)
+	}
+}
+
+// setRPCCache configures the RPC response cache from the set command line flags.
+func setRPCCache(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCResponseCacheFlag.Name) {
+		cfg.RPCResponseCache = ctx.GlobalInt(RPCResponseCacheFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1082,6 +1282,10 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
+	setJWT(ctx, cfg)
+	setRPCPolicy(ctx, cfg)
+	setRPCLimits(ctx, cfg)
+	setRPCCache(ctx, cfg)
 	setWS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
@@ -1174,6 +1378,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalString(TxPoolSnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolResnapshotFlag.Name) {
+		cfg.Resnapshot = ctx.GlobalDuration(TxPoolResnapshotFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1195,6 +1405,18 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPrivateLifetimeFlag.Name) {
+		cfg.PrivateLifetime = ctx.GlobalUint64(TxPoolPrivateLifetimeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPeerRateLimitFlag.Name) {
+		cfg.PeerRateLimit = ctx.GlobalFloat64(TxPoolPeerRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSenderRateLimitFlag.Name) {
+		cfg.SenderRateLimit = ctx.GlobalFloat64(TxPoolSenderRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolClientRateLimitFlag.Name) {
+		cfg.ClientRateLimit = ctx.GlobalFloat64(TxPoolClientRateLimitFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *protocol.Config) {
@@ -1222,6 +1444,27 @@ func setEthash(ctx *cli.Context, cfg *protocol.Config) {
 	if ctx.GlobalIsSet(EthashDatasetsLockMmapFlag.Name) {
 		cfg.Ethash.DatasetsLockMmap = ctx.GlobalBool(EthashDatasetsLockMmapFlag.Name)
 	}
+	if ctx.GlobalIsSet(EthashSafeDepthFlag.Name) {
+		cfg.Ethash.SafeDepth = ctx.GlobalUint64(EthashSafeDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(EthashFinalizedDepthFlag.Name) {
+		cfg.Ethash.FinalizedDepth = ctx.GlobalUint64(EthashFinalizedDepthFlag.Name)
+	}
+}
+
+func setClique(ctx *cli.Context, cfg *protocol.Config) {
+	if ctx.GlobalIsSet(CliqueSafeSignersFlag.Name) {
+		cfg.CliqueSafeSigners = ctx.GlobalInt(CliqueSafeSignersFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueFinalizedSignersFlag.Name) {
+		cfg.CliqueFinalizedSigners = ctx.GlobalInt(CliqueFinalizedSignersFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueSignerFlag.Name) {
+		cfg.CliqueSigner = ctx.GlobalString(CliqueSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueSnapshotRetentionFlag.Name) {
+		cfg.CliqueSnapshotRetention = ctx.GlobalUint64(CliqueSnapshotRetentionFlag.Name)
+	}
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1246,6 +1489,27 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerOrderingFlag.Name) {
+		switch ordering := ctx.GlobalString(MinerOrderingFlag.Name); ordering {
+		case miner.OrderingPriceNonce, miner.OrderingFIFO:
+			cfg.Ordering = ordering
+		default:
+			Fatalf("--%s must be either '%s' or '%s'", MinerOrderingFlag.Name, miner.OrderingPriceNonce, miner.OrderingFIFO)
+		}
+	}
+	if ctx.GlobalIsSet(MinerSenderCapFlag.Name) {
+		cfg.SenderCap = ctx.GlobalInt(MinerSenderCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPriorityFlag.Name) {
+		cfg.PriorityAddresses = nil
+		for _, account := range strings.Split(ctx.GlobalString(MinerPriorityFlag.Name), ",") {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid priority address %q", trimmed)
+			} else {
+				cfg.PriorityAddresses = append(cfg.PriorityAddresses, common.HexToAddress(trimmed))
+			}
+		}
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *protocol.Config) {
@@ -1331,6 +1595,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *protocol.Config) {
 	setGPO(ctx, &cfg.GPO, ctx.GlobalString(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
+	setClique(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
 	setWhitelist(ctx, cfg)
 	setLes(ctx, cfg)
@@ -1358,6 +1623,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *protocol.Config) {
 	if ctx.GlobalIsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.GlobalIsSet(ParallelProcessingFlag.Name) {
+		cfg.ParallelProcessing = ctx.GlobalInt(ParallelProcessingFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
@@ -1504,7 +1772,14 @@ func RegisterEthStatsService(stack *node.Node, backend api.Backend, url string)
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend api.Backend, cfg node.Config) {
-	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
+	_, lightMode := backend.(*les.LesApiBackend)
+	limits := graphql.Limits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+		MaxResultSize: cfg.GraphQLMaxResultSize,
+		Timeout:       cfg.GraphQLTimeout,
+	}
+	if err := graphql.New(stack, backend, lightMode, cfg.GraphQLCors, cfg.GraphQLVirtualHosts, limits); err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
 }
@@ -1602,7 +1877,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 		Fatalf("%v", err)
 	}
 	var engine consensus.Engine
-	if config.Clique != nil {
+	if config.IsClique(common.Big0) {
 		engine = clique.New(config.Clique, chainDb)
 	} else {
 		engine = ethash.NewFaker()
@@ -1618,6 +1893,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 				DatasetsLockMmap: protocol.DefaultConfig.Ethash.DatasetsLockMmap,
 			}, nil, false)
 		}
+		if config.Clique != nil {
+			engine = transition.New(engine, clique.New(config.Clique, chainDb), config.CliqueBlock.Uint64())
+		}
 	}
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1629,6 +1907,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 		TrieDirtyDisabled:   ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieTimeLimit:       protocol.DefaultConfig.TrieTimeout,
 		SnapshotLimit:       protocol.DefaultConfig.SnapshotCache,
+		ParallelProcessing:  ctx.GlobalInt(ParallelProcessingFlag.Name),
 	}
 	if !ctx.GlobalIsSet(SnapshotFlag.Name) {
 		cache.SnapshotLimit = 0 // Disabled
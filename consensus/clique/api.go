@@ -120,6 +120,13 @@ func (api *API) Discard(address common.Address) {
 	delete(api.clique.proposals, address)
 }
 
+// GetDoubleSignEvidence retrieves the evidence of signers sealing two distinct
+// blocks at the same height seen by this node, optionally filtered to a single
+// signer.
+func (api *API) GetDoubleSignEvidence(signer *common.Address) ([]*DoubleSignEvidence, error) {
+	return loadEvidence(api.clique.db, signer)
+}
+
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
@@ -20,6 +20,7 @@ package clique
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"math/rand"
@@ -48,8 +49,13 @@ const (
 	checkpointInterval = 1024 // Number of blocks after which to save the vote snapshot to the database
 	inmemorySnapshots  = 128  // Number of recent vote snapshots to keep in memory
 	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+	inmemorySeals      = 4096 // Number of recent (height, signer) seals to keep in memory for double sign detection
+
+	inmemoryConfirmations = 64 // Number of recent safe and finalized headers to keep in memory
 
 	wiggleTime = 500 * time.Millisecond // Random delay (per signer) to allow concurrent signers
+
+	defaultSafeSigners = 2 // Distinct signers sealing on top of a block before it is considered safe
 )
 
 // Clique proof-of-authority protocol constants.
@@ -179,12 +185,20 @@ type Clique struct {
 
 	recents    *lru.ARCCache // Snapshots for recent block to speed up reorgs
 	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
+	seals      *lru.ARCCache // Headers of recent seals by height and signer to detect double signing
+
+	confirmations *lru.ARCCache // Confirmed headers of recent heads by head hash and threshold
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
 
 	signer common.Address // Ethereum address of the signing key
 	signFn SignerFn       // Signer function to authorize hashes with
-	lock   sync.RWMutex   // Protects the signer fields
+	lock   sync.RWMutex   // Protects the signer, finality and retention fields
+
+	safeSigners      int // Distinct signers needed on top of a block to consider it safe (0 = default)
+	finalizedSigners int // Distinct signers needed on top of a block to consider it finalized (0 = majority)
+
+	retention uint64 // Depth below the latest checkpoint beyond which to prune snapshots (0 = keep all)
 
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
@@ -201,13 +215,17 @@ func New(config *params.CliqueConfig, db database.Database) *Clique {
 	// Allocate the snapshot caches and create the engine
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
+	seals, _ := lru.NewARC(inmemorySeals)
+	confirmations, _ := lru.NewARC(inmemoryConfirmations)
 
 	return &Clique{
-		config:     &conf,
-		db:         db,
-		recents:    recents,
-		signatures: signatures,
-		proposals:  make(map[common.Address]bool),
+		config:        &conf,
+		db:            db,
+		recents:       recents,
+		signatures:    signatures,
+		seals:         seals,
+		confirmations: confirmations,
+		proposals:     make(map[common.Address]bool),
 	}
 }
 
@@ -370,6 +388,16 @@ func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 				break
 			}
 		}
+		// If we're at the last block before transitioning to clique, snapshot the
+		// initial signers from the chain configuration
+		if isTransitionBase(chain.Config(), number) {
+			snap = newSnapshot(c.config, c.signatures, number, hash, c.config.Signers)
+			if err := snap.store(c.db); err != nil {
+				return nil, err
+			}
+			log.Info("Stored transition snapshot to disk", "number", number, "hash", hash)
+			break
+		}
 		// If we're at the genesis, snapshot the initial state. Alternatively if we're
 		// at a checkpoint block without a parent (light client CHT), or we have piled
 		// up more headers than allowed to be reorged (chain reinit from a freezer),
@@ -426,10 +454,74 @@ func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 			return nil, err
 		}
 		log.Trace("Stored voting snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+		c.pruneSnapshots(snap.Number)
 	}
 	return snap, err
 }
 
+// pruneSnapshots deletes the persisted snapshots further than the configured
+// retention depth below the checkpoint at the given number.
+func (c *Clique) pruneSnapshots(number uint64) {
+	c.lock.RLock()
+	retention := c.retention
+	c.lock.RUnlock()
+
+	if retention == 0 || number <= retention {
+		return
+	}
+	limit := number - retention
+	deleted, err := deleteSnapshots(c.db, func(snap *Snapshot) bool {
+		return snap.Number < limit
+	})
+	if err != nil {
+		log.Warn("Failed to prune voting snapshots", "number", number, "err", err)
+		return
+	}
+	if deleted > 0 {
+		log.Debug("Pruned stale voting snapshots", "deleted", deleted, "below", limit)
+	}
+}
+
+// RebuildSnapshots regenerates the persisted vote snapshots of every checkpoint
+// of the canonical chain up to head, returning the number of snapshots stored.
+// It is meant to be used after DeleteSnapshots on an otherwise idle database.
+func (c *Clique) RebuildSnapshots(chain consensus.ChainHeaderReader, head *types.Header) (int, error) {
+	c.recents.Purge()
+
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		rebuilt int
+	)
+	for number := uint64(0); number <= head.Number.Uint64(); number += checkpointInterval {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return rebuilt, fmt.Errorf("missing checkpoint header #%d", number)
+		}
+		// Blocks before a transition to clique carry no clique snapshots
+		if !chain.Config().IsClique(header.Number) && !isTransitionBase(chain.Config(), number) {
+			continue
+		}
+		if _, err := c.snapshot(chain, number, header.Hash(), nil); err != nil {
+			return rebuilt, err
+		}
+		rebuilt++
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Rebuilding voting snapshots", "number", number, "head", head.Number, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	log.Info("Rebuilt voting snapshots", "count", rebuilt, "elapsed", common.PrettyDuration(time.Since(start)))
+	return rebuilt, nil
+}
+
+// isTransitionBase returns whether number is the last block sealed by another
+// consensus engine before the chain transitions to clique.
+func isTransitionBase(config *params.ChainConfig, number uint64) bool {
+	return config.CliqueBlock != nil && config.CliqueBlock.Sign() > 0 && config.CliqueBlock.Uint64() == number+1
+}
+
 // VerifyUncles implements consensus.Engine, always returning an error for any
 // uncles as this consensus mechanism doesn't permit uncles.
 func (c *Clique) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
@@ -487,6 +579,7 @@ func (c *Clique) verifySeal(chain consensus.ChainHeaderReader, header *types.Hea
 			return errWrongDifficulty
 		}
 	}
+	c.checkDoubleSign(header, signer)
 	return nil
 }
 
@@ -688,6 +781,102 @@ func CalcDifficulty(snap *Snapshot, signer common.Address) *big.Int {
 	return new(big.Int).Set(diffNoTurn)
 }
 
+// SetFinality sets the number of distinct signers that need to seal blocks on
+// top of a block before it is considered safe or finalized. Zero values revert
+// to the defaults, which for finality is a majority of the authorized signers.
+func (c *Clique) SetFinality(safe, finalized int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.safeSigners, c.finalizedSigners = safe, finalized
+}
+
+// SetSnapshotRetention sets the depth below the latest checkpoint beyond which
+// persisted vote snapshots are pruned. Zero keeps all snapshots.
+func (c *Clique) SetSnapshotRetention(depth uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.retention = depth
+}
+
+// SafeHeader implements consensus.Finality, returning the most recent header
+// on top of which the configured number of distinct signers sealed blocks.
+func (c *Clique) SafeHeader(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	c.lock.RLock()
+	threshold := c.safeSigners
+	c.lock.RUnlock()
+
+	if threshold == 0 {
+		threshold = defaultSafeSigners
+	}
+	return c.confirmedHeader(chain, head, threshold)
+}
+
+// FinalizedHeader implements consensus.Finality, returning the most recent
+// header on top of which the configured number of distinct signers (or a
+// majority of all authorized signers by default) sealed blocks.
+func (c *Clique) FinalizedHeader(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	c.lock.RLock()
+	threshold := c.finalizedSigners
+	c.lock.RUnlock()
+
+	return c.confirmedHeader(chain, head, threshold)
+}
+
+// confirmationKey identifies the confirmed header of a head for a threshold.
+type confirmationKey struct {
+	head      common.Hash
+	threshold int
+}
+
+// confirmedHeader walks the chain back from head and returns the first header
+// on top of which at least threshold distinct signers sealed blocks. A zero
+// threshold requires a majority of the authorized signers, and any threshold
+// is capped to the number of signers, as more can never be reached.
+//
+// The walk is bounded to an epoch, so if too few signers are active to reach
+// the threshold, no header is considered confirmed instead of walking back to
+// genesis. The outcome is cached per head.
+func (c *Clique) confirmedHeader(chain consensus.ChainHeaderReader, head *types.Header, threshold int) *types.Header {
+	key := confirmationKey{head.Hash(), threshold}
+	if confirmed, ok := c.confirmations.Get(key); ok {
+		return confirmed.(*types.Header)
+	}
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil
+	}
+	if threshold <= 0 {
+		threshold = len(snap.Signers)/2 + 1
+	}
+	if threshold > len(snap.Signers) {
+		threshold = len(snap.Signers)
+	}
+	var (
+		confirmed *types.Header
+		seen      = make(map[common.Address]struct{})
+		depth     uint64
+	)
+	for header := head; header != nil && depth <= c.config.Epoch; header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+		if len(seen) >= threshold || header.Number.Sign() == 0 || isTransitionBase(chain.Config(), header.Number.Uint64()) {
+			confirmed = header
+			break
+		}
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return nil
+		}
+		seen[signer] = struct{}{}
+		depth++
+	}
+	// Don't cache walks cut short by a missing ancestor, it may still be imported
+	if confirmed != nil || depth > c.config.Epoch {
+		c.confirmations.Add(key, confirmed)
+	}
+	return confirmed
+}
+
 // SealHash returns the hash of a block prior to it being sealed.
 func (c *Clique) SealHash(header *types.Header) common.Hash {
 	return SealHash(header)
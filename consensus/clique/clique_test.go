@@ -17,7 +17,10 @@
 package clique
 
 import (
+	"encoding/json"
 	"math/big"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/ccm-chain/ccmchain/common"
@@ -26,6 +29,7 @@ import (
 	"github.com/ccm-chain/ccmchain/core/types"
 	"github.com/ccm-chain/ccmchain/core/vm"
 	"github.com/ccm-chain/ccmchain/crypto"
+	"github.com/ccm-chain/ccmchain/database"
 	"github.com/ccm-chain/ccmchain/params"
 )
 
@@ -114,3 +118,252 @@ func TestReimportMirroredState(t *testing.T) {
 		t.Fatalf("chain head mismatch: have %d, want %d", head, 3)
 	}
 }
+
+// Tests that the safe and finalized headers are derived from the number of
+// distinct signers that sealed blocks on top of them.
+func TestFinality(t *testing.T) {
+	// Create a five signer network sealing blocks in turn
+	var (
+		accounts = newTesterAccountPool()
+		names    = []string{"A", "B", "C", "D", "E"}
+		sealers  = []string{"A", "B", "C", "D", "E", "A", "B"}
+	)
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(names)+extraSeal),
+	}
+	for i, name := range names {
+		copy(genesis.ExtraData[extraVanity+i*common.AddressLength:], accounts.address(name).Bytes())
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+	engine.fakeDiff = true
+
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(sealers), func(i int, gen *core.BlockGen) {
+		// Authorize the sealer so the block rewards are credited correctly
+		engine.Authorize(accounts.address(sealers[i]), nil)
+	})
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffInTurn
+
+		accounts.sign(header, sealers[i])
+		blocks[i] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	head := chain.CurrentHeader()
+
+	tests := []struct {
+		safe, finalized         int
+		wantSafe, wantFinalized uint64
+	}{
+		{0, 0, 5, 4}, // defaults: two signers for safe, majority (3) for finalized
+		{4, 5, 3, 2}, // explicit thresholds
+		{1, 9, 6, 2}, // thresholds above the signer count are capped
+	}
+	for i, tt := range tests {
+		engine.SetFinality(tt.safe, tt.finalized)
+
+		if have := engine.SafeHeader(chain, head).Number.Uint64(); have != tt.wantSafe {
+			t.Errorf("test %d: safe header mismatch: have %d, want %d", i, have, tt.wantSafe)
+		}
+		if have := engine.FinalizedHeader(chain, head).Number.Uint64(); have != tt.wantFinalized {
+			t.Errorf("test %d: finalized header mismatch: have %d, want %d", i, have, tt.wantFinalized)
+		}
+	}
+}
+
+// Tests that a signer sealing two distinct blocks at the same height is detected
+// and the evidence persisted into the database.
+func TestDoubleSignDetection(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		db       = rawdb.NewMemoryDatabase()
+	)
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*2+extraSeal),
+	}
+	copy(genesis.ExtraData[extraVanity:], accounts.address("A").Bytes())
+	copy(genesis.ExtraData[extraVanity+common.AddressLength:], accounts.address("B").Bytes())
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+	engine.fakeDiff = true
+
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Seal two different headers at height 1 with the same signer
+	blocks, _ := core.GenerateChain(&config, chain.Genesis(), engine, db, 1, nil)
+	headers := make([]*types.Header, 2)
+	for i := range headers {
+		header := blocks[0].Header()
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Extra[0] = byte(i)
+		header.Difficulty = diffInTurn
+
+		accounts.sign(header, "A")
+		headers[i] = header
+	}
+	if err := engine.VerifyHeader(chain, headers[0], true); err != nil {
+		t.Fatalf("failed to verify first header: %v", err)
+	}
+	if evidence, _ := loadEvidence(db, nil); len(evidence) != 0 {
+		t.Fatalf("evidence reported for a single seal: %v", evidence)
+	}
+	if err := engine.VerifyHeader(chain, headers[1], true); err != nil {
+		t.Fatalf("failed to verify second header: %v", err)
+	}
+	signer := accounts.address("A")
+	evidence, err := loadEvidence(db, &signer)
+	if err != nil {
+		t.Fatalf("failed to load evidence: %v", err)
+	}
+	if len(evidence) != 1 {
+		t.Fatalf("evidence count mismatch: have %d, want %d", len(evidence), 1)
+	}
+	if evidence[0].Signer != signer || evidence[0].Number != 1 {
+		t.Errorf("evidence mismatch: have %x #%d, want %x #%d", evidence[0].Signer, evidence[0].Number, signer, 1)
+	}
+	if evidence[0].Headers[0].Hash() != headers[0].Hash() || evidence[0].Headers[1].Hash() != headers[1].Hash() {
+		t.Errorf("evidence headers mismatch")
+	}
+	other := accounts.address("B")
+	if evidence, _ := loadEvidence(db, &other); len(evidence) != 0 {
+		t.Errorf("evidence reported for innocent signer: %v", evidence)
+	}
+}
+
+// storedSnapshots returns the numbers of the vote snapshots persisted in db.
+func storedSnapshots(t *testing.T, db database.Database) []uint64 {
+	it := db.NewIterator(snapshotPrefix, nil)
+	defer it.Release()
+
+	var numbers []uint64
+	for it.Next() {
+		if !isSnapshotKey(it.Key()) {
+			continue
+		}
+		snap := new(Snapshot)
+		if err := json.Unmarshal(it.Value(), snap); err != nil {
+			t.Fatalf("failed to decode snapshot: %v", err)
+		}
+		numbers = append(numbers, snap.Number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+// Tests that snapshots beyond the retention depth are pruned, that a node can be
+// restarted on top of the pruned database, and that the snapshots can be deleted
+// and rebuilt from the checkpoint headers.
+func TestSnapshotPruning(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		db       = rawdb.NewMemoryDatabase()
+	)
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	copy(genesis.ExtraData[extraVanity:], accounts.address("A").Bytes())
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+	engine.Authorize(accounts.address("A"), nil)
+	engine.SetSnapshotRetention(checkpointInterval)
+
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, 3*checkpointInterval+8, nil)
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffInTurn
+
+		accounts.sign(header, "A")
+		blocks[i] = block.WithSeal(header)
+	}
+	// Persist some unrelated clique data which must survive all snapshot deletions
+	evidence := &DoubleSignEvidence{Signer: accounts.address("A"), Number: 1}
+	if err := evidence.store(db); err != nil {
+		t.Fatalf("failed to store evidence: %v", err)
+	}
+	// Import all but the last few blocks and ensure old snapshots were pruned
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks[:len(blocks)-4]); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	chain.Stop()
+
+	if have, want := storedSnapshots(t, db), []uint64{2 * checkpointInterval, 3 * checkpointInterval}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("retained snapshots mismatch: have %v, want %v", have, want)
+	}
+	// Restart with a fresh engine on top of the pruned database and import the rest
+	engine = New(config.Clique, db)
+	engine.SetSnapshotRetention(checkpointInterval)
+
+	chain, err = core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to recreate test chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[len(blocks)-4:]); err != nil {
+		t.Fatalf("failed to import chain after restart: %v", err)
+	}
+	if head := chain.CurrentHeader().Number.Uint64(); head != uint64(len(blocks)) {
+		t.Fatalf("chain head mismatch: have %d, want %d", head, len(blocks))
+	}
+	// Delete all the snapshots and rebuild them from the checkpoint headers
+	if _, err := DeleteSnapshots(db); err != nil {
+		t.Fatalf("failed to delete snapshots: %v", err)
+	}
+	if have := storedSnapshots(t, db); len(have) != 0 {
+		t.Fatalf("snapshots left after deletion: %v", have)
+	}
+	engine = New(config.Clique, db)
+	rebuilt, err := engine.RebuildSnapshots(chain, chain.CurrentHeader())
+	if err != nil {
+		t.Fatalf("failed to rebuild snapshots: %v", err)
+	}
+	want := []uint64{0, checkpointInterval, 2 * checkpointInterval, 3 * checkpointInterval}
+	if have := storedSnapshots(t, db); rebuilt != len(want) || !reflect.DeepEqual(have, want) {
+		t.Fatalf("rebuilt snapshots mismatch: have %v (%d), want %v", have, rebuilt, want)
+	}
+	if stored, _ := loadEvidence(db, nil); len(stored) != 1 {
+		t.Fatalf("double sign evidence lost: have %d, want %d", len(stored), 1)
+	}
+	snap, err := engine.snapshot(chain, chain.CurrentHeader().Number.Uint64(), chain.CurrentHeader().Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve head snapshot: %v", err)
+	}
+	if _, ok := snap.Signers[accounts.address("A")]; !ok || len(snap.Signers) != 1 {
+		t.Errorf("head snapshot signers mismatch: have %v", snap.signers())
+	}
+}
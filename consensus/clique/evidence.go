@@ -0,0 +1,119 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/database"
+	"github.com/ccm-chain/ccmchain/log"
+	"github.com/ccm-chain/ccmchain/metrics"
+)
+
+// evidencePrefix is the database key prefix of persisted double sign evidence,
+// followed by the signer address and the big endian block number.
+var evidencePrefix = []byte("clique-doublesign-")
+
+var doubleSignMeter = metrics.NewRegisteredMeter("clique/doublesign", nil)
+
+// sealKey identifies a block height sealed by a particular signer.
+type sealKey struct {
+	number uint64
+	signer common.Address
+}
+
+// DoubleSignEvidence is the proof that a signer sealed two distinct blocks at
+// the same height, consisting of both validly sealed headers.
+type DoubleSignEvidence struct {
+	Signer  common.Address  `json:"signer"`  // Signer that sealed both headers
+	Number  uint64          `json:"number"`  // Height at which the signer equivocated
+	Headers []*types.Header `json:"headers"` // Distinct headers sealed by the signer
+}
+
+// evidenceKey = evidencePrefix + signer + number (uint64 big endian)
+func evidenceKey(signer common.Address, number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+
+	key := append(append([]byte{}, evidencePrefix...), signer.Bytes()...)
+	return append(key, enc...)
+}
+
+// store inserts the evidence into the database.
+func (e *DoubleSignEvidence) store(db database.KeyValueWriter) error {
+	blob, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return db.Put(evidenceKey(e.Signer, e.Number), blob)
+}
+
+// loadEvidence retrieves all the double sign evidence persisted in the database,
+// optionally filtered to a single signer.
+func loadEvidence(db database.Iteratee, signer *common.Address) ([]*DoubleSignEvidence, error) {
+	prefix := evidencePrefix
+	if signer != nil {
+		prefix = append(append([]byte{}, evidencePrefix...), signer.Bytes()...)
+	}
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var evidence []*DoubleSignEvidence
+	for it.Next() {
+		e := new(DoubleSignEvidence)
+		if err := json.Unmarshal(it.Value(), e); err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, e)
+	}
+	return evidence, it.Error()
+}
+
+// checkDoubleSign records the seal of a validly signed header, and if the same
+// signer was already seen sealing a different block at the same height, reports
+// the pair of headers and persists them as evidence.
+func (c *Clique) checkDoubleSign(header *types.Header, signer common.Address) {
+	key := sealKey{number: header.Number.Uint64(), signer: signer}
+
+	prev, ok := c.seals.Get(key)
+	if !ok {
+		c.seals.Add(key, header)
+		return
+	}
+	first := prev.(*types.Header)
+	if first.Hash() == header.Hash() {
+		return
+	}
+	// Only report each equivocation once, even if more blocks show up
+	if has, _ := c.db.Has(evidenceKey(signer, key.number)); has {
+		return
+	}
+	log.Error("Clique signer sealed two blocks at the same height", "signer", signer, "number", key.number, "first", first.Hash(), "second", header.Hash())
+	doubleSignMeter.Mark(1)
+
+	evidence := &DoubleSignEvidence{
+		Signer:  signer,
+		Number:  key.number,
+		Headers: []*types.Header{first, header},
+	}
+	if err := evidence.store(c.db); err != nil {
+		log.Warn("Failed to store double sign evidence", "signer", signer, "number", key.number, "err", err)
+	}
+}
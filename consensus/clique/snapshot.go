@@ -85,9 +85,24 @@ func newSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, number uin
 	return snap
 }
 
+// snapshotPrefix is the database key prefix of persisted vote snapshots, followed
+// by the hash of the block the snapshot was created at.
+var snapshotPrefix = []byte("clique-")
+
+// snapshotKey = snapshotPrefix + hash
+func snapshotKey(hash common.Hash) []byte {
+	return append(append([]byte{}, snapshotPrefix...), hash.Bytes()...)
+}
+
+// isSnapshotKey reports whether a database key under the clique prefix belongs
+// to a vote snapshot, as opposed to other clique data such as double sign evidence.
+func isSnapshotKey(key []byte) bool {
+	return len(key) == len(snapshotPrefix)+common.HashLength
+}
+
 // loadSnapshot loads an existing snapshot from the database.
 func loadSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, db database.Database, hash common.Hash) (*Snapshot, error) {
-	blob, err := db.Get(append([]byte("clique-"), hash[:]...))
+	blob, err := db.Get(snapshotKey(hash))
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +122,50 @@ func (s *Snapshot) store(db database.Database) error {
 	if err != nil {
 		return err
 	}
-	return db.Put(append([]byte("clique-"), s.Hash[:]...), blob)
+	return db.Put(snapshotKey(s.Hash), blob)
+}
+
+// DeleteSnapshots removes all the vote snapshots persisted in the database and
+// returns the number of snapshots deleted. Snapshots are regenerated on demand
+// from the checkpoint headers, or explicitly via Clique.RebuildSnapshots.
+func DeleteSnapshots(db database.Database) (int, error) {
+	return deleteSnapshots(db, func(*Snapshot) bool { return true })
+}
+
+// deleteSnapshots removes the persisted vote snapshots matching the filter and
+// returns the number of snapshots deleted.
+func deleteSnapshots(db database.Database, filter func(*Snapshot) bool) (int, error) {
+	it := db.NewIterator(snapshotPrefix, nil)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	deleted := 0
+	for it.Next() {
+		if !isSnapshotKey(it.Key()) {
+			continue
+		}
+		snap := new(Snapshot)
+		if err := json.Unmarshal(it.Value(), snap); err != nil {
+			return deleted, err
+		}
+		if !filter(snap) {
+			continue
+		}
+		if err := batch.Delete(common.CopyBytes(it.Key())); err != nil {
+			return deleted, err
+		}
+		deleted++
+		if batch.ValueSize() > database.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return deleted, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return deleted, err
+	}
+	return deleted, batch.Write()
 }
 
 // copy creates a deep copy of the snapshot, though not the individual votes.
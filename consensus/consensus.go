@@ -129,3 +129,17 @@ type PoW interface {
 	// Hashrate returns the current mining hashrate of a PoW consensus engine.
 	Hashrate() float64
 }
+
+// Finality is a consensus engine that is able to tell how deeply a block needs
+// to be buried before it is considered safe or finalized.
+type Finality interface {
+	Engine
+
+	// SafeHeader retrieves the most recent header of the chain ending in head
+	// that is unlikely to be reorged out.
+	SafeHeader(chain ChainHeaderReader, head *types.Header) *types.Header
+
+	// FinalizedHeader retrieves the most recent header of the chain ending in
+	// head that is not expected to ever be reorged out.
+	FinalizedHeader(chain ChainHeaderReader, head *types.Header) *types.Header
+}
@@ -44,6 +44,8 @@ var (
 	ConstantinopleBlockReward = big.NewInt(2e+18)           // Block reward in wei for successfully mining a block upward from Constantinople
 	maxUncles                 = 2                           // Maximum number of uncles allowed in a single block
 	allowedFutureBlockTime    = 15 * time.Second            // Max time from current time allowed for blocks, before they're considered future blocks
+	defaultSafeDepth          = uint64(12)                  // Number of blocks on top of a block before it is considered safe
+	defaultFinalizedDepth     = uint64(64)                  // Number of blocks on top of a block before it is considered finalized
 
 	// calcDifficultyConstantinople is the difficulty adjustment algorithm for Constantinople.
 	// It returns the difficulty that a new block should have when created at time given the
@@ -577,6 +579,36 @@ func (ethash *Ethash) FinalizeAndAssemble(chain consensus.ChainHeaderReader, hea
 	return types.NewBlock(header, txs, uncles, receipts, new(trie.Trie)), nil
 }
 
+// SafeHeader implements consensus.Finality, returning the canonical header
+// buried under the configured safe depth of blocks.
+func (ethash *Ethash) SafeHeader(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	depth := ethash.config.SafeDepth
+	if depth == 0 {
+		depth = defaultSafeDepth
+	}
+	return headerAtDepth(chain, head, depth)
+}
+
+// FinalizedHeader implements consensus.Finality, returning the canonical header
+// buried under the configured finalized depth of blocks.
+func (ethash *Ethash) FinalizedHeader(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	depth := ethash.config.FinalizedDepth
+	if depth == 0 {
+		depth = defaultFinalizedDepth
+	}
+	return headerAtDepth(chain, head, depth)
+}
+
+// headerAtDepth retrieves the canonical header depth blocks below head, or the
+// genesis header if the chain is not long enough yet.
+func headerAtDepth(chain consensus.ChainHeaderReader, head *types.Header, depth uint64) *types.Header {
+	number := head.Number.Uint64()
+	if number < depth {
+		return chain.GetHeaderByNumber(0)
+	}
+	return chain.GetHeaderByNumber(number - depth)
+}
+
 // SealHash returns the hash of a block prior to it being sealed.
 func (ethash *Ethash) SealHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewLegacyKeccak256()
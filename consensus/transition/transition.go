@@ -0,0 +1,257 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package transition implements a consensus engine wrapper which hands over the
+// sealing and verification of a running chain from one engine to another at a
+// configured fork block.
+package transition
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/consensus"
+	"github.com/ccm-chain/ccmchain/core/state"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/rpc"
+)
+
+// Engine is a consensus engine dispatching every operation to one of two wrapped
+// engines, depending on whether the block in question is before or after the
+// fork block.
+type Engine struct {
+	before consensus.Engine // Engine handling the blocks before the fork
+	after  consensus.Engine // Engine handling the blocks from the fork onwards
+	fork   uint64           // First block handled by the after engine
+}
+
+// New creates a consensus engine transitioning from before to after at the given
+// fork block.
+func New(before, after consensus.Engine, fork uint64) *Engine {
+	return &Engine{
+		before: before,
+		after:  after,
+		fork:   fork,
+	}
+}
+
+// Engines returns the wrapped consensus engines handling the blocks before and
+// after the fork.
+func (e *Engine) Engines() (before, after consensus.Engine) {
+	return e.before, e.after
+}
+
+// engine returns the consensus engine responsible for the given block number.
+func (e *Engine) engine(number *big.Int) consensus.Engine {
+	if number.Uint64() < e.fork {
+		return e.before
+	}
+	return e.after
+}
+
+// Author implements consensus.Engine, retrieving the block author using the
+// engine responsible for the header.
+func (e *Engine) Author(header *types.Header) (common.Address, error) {
+	return e.engine(header.Number).Author(header)
+}
+
+// VerifyHeader implements consensus.Engine, checking whether a header conforms
+// to the consensus rules of the engine responsible for it.
+func (e *Engine) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	return e.engine(header.Number).VerifyHeader(chain, header, seal)
+}
+
+// VerifyHeaders implements consensus.Engine, verifying a batch of headers which
+// may span the fork. The headers on each side are verified concurrently by their
+// own engine, the ones after the fork seeing the earlier ones as their ancestors.
+func (e *Engine) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	split := sort.Search(len(headers), func(i int) bool {
+		return headers[i].Number.Uint64() >= e.fork
+	})
+	if split == 0 {
+		return e.after.VerifyHeaders(chain, headers, seals)
+	}
+	if split == len(headers) {
+		return e.before.VerifyHeaders(chain, headers, seals)
+	}
+	var (
+		abort   = make(chan struct{})
+		results = make(chan error, len(headers))
+	)
+	beforeAbort, beforeResults := e.before.VerifyHeaders(chain, headers[:split], seals[:split])
+	afterAbort, afterResults := e.after.VerifyHeaders(newBatchChain(chain, headers[:split]), headers[split:], seals[split:])
+
+	go func() {
+		defer close(beforeAbort)
+		defer close(afterAbort)
+
+		for i := range headers {
+			source := beforeResults
+			if i >= split {
+				source = afterResults
+			}
+			select {
+			case err := <-source:
+				results <- err
+			case <-abort:
+				return
+			}
+		}
+	}()
+	return abort, results
+}
+
+// VerifyUncles implements consensus.Engine, verifying the uncles of a block with
+// the engine responsible for it.
+func (e *Engine) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	return e.engine(block.Number()).VerifyUncles(chain, block)
+}
+
+// VerifySeal implements consensus.Engine, checking the seal of a header with the
+// engine responsible for it.
+func (e *Engine) VerifySeal(chain consensus.ChainHeaderReader, header *types.Header) error {
+	return e.engine(header.Number).VerifySeal(chain, header)
+}
+
+// Prepare implements consensus.Engine, initializing the consensus fields of a
+// header with the engine responsible for it.
+func (e *Engine) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	return e.engine(header.Number).Prepare(chain, header)
+}
+
+// Finalize implements consensus.Engine, running the post-transaction state
+// modifications of the engine responsible for the header.
+func (e *Engine) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	e.engine(header.Number).Finalize(chain, header, state, txs, uncles)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, finalizing and assembling the
+// block with the engine responsible for the header.
+func (e *Engine) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	return e.engine(header.Number).FinalizeAndAssemble(chain, header, state, txs, uncles, receipts)
+}
+
+// Seal implements consensus.Engine, sealing the block with the engine responsible
+// for it.
+func (e *Engine) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	return e.engine(block.Number()).Seal(chain, block, results, stop)
+}
+
+// SealHash implements consensus.Engine, returning the hash of a block prior to
+// it being sealed by the engine responsible for it.
+func (e *Engine) SealHash(header *types.Header) common.Hash {
+	return e.engine(header.Number).SealHash(header)
+}
+
+// CalcDifficulty implements consensus.Engine, returning the difficulty of the
+// block following parent, as calculated by the engine responsible for it.
+func (e *Engine) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return e.engine(new(big.Int).Add(parent.Number, common.Big1)).CalcDifficulty(chain, time, parent)
+}
+
+// APIs implements consensus.Engine, returning the RPC APIs of both engines.
+func (e *Engine) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return append(e.before.APIs(chain), e.after.APIs(chain)...)
+}
+
+// Close implements consensus.Engine, terminating both engines.
+func (e *Engine) Close() error {
+	err := e.before.Close()
+	if afterErr := e.after.Close(); err == nil {
+		err = afterErr
+	}
+	return err
+}
+
+// SafeHeader implements consensus.Finality, deferring to the engine responsible
+// for the chain head if it is able to judge finality.
+func (e *Engine) SafeHeader(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	if engine, ok := e.engine(head.Number).(consensus.Finality); ok {
+		return engine.SafeHeader(chain, head)
+	}
+	return nil
+}
+
+// FinalizedHeader implements consensus.Finality, deferring to the engine
+// responsible for the chain head if it is able to judge finality.
+func (e *Engine) FinalizedHeader(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	if engine, ok := e.engine(head.Number).(consensus.Finality); ok {
+		return engine.FinalizedHeader(chain, head)
+	}
+	return nil
+}
+
+// Hashrate implements consensus.PoW, returning the mining hashrate of any wrapped
+// proof-of-work engine.
+func (e *Engine) Hashrate() float64 {
+	var hashrate float64
+	for _, engine := range []consensus.Engine{e.before, e.after} {
+		if pow, ok := engine.(consensus.PoW); ok {
+			hashrate += pow.Hashrate()
+		}
+	}
+	return hashrate
+}
+
+// SetThreads updates the number of mining threads of any wrapped engine that
+// supports multi-threaded mining.
+func (e *Engine) SetThreads(threads int) {
+	type threaded interface {
+		SetThreads(threads int)
+	}
+	for _, engine := range []consensus.Engine{e.before, e.after} {
+		if th, ok := engine.(threaded); ok {
+			th.SetThreads(threads)
+		}
+	}
+}
+
+// batchChain is a chain reader which also knows about a batch of headers that
+// have not yet been written into the chain.
+type batchChain struct {
+	consensus.ChainHeaderReader
+	headers map[common.Hash]*types.Header
+}
+
+// newBatchChain creates a chain reader overlaying headers on top of chain.
+func newBatchChain(chain consensus.ChainHeaderReader, headers []*types.Header) *batchChain {
+	batch := &batchChain{
+		ChainHeaderReader: chain,
+		headers:           make(map[common.Hash]*types.Header, len(headers)),
+	}
+	for _, header := range headers {
+		batch.headers[header.Hash()] = header
+	}
+	return batch
+}
+
+// GetHeader retrieves a block header from the batch or the chain by hash and
+// number.
+func (c *batchChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := c.headers[hash]; ok && header.Number.Uint64() == number {
+		return header
+	}
+	return c.ChainHeaderReader.GetHeader(hash, number)
+}
+
+// GetHeaderByHash retrieves a block header from the batch or the chain by hash.
+func (c *batchChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header, ok := c.headers[hash]; ok {
+		return header
+	}
+	return c.ChainHeaderReader.GetHeaderByHash(hash)
+}
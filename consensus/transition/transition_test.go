@@ -0,0 +1,100 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package transition
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/consensus/clique"
+	"github.com/ccm-chain/ccmchain/consensus/ethash"
+	"github.com/ccm-chain/ccmchain/core"
+	"github.com/ccm-chain/ccmchain/core/rawdb"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/core/vm"
+	"github.com/ccm-chain/ccmchain/crypto"
+	"github.com/ccm-chain/ccmchain/params"
+)
+
+// Tests that a chain started on proof-of-work can transition to proof-of-authority
+// at the configured fork block, and that batches spanning the fork are verified.
+func TestEthashToCliqueTransition(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		fork   = uint64(4)
+		config = *params.TestChainConfig
+	)
+	config.CliqueBlock = new(big.Int).SetUint64(fork)
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000, Signers: []common.Address{addr}}
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := (&core.Genesis{Config: &config}).MustCommit(db)
+
+	signer := clique.New(config.Clique, db)
+	signer.Authorize(addr, nil)
+	engine := New(ethash.NewFaker(), signer, fork)
+
+	blocks, _ := core.GenerateChain(&config, genesis, engine, db, 8, nil)
+	for i, block := range blocks {
+		if block.NumberU64() < fork {
+			continue
+		}
+		// Seal the blocks after the fork with the clique signer
+		header := block.Header()
+		header.ParentHash = blocks[i-1].Hash()
+		header.Extra = make([]byte, 32+crypto.SignatureLength)
+		header.Difficulty = big.NewInt(2)
+
+		sig, _ := crypto.Sign(clique.SealHash(header).Bytes(), key)
+		copy(header.Extra[len(header.Extra)-crypto.SignatureLength:], sig)
+		blocks[i] = block.WithSeal(header)
+	}
+	// Import the chain into a pristine database in a single batch spanning the fork
+	db = rawdb.NewMemoryDatabase()
+	(&core.Genesis{Config: &config}).MustCommit(db)
+
+	engine = New(ethash.NewFaker(), clique.New(config.Clique, db), fork)
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	for _, block := range blocks {
+		author, err := engine.Author(block.Header())
+		if err != nil {
+			t.Fatalf("failed to retrieve author of block %d: %v", block.NumberU64(), err)
+		}
+		if block.NumberU64() >= fork && author != addr {
+			t.Errorf("block %d: author mismatch: have %x, want %x", block.NumberU64(), author, addr)
+		}
+		if block.NumberU64() < fork && author != block.Coinbase() {
+			t.Errorf("block %d: author mismatch: have %x, want %x", block.NumberU64(), author, block.Coinbase())
+		}
+	}
+	// Blocks after the fork must not be accepted with a proof-of-work seal
+	invalid := types.CopyHeader(blocks[fork].Header())
+	invalid.Extra = nil
+	if err := engine.VerifyHeader(chain, invalid, true); err == nil {
+		t.Errorf("unsealed header after the fork accepted")
+	}
+}
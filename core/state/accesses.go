@@ -0,0 +1,209 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ccm-chain/ccmchain/common"
+)
+
+// Accesses records the accounts and storage slots read and written while
+// executing transactions on a state, allowing the detection of conflicts between
+// transactions executed optimistically in parallel.
+//
+// Only reads observing a value are tracked: blind balance increases (e.g. value
+// transfers to a recipient or fee payments to the coinbase) are writes without
+// reads, which can be merged on top of a modified state as balance deltas.
+type Accesses struct {
+	Reads      map[common.Address]struct{}                 // Accounts whose balance, nonce, code or existence was observed
+	SlotReads  map[common.Address]map[common.Hash]struct{} // Storage slots whose value was observed
+	Writes     map[common.Address]struct{}                 // Accounts whose balance, nonce or code was modified
+	SlotWrites map[common.Address]map[common.Hash]struct{} // Storage slots which were modified
+	Created    map[common.Address]struct{}                 // Accounts (re)created, resetting their storage
+	Destructed map[common.Address]struct{}                 // Accounts which self destructed
+	Dirtied    map[common.Address]struct{}                 // Accounts with modifications surviving reverts
+
+	creates map[common.Address]struct{} // Accounts explicitly created, possibly reverted since
+}
+
+// NewAccesses creates an empty access set.
+func NewAccesses() *Accesses {
+	return &Accesses{
+		Reads:      make(map[common.Address]struct{}),
+		SlotReads:  make(map[common.Address]map[common.Hash]struct{}),
+		Writes:     make(map[common.Address]struct{}),
+		SlotWrites: make(map[common.Address]map[common.Hash]struct{}),
+		Created:    make(map[common.Address]struct{}),
+		Destructed: make(map[common.Address]struct{}),
+		Dirtied:    make(map[common.Address]struct{}),
+		creates:    make(map[common.Address]struct{}),
+	}
+}
+
+// readAccount records an observation of an account. It's a noop on a nil set.
+func (a *Accesses) readAccount(addr common.Address) {
+	if a != nil {
+		a.Reads[addr] = struct{}{}
+	}
+}
+
+// readSlot records an observation of a storage slot. It's a noop on a nil set.
+func (a *Accesses) readSlot(addr common.Address, slot common.Hash) {
+	if a != nil {
+		addSlot(a.SlotReads, addr, slot)
+	}
+}
+
+// writeAccount records a modification of an account. It's a noop on a nil set.
+func (a *Accesses) writeAccount(addr common.Address) {
+	if a != nil {
+		a.Writes[addr] = struct{}{}
+	}
+}
+
+// writeSlot records a modification of a storage slot. It's a noop on a nil set.
+func (a *Accesses) writeSlot(addr common.Address, slot common.Hash) {
+	if a != nil {
+		addSlot(a.SlotWrites, addr, slot)
+	}
+}
+
+// createAccount records an explicit account creation. It's a noop on a nil set.
+func (a *Accesses) createAccount(addr common.Address) {
+	if a != nil {
+		a.Writes[addr] = struct{}{}
+		a.creates[addr] = struct{}{}
+	}
+}
+
+// finalise records the effects of a transaction surviving in the journal, before
+// it gets cleared. It's a noop on a nil set.
+func (a *Accesses) finalise(j *journal) {
+	if a == nil {
+		return
+	}
+	for addr := range j.dirties {
+		a.Dirtied[addr] = struct{}{}
+	}
+	for _, entry := range j.entries {
+		switch entry := entry.(type) {
+		case createObjectChange:
+			if _, ok := a.creates[*entry.account]; ok {
+				a.Created[*entry.account] = struct{}{}
+			}
+		case resetObjectChange:
+			if _, ok := a.creates[entry.prev.address]; ok {
+				a.Created[entry.prev.address] = struct{}{}
+			}
+		case suicideChange:
+			a.Destructed[*entry.account] = struct{}{}
+		}
+	}
+}
+
+// addSlot inserts a storage slot into a set of slots grouped by account.
+func addSlot(set map[common.Address]map[common.Hash]struct{}, addr common.Address, slot common.Hash) {
+	slots, ok := set[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		set[addr] = slots
+	}
+	slots[slot] = struct{}{}
+}
+
+// Include adds all the writes of another access set to this one.
+func (a *Accesses) Include(other *Accesses) {
+	for addr := range other.Writes {
+		a.Writes[addr] = struct{}{}
+	}
+	for addr, slots := range other.SlotWrites {
+		for slot := range slots {
+			addSlot(a.SlotWrites, addr, slot)
+		}
+	}
+	for addr := range other.Created {
+		a.Created[addr] = struct{}{}
+	}
+	for addr := range other.Destructed {
+		a.Destructed[addr] = struct{}{}
+	}
+}
+
+// Conflicts reports whether any of the values observed by this access set were
+// modified by the writes of the other one.
+func (a *Accesses) Conflicts(writes *Accesses) bool {
+	for addr := range a.Reads {
+		if _, ok := writes.Writes[addr]; ok {
+			return true
+		}
+	}
+	for addr, slots := range a.SlotReads {
+		if _, ok := writes.Created[addr]; ok {
+			return true
+		}
+		if _, ok := writes.Destructed[addr]; ok {
+			return true
+		}
+		for slot := range slots {
+			if _, ok := writes.SlotWrites[addr][slot]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TrackAccesses starts recording the state accesses into the given set, or stops
+// recording if it's nil.
+func (s *StateDB) TrackAccesses(accesses *Accesses) {
+	s.accesses = accesses
+}
+
+// Merge applies the effects of a single finalised transaction, executed on a
+// copy of the origin state, on top of this state. The transaction must not have
+// observed any value modified between the origin state and this one: blind
+// balance increases are applied as deltas, everything else as absolute values.
+func (s *StateDB) Merge(spec, origin *StateDB, accesses *Accesses) {
+	for addr := range accesses.Dirtied {
+		if _, ok := accesses.Destructed[addr]; ok {
+			s.Suicide(addr)
+			continue
+		}
+		if _, ok := accesses.Created[addr]; ok {
+			s.CreateAccount(addr)
+		}
+		// Apply the balance change as a delta, touching the account as the
+		// transaction did
+		delta := new(big.Int).Sub(spec.GetBalance(addr), origin.GetBalance(addr))
+		if delta.Sign() >= 0 {
+			s.AddBalance(addr, delta)
+		} else {
+			s.SubBalance(addr, delta.Neg(delta))
+		}
+		if nonce := spec.GetNonce(addr); nonce != origin.GetNonce(addr) {
+			s.SetNonce(addr, nonce)
+		}
+		if hash := spec.GetCodeHash(addr); hash != origin.GetCodeHash(addr) {
+			s.SetCode(addr, spec.GetCode(addr))
+		}
+		for slot := range accesses.SlotWrites[addr] {
+			s.SetState(addr, slot, spec.GetState(addr, slot))
+		}
+	}
+	s.Finalise(true)
+}
@@ -214,6 +214,23 @@ func (t *Tree) waitBuild() {
 	}
 }
 
+// Generating reports whether the persistent base layer of the snapshot is still
+// being generated, in which case iterators may miss not yet indexed entries.
+func (t *Tree) Generating() bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, layer := range t.layers {
+		if layer, ok := layer.(*diskLayer); ok {
+			layer.lock.RLock()
+			defer layer.lock.RUnlock()
+
+			return layer.genMarker != nil
+		}
+	}
+	return false
+}
+
 // Snapshot retrieves a snapshot belonging to the given block root, or nil if no
 // snapshot is maintained for that block.
 func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
@@ -0,0 +1,188 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/ccm-chain/ccmchain/consensus"
+	"github.com/ccm-chain/ccmchain/core/state"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/core/vm"
+	"github.com/ccm-chain/ccmchain/metrics"
+	"github.com/ccm-chain/ccmchain/params"
+)
+
+var (
+	parallelMergedMeter     = metrics.NewRegisteredMeter("chain/parallel/merged", nil)
+	parallelReexecutedMeter = metrics.NewRegisteredMeter("chain/parallel/reexecuted", nil)
+)
+
+// ParallelStateProcessor is a Processor executing the transactions of a block
+// optimistically in parallel. Every transaction is first executed speculatively
+// on its own copy of the pre-block state, tracking the accounts and storage slots
+// it reads and writes. The results are then merged in block order, re-executing
+// the transactions which observed values modified by earlier ones, producing the
+// exact same receipts and state as the sequential StateProcessor.
+//
+// ParallelStateProcessor implements Processor.
+type ParallelStateProcessor struct {
+	config  *params.ChainConfig // Chain configuration options
+	bc      *BlockChain         // Canonical block chain
+	engine  consensus.Engine    // Consensus engine used for block rewards
+	threads int                 // Number of threads executing transactions speculatively
+
+	sequential *StateProcessor // Fallback processor for blocks not worth parallelising
+}
+
+// speculativeResult is the outcome of executing a transaction on a copy of the
+// pre-block state.
+type speculativeResult struct {
+	state    *state.StateDB
+	accesses *state.Accesses
+	receipt  *types.Receipt
+	err      error
+}
+
+// NewParallelStateProcessor initialises a new ParallelStateProcessor.
+func NewParallelStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine, threads int) *ParallelStateProcessor {
+	return &ParallelStateProcessor{
+		config:     config,
+		bc:         bc,
+		engine:     engine,
+		threads:    threads,
+		sequential: NewStateProcessor(config, bc, engine),
+	}
+}
+
+// Process processes the state changes according to the Ethereum rules by running
+// the transaction messages using the statedb and applying any rewards to both
+// the processor (coinbase) and any included uncles.
+//
+// Blocks before Byzantium (whose receipts contain intermediate state roots) and
+// executions with tracing or preimage recording enabled are processed
+// sequentially.
+func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+	txs := block.Transactions()
+	if p.threads < 2 || len(txs) < 2 || !p.config.IsByzantium(block.Number()) || !p.config.IsEIP158(block.Number()) || cfg.Debug || cfg.EnablePreimageRecording {
+		return p.sequential.Process(block, statedb, cfg)
+	}
+	var (
+		receipts types.Receipts
+		usedGas  = new(uint64)
+		header   = block.Header()
+		allLogs  []*types.Log
+		gp       = new(GasPool).AddGas(block.GasLimit())
+
+		base   = statedb.Copy() // Pre-block state copied by the speculative executors
+		origin = statedb.Copy() // Pre-block state to compute the merged changes against
+
+		results   = make([]*speculativeResult, len(txs))
+		done      = make([]chan struct{}, len(txs))
+		tasks     = make(chan int, len(txs))
+		interrupt uint32
+	)
+	for i := range txs {
+		done[i] = make(chan struct{})
+		tasks <- i
+	}
+	close(tasks)
+	defer atomic.StoreUint32(&interrupt, 1)
+
+	threads := p.threads
+	if threads > len(txs) {
+		threads = len(txs)
+	}
+	for i := 0; i < threads; i++ {
+		go func() {
+			for i := range tasks {
+				if atomic.LoadUint32(&interrupt) == 0 {
+					results[i] = p.speculate(block, header, i, base.Copy(), cfg)
+				}
+				close(done[i])
+			}
+		}()
+	}
+	// Merge the speculative results in order, re-executing conflicting transactions
+	written := state.NewAccesses()
+	for i, tx := range txs {
+		<-done[i]
+		res := results[i]
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if res.err == nil && gp.Gas() >= tx.Gas() && !res.accesses.Conflicts(written) {
+			statedb.Merge(res.state, origin, res.accesses)
+			if err := gp.SubGas(res.receipt.GasUsed); err != nil {
+				return nil, nil, 0, err
+			}
+			*usedGas += res.receipt.GasUsed
+			res.receipt.CumulativeGasUsed = *usedGas
+
+			receipts = append(receipts, res.receipt)
+			written.Include(res.accesses)
+			parallelMergedMeter.Mark(1)
+			continue
+		}
+		accesses := state.NewAccesses()
+		statedb.TrackAccesses(accesses)
+		receipt, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+		statedb.TrackAccesses(nil)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		receipts = append(receipts, receipt)
+		written.Include(accesses)
+		parallelReexecutedMeter.Mark(1)
+	}
+	// Speculative executions number their logs independently, renumber them
+	var index uint
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			log.Index = index
+			index++
+		}
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles())
+
+	return receipts, allLogs, *usedGas, nil
+}
+
+// speculate executes a transaction on its own copy of the pre-block state, with
+// a gas pool of its own, tracking the state it accesses.
+func (p *ParallelStateProcessor) speculate(block *types.Block, header *types.Header, index int, statedb *state.StateDB, cfg vm.Config) *speculativeResult {
+	tx := block.Transactions()[index]
+
+	accesses := state.NewAccesses()
+	statedb.TrackAccesses(accesses)
+	statedb.Prepare(tx.Hash(), block.Hash(), index)
+
+	var (
+		usedGas uint64
+		gp      = new(GasPool).AddGas(block.GasLimit())
+	)
+	receipt, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, &usedGas, cfg)
+	statedb.TrackAccesses(nil)
+
+	return &speculativeResult{
+		state:    statedb,
+		accesses: accesses,
+		receipt:  receipt,
+		err:      err,
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/consensus/ethash"
+	"github.com/ccm-chain/ccmchain/core/rawdb"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/core/vm"
+	"github.com/ccm-chain/ccmchain/crypto"
+	"github.com/ccm-chain/ccmchain/params"
+)
+
+var (
+	// parallelCounterCode increments storage slot 0 and logs the new value
+	parallelCounterCode = common.FromHex("6000546001018060005560006000a100")
+
+	// parallelDepositCode adds the call value to the storage slot of the caller
+	parallelDepositCode = common.FromHex("3354340133550000")
+
+	// parallelCoinbaseCode stores the balance of the coinbase in slot 0
+	parallelCoinbaseCode = common.FromHex("4131600055")
+
+	// parallelRevertCode writes storage slot 0 and reverts
+	parallelRevertCode = common.FromHex("60016000556000600060fd")
+
+	// parallelDestructCode self destructs, sending its balance to the caller
+	parallelDestructCode = common.FromHex("33ff")
+)
+
+// parallelDeployCode wraps runtime code into init code returning it.
+func parallelDeployCode(runtime []byte) []byte {
+	size := byte(len(runtime))
+	return append([]byte{0x60, size, 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, size, 0x60, 0x00, 0xf3}, runtime...)
+}
+
+// Tests that the parallel state processor produces the exact same receipts, logs
+// and state roots as the sequential one, on generated chains mixing independent
+// and conflicting transactions.
+func TestParallelStateProcessor(t *testing.T) {
+	var (
+		config  = params.TestChainConfig
+		signer  = types.NewEIP155Signer(config.ChainID)
+		seed    = time.Now().UnixNano()
+		random  = rand.New(rand.NewSource(seed))
+		funds   = big.NewInt(1000000000000000000)
+		keys    = make([]*ecdsa.PrivateKey, 32)
+		senders = make([]common.Address, len(keys))
+
+		counter  = common.HexToAddress("0xc1")
+		deposit  = common.HexToAddress("0xc2")
+		coinbase = common.HexToAddress("0xc3")
+		reverter = common.HexToAddress("0xc4")
+
+		alloc = GenesisAlloc{
+			counter:  {Code: parallelCounterCode, Balance: new(big.Int)},
+			deposit:  {Code: parallelDepositCode, Balance: new(big.Int)},
+			coinbase: {Code: parallelCoinbaseCode, Balance: new(big.Int)},
+			reverter: {Code: parallelRevertCode, Balance: new(big.Int)},
+		}
+		destructs []common.Address
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		senders[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[senders[i]] = GenesisAccount{Balance: funds}
+	}
+	for i := 0; i < 16; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(0xd0 + i)))
+		alloc[addr] = GenesisAccount{Code: parallelDestructCode, Balance: big.NewInt(1000)}
+		destructs = append(destructs, addr)
+	}
+	gspec := &Genesis{Config: config, Alloc: alloc, GasLimit: 10000000}
+	t.Logf("random seed: %d", seed)
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(config, genesis, ethash.NewFaker(), db, 8, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(senders[random.Intn(len(senders))])
+
+		for j := 0; j < 40; j++ {
+			var (
+				sender = random.Intn(len(keys))
+				nonce  = gen.TxNonce(senders[sender])
+				tx     *types.Transaction
+			)
+			switch random.Intn(9) {
+			case 0: // Value transfer to a known or a fresh account
+				to := senders[random.Intn(len(senders))]
+				if random.Intn(2) == 0 {
+					to = common.BigToAddress(big.NewInt(random.Int63()))
+				}
+				tx = types.NewTransaction(nonce, to, big.NewInt(1000), params.TxGas, big.NewInt(1), nil)
+			case 1: // Shared storage slot and logs
+				tx = types.NewTransaction(nonce, counter, new(big.Int), 100000, big.NewInt(1), nil)
+			case 2: // Per sender storage slot
+				tx = types.NewTransaction(nonce, deposit, big.NewInt(100), 100000, big.NewInt(1), nil)
+			case 3: // Coinbase balance observation
+				tx = types.NewTransaction(nonce, coinbase, new(big.Int), 100000, big.NewInt(1), nil)
+			case 4: // Reverted storage write
+				tx = types.NewTransaction(nonce, reverter, new(big.Int), 100000, big.NewInt(1), nil)
+			case 5: // Contract creation
+				tx = types.NewContractCreation(nonce, new(big.Int), 200000, big.NewInt(1), parallelDeployCode(parallelCounterCode))
+			case 6: // Self destruct, or a call to an already destructed contract
+				tx = types.NewTransaction(nonce, destructs[random.Intn(len(destructs))], new(big.Int), 100000, big.NewInt(1), nil)
+			case 7: // Value transfer to the coinbase
+				tx = types.NewTransaction(nonce, gen.header.Coinbase, big.NewInt(1000), params.TxGas, big.NewInt(1), nil)
+			case 8: // Empty account touch
+				tx = types.NewTransaction(nonce, common.BigToAddress(big.NewInt(random.Int63())), new(big.Int), params.TxGas, big.NewInt(1), nil)
+			}
+			tx, _ = types.SignTx(tx, signer, keys[sender])
+			gen.AddTx(tx)
+		}
+	})
+	// Import the chain with the parallel processor, validating it against the
+	// sequentially generated headers
+	chaindb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(chaindb)
+
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.SnapshotLimit = 0
+	cacheConfig.ParallelProcessing = 4
+
+	chain, err := NewBlockChain(chaindb, &cacheConfig, config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, ok := chain.Processor().(*ParallelStateProcessor); !ok {
+		t.Fatalf("processor type mismatch: have %T, want %T", chain.Processor(), new(ParallelStateProcessor))
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import block %d: %v", n, err)
+	}
+	// Reprocess every block with both processors and compare the results
+	var (
+		sequential = NewStateProcessor(config, chain, chain.engine)
+		parallel   = NewParallelStateProcessor(config, chain, chain.engine, 4)
+	)
+	for _, block := range blocks {
+		parent := chain.GetBlockByHash(block.ParentHash())
+
+		seqdb, _ := chain.StateAt(parent.Root())
+		seqReceipts, seqLogs, seqGas, err := sequential.Process(block, seqdb, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: sequential processing failed: %v", block.NumberU64(), err)
+		}
+		pardb, _ := chain.StateAt(parent.Root())
+		parReceipts, parLogs, parGas, err := parallel.Process(block, pardb, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: parallel processing failed: %v", block.NumberU64(), err)
+		}
+		if !reflect.DeepEqual(parReceipts, seqReceipts) {
+			t.Errorf("block %d: receipts mismatch", block.NumberU64())
+		}
+		if !reflect.DeepEqual(parLogs, seqLogs) {
+			t.Errorf("block %d: logs mismatch", block.NumberU64())
+		}
+		if parGas != seqGas {
+			t.Errorf("block %d: gas used mismatch: have %d, want %d", block.NumberU64(), parGas, seqGas)
+		}
+		if root := pardb.IntermediateRoot(true); root != block.Root() {
+			t.Errorf("block %d: state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+	}
+}
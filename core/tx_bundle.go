@@ -0,0 +1,116 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/log"
+	"github.com/ccm-chain/ccmchain/metrics"
+)
+
+var (
+	// ErrEmptyBundle is returned if a bundle without transactions is submitted.
+	ErrEmptyBundle = errors.New("empty bundle")
+
+	// ErrStaleBundle is returned if a bundle targets a block that is already
+	// part of the chain.
+	ErrStaleBundle = errors.New("bundle targets past block")
+
+	// ErrBundlePoolFull is returned if a bundle is submitted while the pool
+	// already tracks the maximum number of bundles.
+	ErrBundlePoolFull = errors.New("bundle pool full")
+)
+
+var bundleGauge = metrics.NewRegisteredGauge("txpool/bundles", nil)
+
+// TxBundle is a list of transactions that must be included in the target block
+// atomically and in order, or not at all.
+type TxBundle struct {
+	Txs          types.Transactions // Transactions to include, in order
+	BlockNumber  *big.Int           // Block the bundle must be included in
+	MinTimestamp uint64             // Earliest block timestamp to include the bundle at (0 = any)
+	MaxTimestamp uint64             // Latest block timestamp to include the bundle at (0 = any)
+}
+
+// AddBundle validates a transaction bundle and tracks it until its target block
+// is mined. Bundles are never announced to the network, they are only included
+// atomically by the local miner.
+func (pool *TxPool) AddBundle(bundle *TxBundle) error {
+	if len(bundle.Txs) == 0 {
+		return ErrEmptyBundle
+	}
+	for _, tx := range bundle.Txs {
+		if _, err := types.Sender(pool.signer, tx); err != nil {
+			return ErrInvalidSender
+		}
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if bundle.BlockNumber.Cmp(pool.chain.CurrentBlock().Number()) <= 0 {
+		return ErrStaleBundle
+	}
+	if uint64(len(pool.bundles)) >= pool.config.GlobalQueue {
+		return ErrBundlePoolFull
+	}
+	pool.bundles = append(pool.bundles, bundle)
+	bundleGauge.Update(int64(len(pool.bundles)))
+
+	log.Trace("Added transaction bundle", "txs", len(bundle.Txs), "block", bundle.BlockNumber)
+	return nil
+}
+
+// Bundles retrieves the bundles eligible for inclusion in a block with the given
+// number and timestamp, in submission order.
+func (pool *TxPool) Bundles(number *big.Int, timestamp uint64) []*TxBundle {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	var bundles []*TxBundle
+	for _, bundle := range pool.bundles {
+		if bundle.BlockNumber.Cmp(number) != 0 {
+			continue
+		}
+		if bundle.MinTimestamp != 0 && timestamp < bundle.MinTimestamp {
+			continue
+		}
+		if bundle.MaxTimestamp != 0 && timestamp > bundle.MaxTimestamp {
+			continue
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles
+}
+
+// resetBundles drops the bundles targeting blocks up to and including the new
+// chain head. The pool lock must be held.
+func (pool *TxPool) resetBundles(head *types.Header) {
+	bundles := pool.bundles[:0]
+	for _, bundle := range pool.bundles {
+		if bundle.BlockNumber.Cmp(head.Number) > 0 {
+			bundles = append(bundles, bundle)
+		}
+	}
+	for i := len(bundles); i < len(pool.bundles); i++ {
+		pool.bundles[i] = nil
+	}
+	pool.bundles = bundles
+	bundleGauge.Update(int64(len(pool.bundles)))
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/core/state"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/log"
+	"github.com/ccm-chain/ccmchain/metrics"
+)
+
+// maxConditions is the maximum number of account nonces and storage slots a
+// conditional transaction may be made to depend on.
+const maxConditions = 64
+
+var (
+	// ErrConditionPremature is returned if a conditional transaction cannot be
+	// included yet, as its minimum block number was not reached.
+	ErrConditionPremature = errors.New("condition block number not yet reached")
+
+	// ErrConditionExpired is returned if a conditional transaction cannot be
+	// included anymore, as its maximum block number or timestamp passed.
+	ErrConditionExpired = errors.New("condition block number or timestamp passed")
+
+	// ErrConditionState is returned if the state a conditional transaction is
+	// executed on does not match the expected account nonces or storage slots.
+	ErrConditionState = errors.New("condition state mismatch")
+
+	// ErrTooManyConditions is returned if a conditional transaction depends on
+	// more state entries than allowed.
+	ErrTooManyConditions = errors.New("too many conditions")
+
+	// ErrConditionalPoolFull is returned if a conditional transaction is submitted
+	// while the conditional sub-pool already holds the maximum number of them.
+	ErrConditionalPoolFull = errors.New("conditional transaction pool full")
+)
+
+var (
+	conditionalGauge       = metrics.NewRegisteredGauge("txpool/conditional", nil)
+	conditionalStaleMeter  = metrics.NewRegisteredMeter("txpool/conditional/stale", nil)
+	conditionalFailedMeter = metrics.NewRegisteredMeter("txpool/conditional/failed", nil)
+)
+
+// TxConditions is a set of preconditions a transaction may only be included
+// under. Nil fields impose no restriction.
+type TxConditions struct {
+	BlockNumberMin *big.Int                                       // Earliest block to include the transaction in
+	BlockNumberMax *big.Int                                       // Latest block to include the transaction in
+	TimestampMax   *uint64                                        // Latest block timestamp to include the transaction at
+	Nonces         map[common.Address]uint64                      // Expected nonces of accounts
+	Storage        map[common.Address]map[common.Hash]common.Hash // Expected values of storage slots
+}
+
+// size returns the number of state entries the conditions depend on.
+func (c *TxConditions) size() int {
+	size := len(c.Nonces)
+	for _, slots := range c.Storage {
+		size += len(slots)
+	}
+	return size
+}
+
+// Check verifies whether a transaction with these conditions may be included in
+// a block with the given number and timestamp, executing on top of the given
+// state.
+func (c *TxConditions) Check(number *big.Int, timestamp uint64, statedb *state.StateDB) error {
+	if c.BlockNumberMin != nil && number.Cmp(c.BlockNumberMin) < 0 {
+		return ErrConditionPremature
+	}
+	if c.BlockNumberMax != nil && number.Cmp(c.BlockNumberMax) > 0 {
+		return ErrConditionExpired
+	}
+	if c.TimestampMax != nil && timestamp > *c.TimestampMax {
+		return ErrConditionExpired
+	}
+	for addr, nonce := range c.Nonces {
+		if have := statedb.GetNonce(addr); have != nonce {
+			return fmt.Errorf("%w: account %x nonce %d, want %d", ErrConditionState, addr, have, nonce)
+		}
+	}
+	for addr, slots := range c.Storage {
+		for slot, value := range slots {
+			if have := statedb.GetState(addr, slot); have != value {
+				return fmt.Errorf("%w: account %x slot %x is %x, want %x", ErrConditionState, addr, slot, have, value)
+			}
+		}
+	}
+	return nil
+}
+
+// ConditionalTx is a transaction along with the preconditions of its inclusion.
+type ConditionalTx struct {
+	Tx         *types.Transaction
+	Conditions *TxConditions
+}
+
+// conditionalTx is a conditional transaction tracked by the pool.
+type conditionalTx struct {
+	ConditionalTx
+	from common.Address
+}
+
+// AddConditional validates a transaction along with its inclusion preconditions
+// and places it into the conditional sub-pool. Conditional transactions are not
+// announced to the network, they are only included by the local miner if their
+// conditions hold at the time of execution. The conditions are rechecked on every
+// new head and the transaction dropped as soon as they cannot be met anymore.
+func (pool *TxPool) AddConditional(tx *types.Transaction, conditions *TxConditions) error {
+	if conditions.size() > maxConditions {
+		return ErrTooManyConditions
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	hash := tx.Hash()
+	if _, ok := pool.conditional[hash]; ok || pool.all.Get(hash) != nil {
+		knownTxMeter.Mark(1)
+		return ErrAlreadyKnown
+	}
+	if err := pool.validateTx(tx, true); err != nil {
+		invalidTxMeter.Mark(1)
+		return err
+	}
+	// Reject the transaction if its conditions already fail for the next block
+	head := pool.chain.CurrentBlock().Header()
+	if err := conditions.Check(new(big.Int).Add(head.Number, common.Big1), head.Time+1, pool.currentState); err != nil && err != ErrConditionPremature {
+		return err
+	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	for old, entry := range pool.conditional {
+		if entry.from != from || entry.Tx.Nonce() != tx.Nonce() {
+			continue
+		}
+		// Replace an existing conditional transaction only with the usual price bump
+		threshold := new(big.Int).Div(new(big.Int).Mul(entry.Tx.GasPrice(), big.NewInt(100+int64(pool.config.PriceBump))), big.NewInt(100))
+		if tx.GasPriceIntCmp(threshold) < 0 {
+			return ErrReplaceUnderpriced
+		}
+		delete(pool.conditional, old)
+	}
+	if uint64(len(pool.conditional)) >= pool.config.GlobalSlots {
+		return ErrConditionalPoolFull
+	}
+	pool.conditional[hash] = &conditionalTx{
+		ConditionalTx: ConditionalTx{Tx: tx, Conditions: conditions},
+		from:          from,
+	}
+	conditionalGauge.Update(int64(len(pool.conditional)))
+
+	log.Trace("Added conditional transaction", "hash", hash, "from", from, "nonce", tx.Nonce())
+	return nil
+}
+
+// Conditional retrieves the conditional transactions which are executable nonce
+// wise, grouped by origin account and sorted by nonce. Their conditions need to
+// be checked against the state they are executed on.
+func (pool *TxPool) Conditional() map[common.Address][]*ConditionalTx {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	conditional := make(map[common.Address][]*ConditionalTx)
+	for _, entry := range pool.conditional {
+		conditional[entry.from] = append(conditional[entry.from], &entry.ConditionalTx)
+	}
+	for addr, txs := range conditional {
+		sort.Slice(txs, func(i, j int) bool { return txs[i].Tx.Nonce() < txs[j].Tx.Nonce() })
+
+		// Only keep the gapless sequence starting at the current account nonce
+		next := pool.currentState.GetNonce(addr)
+		for i, entry := range txs {
+			if entry.Tx.Nonce() != next {
+				txs = txs[:i]
+				break
+			}
+			next++
+		}
+		if len(txs) == 0 {
+			delete(conditional, addr)
+		} else {
+			conditional[addr] = txs
+		}
+	}
+	return conditional
+}
+
+// resetConditional rechecks the conditional transactions against the new chain
+// head, dropping the ones which were included or superseded, as well as the ones
+// whose conditions cannot be met by the next block. Transactions waiting for a
+// later block number are retained. The pool lock must be held.
+func (pool *TxPool) resetConditional(head *types.Header) {
+	number := new(big.Int).Add(head.Number, common.Big1)
+	for hash, entry := range pool.conditional {
+		if pool.currentState.GetNonce(entry.from) > entry.Tx.Nonce() {
+			log.Trace("Removing stale conditional transaction", "hash", hash)
+			conditionalStaleMeter.Mark(1)
+			delete(pool.conditional, hash)
+			continue
+		}
+		if err := entry.Conditions.Check(number, head.Time+1, pool.currentState); err != nil && err != ErrConditionPremature {
+			log.Debug("Removing failed conditional transaction", "hash", hash, "err", err)
+			conditionalFailedMeter.Mark(1)
+			delete(pool.conditional, hash)
+		}
+	}
+	conditionalGauge.Update(int64(len(pool.conditional)))
+}
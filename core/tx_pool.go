@@ -112,6 +112,43 @@ var (
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
 )
 
+// TxDropReason is the reason a transaction was dropped from the pool.
+type TxDropReason string
+
+const (
+	// TxDropReplaced is the reason of transactions replaced by another one with
+	// the same nonce and a higher gas price.
+	TxDropReplaced TxDropReason = "replaced"
+
+	// TxDropUnderpriced is the reason of transactions evicted to make room for
+	// better paying ones, or falling below the minimum gas price of the pool.
+	TxDropUnderpriced TxDropReason = "underpriced"
+
+	// TxDropNonceTooLow is the reason of transactions whose nonce was used up by
+	// another transaction. Transactions included in a block aren't dropped.
+	TxDropNonceTooLow TxDropReason = "nonce too low"
+
+	// TxDropExpired is the reason of transactions queued for longer than the
+	// configured lifetime.
+	TxDropExpired TxDropReason = "expired"
+
+	// TxDropBalance is the reason of transactions the sender can no longer pay
+	// for, or exceeding the block gas limit.
+	TxDropBalance TxDropReason = "balance"
+
+	// TxDropOverflow is the reason of transactions dropped to keep the pool and
+	// its accounts within their configured limits.
+	TxDropOverflow TxDropReason = "overflow"
+)
+
+// DroppedTx is a transaction dropped from the pool, along with the reason and
+// the hash of the transaction replacing it, if any.
+type DroppedTx struct {
+	Hash        common.Hash
+	Reason      TxDropReason
+	Replacement common.Hash
+}
+
 // TxStatus is the current status of a transaction as seen by the pool.
 type TxStatus uint
 
@@ -139,6 +176,9 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	Snapshot   string        // Snapshot of remote transactions to warm restart with (empty = disabled)
+	Resnapshot time.Duration // Time interval to regenerate the remote transaction snapshot
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -148,6 +188,12 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	PrivateLifetime uint64 // Number of blocks private transactions are withheld before being published
+
+	PeerRateLimit   float64 // Transactions per second admitted from a single peer (0 = unlimited)
+	SenderRateLimit float64 // Transactions per second admitted from a single remote sender (0 = unlimited)
+	ClientRateLimit float64 // Transactions per second admitted from a single RPC client IP (0 = unlimited)
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -156,6 +202,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	Resnapshot: 10 * time.Minute,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -165,6 +213,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	PrivateLifetime: 25,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -175,6 +225,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.Resnapshot < time.Second {
+		log.Warn("Sanitizing invalid txpool snapshot time", "provided", conf.Resnapshot, "updated", time.Second)
+		conf.Resnapshot = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -203,6 +257,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
 	}
+	if conf.PrivateLifetime < 1 {
+		log.Warn("Sanitizing invalid txpool private lifetime", "provided", conf.PrivateLifetime, "updated", DefaultTxPoolConfig.PrivateLifetime)
+		conf.PrivateLifetime = DefaultTxPoolConfig.PrivateLifetime
+	}
 	return conf
 }
 
@@ -219,6 +277,7 @@ type TxPool struct {
 	chain       blockChain
 	gasPrice    *big.Int
 	txFeed      event.Feed
+	dropFeed    event.Feed
 	scope       event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
@@ -232,12 +291,23 @@ type TxPool struct {
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
+	snapshot *txSnapshot    // Snapshot of remote transactions to back up to disk
+	senders  *TxRateLimiter // Admission rate limiter of remote transaction senders
+
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
 
+	private map[common.Hash]*privateTx // Transactions withheld from the network for the local miner
+	bundles []*TxBundle                // Transaction bundles to be included atomically by the local miner
+
+	conditional map[common.Hash]*conditionalTx // Transactions only included while their preconditions hold
+
+	dropped  []*DroppedTx             // Transactions dropped since the last announcement
+	included map[common.Hash]struct{} // Transactions included by the blocks of the current reset, not reported as dropped
+
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
 	reqResetCh      chan *txpoolResetRequest
@@ -268,6 +338,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
 		all:             newTxLookup(),
+		private:         make(map[common.Hash]*privateTx),
+		conditional:     make(map[common.Hash]*conditionalTx),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -299,6 +371,18 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction snapshots are enabled, warm up the pool from disk
+	if config.Snapshot != "" {
+		pool.snapshot = newTxSnapshot(config.Snapshot)
+
+		if err := pool.snapshot.load(pool.AddRemotesSync); err != nil {
+			log.Warn("Failed to load transaction snapshot", "err", err)
+		}
+	}
+	// Limit the admission rate of remote senders only after the warm restart
+	pool.mu.Lock()
+	pool.senders = NewTxRateLimiter(config.SenderRateLimit)
+	pool.mu.Unlock()
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -317,15 +401,17 @@ func (pool *TxPool) loop() {
 	var (
 		prevPending, prevQueued, prevStales int
 		// Start the stats reporting and transaction eviction tickers
-		report  = time.NewTicker(statsReportInterval)
-		evict   = time.NewTicker(evictionInterval)
-		journal = time.NewTicker(pool.config.Rejournal)
+		report   = time.NewTicker(statsReportInterval)
+		evict    = time.NewTicker(evictionInterval)
+		journal  = time.NewTicker(pool.config.Rejournal)
+		snapshot = time.NewTicker(pool.config.Resnapshot)
 		// Track the previous head headers for transaction reorgs
 		head = pool.chain.CurrentBlock()
 	)
 	defer report.Stop()
 	defer evict.Stop()
 	defer journal.Stop()
+	defer snapshot.Stop()
 
 	for {
 		select {
@@ -366,11 +452,13 @@ func (pool *TxPool) loop() {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
 						pool.removeTx(tx.Hash(), true)
+						pool.dropTx(tx.Hash(), TxDropExpired, common.Hash{})
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
+			pool.announceDrops()
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -381,6 +469,12 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+
+		// Handle remote transaction snapshot regeneration
+		case <-snapshot.C:
+			if pool.snapshot != nil {
+				pool.saveSnapshot()
+			}
 		}
 	}
 }
@@ -397,6 +491,9 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.snapshot != nil {
+		pool.saveSnapshot()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -406,6 +503,50 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeDroppedTxsEvent registers a subscription of DroppedTxsEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeDroppedTxsEvent(ch chan<- DroppedTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// dropTx records a transaction dropped from the pool, to be announced once the
+// pool lock is released. The pool lock must be held.
+func (pool *TxPool) dropTx(hash common.Hash, reason TxDropReason, replacement common.Hash) {
+	pool.dropped = append(pool.dropped, &DroppedTx{Hash: hash, Reason: reason, Replacement: replacement})
+}
+
+// dropTxs records a batch of transactions dropped from the pool for the same
+// reason. The pool lock must be held.
+func (pool *TxPool) dropTxs(txs types.Transactions, reason TxDropReason) {
+	for _, tx := range txs {
+		pool.dropTx(tx.Hash(), reason, common.Hash{})
+	}
+}
+
+// dropUsedNonces records a batch of transactions whose nonce was used up, except
+// for the ones included by the blocks of the current reset, which weren't
+// dropped but mined. The pool lock must be held.
+func (pool *TxPool) dropUsedNonces(txs types.Transactions) {
+	for _, tx := range txs {
+		if _, ok := pool.included[tx.Hash()]; !ok {
+			pool.dropTx(tx.Hash(), TxDropNonceTooLow, common.Hash{})
+		}
+	}
+}
+
+// announceDrops sends out the transactions dropped since the last announcement.
+// The pool lock must not be held.
+func (pool *TxPool) announceDrops() {
+	pool.mu.Lock()
+	dropped := pool.dropped
+	pool.dropped = nil
+	pool.mu.Unlock()
+
+	if len(dropped) > 0 {
+		pool.dropFeed.Send(DroppedTxsEvent{dropped})
+	}
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -418,12 +559,14 @@ func (pool *TxPool) GasPrice() *big.Int {
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
 	pool.mu.Lock()
-	defer pool.mu.Unlock()
-
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
 		pool.removeTx(tx.Hash(), false)
+		pool.dropTx(tx.Hash(), TxDropUnderpriced, common.Hash{})
 	}
+	pool.mu.Unlock()
+	pool.announceDrops()
+
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
@@ -514,6 +657,36 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, split into pending
+// and queued ones, grouped by origin account and sorted by nonce.
+func (pool *TxPool) remote() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	pending := make(map[common.Address]types.Transactions)
+	for addr, list := range pool.pending {
+		if !pool.locals.contains(addr) {
+			pending[addr] = list.Flatten()
+		}
+	}
+	queued := make(map[common.Address]types.Transactions)
+	for addr, list := range pool.queue {
+		if !pool.locals.contains(addr) {
+			queued[addr] = list.Flatten()
+		}
+	}
+	return pending, queued
+}
+
+// saveSnapshot regenerates the remote transaction snapshot from the current
+// contents of the pool, bounded by the global pool limits.
+func (pool *TxPool) saveSnapshot() {
+	pool.mu.RLock()
+	pending, queued := pool.remote()
+	pool.mu.RUnlock()
+
+	if err := pool.snapshot.save(pending, queued, pool.config.GlobalSlots, pool.config.GlobalQueue); err != nil {
+		log.Warn("Failed to save remote tx snapshot", "err", err)
+	}
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -595,6 +768,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxMeter.Mark(1)
 			pool.removeTx(tx.Hash(), false)
+			pool.dropTx(tx.Hash(), TxDropUnderpriced, hash)
 		}
 	}
 	// Try to replace an existing transaction in the pending pool
@@ -610,6 +784,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		if old != nil {
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
+			pool.dropTx(old.Hash(), TxDropReplaced, hash)
 			pendingReplaceMeter.Mark(1)
 		}
 		pool.all.Add(tx)
@@ -662,6 +837,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	if old != nil {
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
+		pool.dropTx(old.Hash(), TxDropReplaced, hash)
 		queuedReplaceMeter.Mark(1)
 	} else {
 		// Nothing was replaced, bump the queued counter
@@ -706,6 +882,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		// An older transaction was better, discard this
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
+		pool.dropTx(hash, TxDropReplaced, list.txs.Get(tx.Nonce()).Hash())
 		pendingDiscardMeter.Mark(1)
 		return false
 	}
@@ -713,6 +890,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	if old != nil {
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
+		pool.dropTx(old.Hash(), TxDropReplaced, hash)
 		pendingReplaceMeter.Mark(1)
 	} else {
 		// Nothing was replaced, bump the pending counter
@@ -808,8 +986,9 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, local, true)
 	pool.mu.Unlock()
+	pool.announceDrops()
 
 	var nilSlot = 0
 	for _, err := range newErrs {
@@ -827,12 +1006,24 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	return errs
 }
 
-// addTxsLocked attempts to queue a batch of transactions if they are valid.
-// The transaction pool lock must be held.
-func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error, *accountSet) {
+// addTxsLocked attempts to queue a batch of transactions if they are valid. If
+// limit is set, remote transactions are subject to the admission rate of their
+// sender, which doesn't apply to transactions the pool re-adds by itself, such
+// as the ones reinjected after a reorg. The transaction pool lock must be held.
+func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local, limit bool) ([]error, *accountSet) {
 	dirty := newAccountSet(pool.signer)
 	errs := make([]error, len(txs))
 	for i, tx := range txs {
+		// If the remote sender is flooding the pool, discard the transaction
+		if limit && !local {
+			from, _ := types.Sender(pool.signer, tx) // already validated
+			if !pool.locals.contains(from) && !pool.senders.Allow(from, 1) {
+				log.Trace("Discarding rate limited transaction", "hash", tx.Hash(), "from", from)
+				senderRateLimitMeter.Mark(1)
+				errs[i] = ErrTxRateLimited
+				continue
+			}
+		}
 		replaced, err := pool.add(tx, local)
 		errs[i] = err
 		if err == nil && !replaced {
@@ -1044,6 +1235,18 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		// Reset from the old head to the new, rescheduling any reorged transactions
 		pool.reset(reset.oldHead, reset.newHead)
 
+		// Publish any private transactions that were not included in time, drop
+		// the bundles that missed their target block and recheck the conditional
+		// transactions against the new head
+		head := reset.newHead
+		if head == nil {
+			head = pool.chain.CurrentBlock().Header() // Special case during testing
+		}
+		if expired := pool.resetPrivate(head); len(expired) > 0 {
+			pool.addTxsLocked(expired, true, false)
+		}
+		pool.resetBundles(head)
+		pool.resetConditional(head)
 		// Nonces were reset, discard any events that became stale
 		for addr := range events {
 			events[addr].Forward(pool.pendingNonces.get(addr))
@@ -1075,7 +1278,9 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		highestPending := list.LastElement()
 		pool.pendingNonces.set(addr, highestPending.Nonce()+1)
 	}
+	pool.included = nil
 	pool.mu.Unlock()
+	pool.announceDrops()
 
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
@@ -1100,6 +1305,12 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
 	var reinject types.Transactions
 
+	if oldHead != nil && oldHead.Hash() == newHead.ParentHash {
+		// Transactions of the new head were mined, not dropped
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			pool.markIncluded(block.Transactions())
+		}
+	}
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
 		oldNum := oldHead.Number.Uint64()
@@ -1157,6 +1368,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				}
 			}
 			reinject = types.TxDifference(discarded, included)
+			pool.markIncluded(included)
 		}
 	}
 	// Initialize the internal state to the current head
@@ -1175,7 +1387,18 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, false, false)
+}
+
+// markIncluded records transactions included by the blocks of the current reset,
+// so they aren't reported as dropped when removed for their used up nonce.
+func (pool *TxPool) markIncluded(txs types.Transactions) {
+	if pool.included == nil {
+		pool.included = make(map[common.Hash]struct{}, len(txs))
+	}
+	for _, tx := range txs {
+		pool.included[tx.Hash()] = struct{}{}
+	}
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1197,6 +1420,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			hash := tx.Hash()
 			pool.all.Remove(hash)
 		}
+		pool.dropUsedNonces(forwards)
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
@@ -1204,6 +1428,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			hash := tx.Hash()
 			pool.all.Remove(hash)
 		}
+		pool.dropTxs(drops, TxDropBalance)
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
@@ -1227,6 +1452,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 				pool.all.Remove(hash)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
+			pool.dropTxs(caps, TxDropOverflow)
 			queuedRateLimitMeter.Mark(int64(len(caps)))
 		}
 		// Mark all the items dropped as removed
@@ -1293,6 +1519,7 @@ func (pool *TxPool) truncatePending() {
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
 					pool.priced.Removed(len(caps))
+					pool.dropTxs(caps, TxDropOverflow)
 					pendingGauge.Dec(int64(len(caps)))
 					if pool.locals.contains(offenders[i]) {
 						localGauge.Dec(int64(len(caps)))
@@ -1320,6 +1547,7 @@ func (pool *TxPool) truncatePending() {
 					log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 				}
 				pool.priced.Removed(len(caps))
+				pool.dropTxs(caps, TxDropOverflow)
 				pendingGauge.Dec(int64(len(caps)))
 				if pool.locals.contains(addr) {
 					localGauge.Dec(int64(len(caps)))
@@ -1361,6 +1589,7 @@ func (pool *TxPool) truncateQueue() {
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
 				pool.removeTx(tx.Hash(), true)
+				pool.dropTx(tx.Hash(), TxDropOverflow, common.Hash{})
 			}
 			drop -= size
 			queuedRateLimitMeter.Mark(int64(size))
@@ -1370,6 +1599,7 @@ func (pool *TxPool) truncateQueue() {
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 			pool.removeTx(txs[i].Hash(), true)
+			pool.dropTx(txs[i].Hash(), TxDropOverflow, common.Hash{})
 			drop--
 			queuedRateLimitMeter.Mark(1)
 		}
@@ -1399,6 +1629,8 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 		}
 		pool.priced.Removed(len(olds) + len(drops))
+		pool.dropUsedNonces(olds)
+		pool.dropTxs(drops, TxDropBalance)
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
 		for _, tx := range invalids {
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
@@ -24,6 +25,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -1850,6 +1852,308 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are snapshotted on shutdown along with their
+// arrival times, and are revalidated when the pool is restarted from them.
+func TestTransactionSnapshot(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the snapshot
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary snapshot: %v", err)
+	}
+	snapshot := file.Name()
+	defer os.Remove(snapshot)
+
+	file.Close()
+	os.Remove(snapshot)
+
+	// Create the original pool and fill it with pending and queued remotes
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Snapshot = snapshot
+	config.GlobalQueue = 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.AddLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), remote),
+		pricedTransaction(1, 100000, big.NewInt(1), remote),
+		pricedTransaction(3, 100000, big.NewInt(1), remote),
+	}
+	for i, tx := range txs {
+		tx.SetTime(time.Unix(int64(i+1), 0))
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 3, 1)
+	}
+	// Restart the pool and ensure only the remotes were restored, with their times
+	pool.Stop()
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("restored pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	for i, tx := range txs {
+		restored := pool.Get(tx.Hash())
+		if restored == nil {
+			t.Fatalf("transaction %d not restored", i)
+		}
+		if !restored.Time().Equal(tx.Time()) {
+			t.Errorf("transaction %d arrival time mismatch: have %v, want %v", i, restored.Time(), tx.Time())
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Include the first remote transaction and ensure it's dropped on the next restart
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 1)
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("revalidated pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 1, 1)
+	}
+	if pool.Get(txs[0].Hash()) != nil {
+		t.Errorf("stale transaction restored")
+	}
+}
+
+// Tests that the pool can be queried with filters and paginated over, and that
+// queued transactions are annotated with the reason they are not executable.
+func TestTransactionQuery(t *testing.T) {
+	t.Parallel()
+
+	pool, local := setupTxPool()
+	defer pool.Stop()
+
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Add two pending and a gapped local transaction, and a gapped remote one
+	for _, nonce := range []uint64{0, 1, 3} {
+		if err := pool.AddLocal(pricedTransaction(nonce, 100000, big.NewInt(int64(nonce+1)), local)); err != nil {
+			t.Fatalf("failed to add local transaction %d: %v", nonce, err)
+		}
+	}
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	var (
+		yes = true
+		no  = false
+		gap = "nonce gap: missing nonce 2"
+	)
+	if pool.locals.contains(crypto.PubkeyToAddress(remote.PublicKey)) {
+		t.Fatalf("remote account treated as local")
+	}
+	tests := []struct {
+		query TxPoolQuery
+		want  int
+	}{
+		{TxPoolQuery{}, 4},
+		{TxPoolQuery{Pending: &yes}, 2},
+		{TxPoolQuery{Pending: &no}, 2},
+		{TxPoolQuery{NonceGap: &yes}, 2},
+		{TxPoolQuery{Local: &no}, 1},
+		{TxPoolQuery{MinGasPrice: big.NewInt(2)}, 2},
+		{TxPoolQuery{Local: &yes, Pending: &no}, 1},
+	}
+	for i, tt := range tests {
+		entries, cursor := pool.Query(tt.query)
+		if len(entries) != tt.want {
+			t.Errorf("test %d: entry count mismatch: have %d, want %d", i, len(entries), tt.want)
+		}
+		if cursor != nil {
+			t.Errorf("test %d: unexpected cursor %v", i, cursor)
+		}
+	}
+	// Check the annotations of the queued transactions
+	from := crypto.PubkeyToAddress(local.PublicKey)
+	entries, _ := pool.Query(TxPoolQuery{From: &from, Pending: &no})
+	if len(entries) != 1 || !entries[0].NonceGap || !entries[0].Local || entries[0].Reason != gap {
+		t.Fatalf("queued local entry mismatch: %+v", entries)
+	}
+	from = crypto.PubkeyToAddress(remote.PublicKey)
+	if entries, _ = pool.Query(TxPoolQuery{From: &from}); len(entries) != 1 || entries[0].Reason != "nonce gap: missing nonce 0" {
+		t.Fatalf("queued remote entry mismatch: %+v", entries)
+	}
+	// Iterate over the pool in pages and ensure all transactions are returned in order
+	var (
+		query = TxPoolQuery{Limit: 3}
+		seen  []*TxPoolEntry
+	)
+	for {
+		entries, cursor := pool.Query(query)
+		seen = append(seen, entries...)
+		if cursor == nil {
+			break
+		}
+		query.After = cursor
+	}
+	if len(seen) != 4 {
+		t.Fatalf("paginated entry count mismatch: have %d, want %d", len(seen), 4)
+	}
+	for i := 1; i < len(seen); i++ {
+		prev, cur := seen[i-1], seen[i]
+		if c := bytes.Compare(prev.From[:], cur.From[:]); c > 0 || (c == 0 && prev.Tx.Nonce() >= cur.Tx.Nonce()) {
+			t.Errorf("entry %d out of order: %x/%d after %x/%d", i, cur.From, cur.Tx.Nonce(), prev.From, prev.Tx.Nonce())
+		}
+	}
+}
+
+// Tests that transactions dropped from the pool are announced along with the
+// reason they were dropped for.
+func TestTransactionDropEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+
+	drops := make(chan DroppedTxsEvent, 16)
+	sub := pool.SubscribeDroppedTxsEvent(drops)
+	defer sub.Unsubscribe()
+
+	expect := func(want ...*DroppedTx) {
+		t.Helper()
+		var have []*DroppedTx
+		for len(have) < len(want) {
+			select {
+			case ev := <-drops:
+				have = append(have, ev.Txs...)
+			case <-time.After(time.Second):
+				t.Fatalf("drop event timeout: have %d, want %d", len(have), len(want))
+			}
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("dropped transactions mismatch: have %v, want %v", have, want)
+		}
+	}
+	// Replace a pending transaction and ensure the replacement is reported
+	tx0 := pricedTransaction(0, 100000, big.NewInt(1), key)
+	tx1 := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(tx0); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(tx1); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	expect(&DroppedTx{Hash: tx0.Hash(), Reason: TxDropReplaced, Replacement: tx1.Hash()})
+
+	// Raise the price threshold of the pool to drop a cheap transaction
+	tx2 := pricedTransaction(1, 100000, big.NewInt(1), key)
+	if err := pool.addRemoteSync(tx2); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	pool.SetGasPrice(big.NewInt(2))
+	expect(&DroppedTx{Hash: tx2.Hash(), Reason: TxDropUnderpriced})
+
+	// Consume the nonce of the remaining transaction and ensure it's dropped
+	pool.currentState.SetNonce(from, 1)
+	<-pool.requestReset(nil, nil)
+	expect(&DroppedTx{Hash: tx1.Hash(), Reason: TxDropNonceTooLow})
+}
+
+// Tests that remote senders exceeding their admission rate are rejected, even
+// if their transactions are valid replacements, while locals are exempt.
+func TestTransactionSenderRateLimit(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.SenderRateLimit = 0.1 // Burst allowance of a single transaction
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(2), remote)); err != ErrTxRateLimited {
+		t.Fatalf("replacement error mismatch: have %v, want %v", err, ErrTxRateLimited)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(1), remote)); err != ErrTxRateLimited {
+		t.Fatalf("remote error mismatch: have %v, want %v", err, ErrTxRateLimited)
+	}
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := pool.AddLocal(pricedTransaction(nonce, 100000, big.NewInt(1), local)); err != nil {
+			t.Fatalf("failed to add local transaction %d: %v", nonce, err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 4 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 4)
+	}
+}
+
+// Tests that remote transactions reinjected after a reorg are not subject to the
+// admission rate of their sender.
+func TestTransactionSenderRateLimitReorg(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &minedTestBlockChain{testBlockChain: &testBlockChain{statedb, 1000000, new(event.Feed)}}
+
+	config := testTxPoolConfig
+	config.SenderRateLimit = 0.1 // Burst allowance of a single transaction
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+
+	// Use up the allowance of the sender and mine its transaction
+	tx := pricedTransaction(0, 100000, big.NewInt(1), key)
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	parent := blockchain.CurrentBlock().Header()
+	mined := blockchain.mine(parent, tx)
+	statedb.SetNonce(from, 1)
+	<-pool.requestReset(parent, mined.Header())
+
+	if pending, _ := pool.Stats(); pending != 0 {
+		t.Fatalf("pending transactions mismatched after mining: have %d, want %d", pending, 0)
+	}
+	// Reorg the transaction out of the chain and ensure it's reinjected
+	sibling := blockchain.mine(parent)
+	statedb.SetNonce(from, 0)
+	<-pool.requestReset(mined.Header(), sibling.Header())
+
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatched after reorg: have %d, want %d", pending, 1)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -1906,6 +2210,197 @@ func TestTransactionStatusCheck(t *testing.T) {
 	}
 }
 
+// Tests that private transactions are withheld from the network, handed to the
+// miner until included, and published once they expire.
+func TestTransactionPrivate(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	events := make(chan NewTxsEvent, 32)
+	sub := pool.txFeed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+
+	// Add two private transactions and ensure they are not visible publicly
+	txs := types.Transactions{transaction(0, 100000, key), transaction(1, 100000, key)}
+	for i, tx := range txs {
+		if err := pool.AddPrivate(tx); err != nil {
+			t.Fatalf("failed to add private transaction %d: %v", i, err)
+		}
+	}
+	if err := pool.AddPrivate(txs[0]); err != ErrAlreadyKnown {
+		t.Fatalf("duplicate private transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("private transactions leaked into the pool: pending %d, queued %d", pending, queued)
+	}
+	if err := validateEvents(events, 0); err != nil {
+		t.Fatalf("private transactions announced: %v", err)
+	}
+	if private := pool.Private(); len(private[from]) != 2 {
+		t.Fatalf("private transaction count mismatch: have %d, want %d", len(private[from]), 2)
+	}
+	// Include the first transaction and ensure it's dropped from the private pool
+	statedb.SetNonce(from, 1)
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: 1000000})
+
+	if private := pool.Private(); len(private[from]) != 1 || private[from][0].Hash() != txs[1].Hash() {
+		t.Fatalf("private transactions mismatch after inclusion: have %v", private[from])
+	}
+	// Expire the remaining transaction and ensure it's published
+	<-pool.requestReset(nil, &types.Header{Number: new(big.Int).SetUint64(testTxPoolConfig.PrivateLifetime), GasLimit: 1000000})
+
+	if private := pool.Private(); len(private) != 0 {
+		t.Fatalf("expired private transactions retained: %v", private)
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if err := validateEvents(events, 1); err != nil {
+		t.Fatalf("expired private transaction not announced: %v", err)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that transaction bundles are validated, filtered by their target block
+// and timestamp, and dropped once their target block is mined.
+func TestTransactionBundles(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	if err := pool.AddBundle(&TxBundle{BlockNumber: big.NewInt(1)}); err != ErrEmptyBundle {
+		t.Fatalf("empty bundle error mismatch: have %v, want %v", err, ErrEmptyBundle)
+	}
+	txs := types.Transactions{transaction(0, 100000, key), transaction(1, 100000, key)}
+	if err := pool.AddBundle(&TxBundle{Txs: txs, BlockNumber: big.NewInt(0)}); err != ErrStaleBundle {
+		t.Fatalf("stale bundle error mismatch: have %v, want %v", err, ErrStaleBundle)
+	}
+	bundles := []*TxBundle{
+		{Txs: txs, BlockNumber: big.NewInt(1)},
+		{Txs: txs, BlockNumber: big.NewInt(1), MinTimestamp: 10, MaxTimestamp: 20},
+		{Txs: txs, BlockNumber: big.NewInt(2)},
+	}
+	for i, bundle := range bundles {
+		if err := pool.AddBundle(bundle); err != nil {
+			t.Fatalf("failed to add bundle %d: %v", i, err)
+		}
+	}
+	tests := []struct {
+		number, time uint64
+		want         []*TxBundle
+	}{
+		{1, 5, bundles[:1]},
+		{1, 15, bundles[:2]},
+		{1, 25, bundles[:1]},
+		{2, 15, bundles[2:]},
+		{3, 15, nil},
+	}
+	for i, tt := range tests {
+		if have := pool.Bundles(new(big.Int).SetUint64(tt.number), tt.time); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: bundles mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("bundle transactions leaked into the pool: pending %d, queued %d", pending, queued)
+	}
+	// Mine the first target block and ensure its bundles are dropped
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: 1000000})
+
+	if have := pool.Bundles(big.NewInt(1), 15); len(have) != 0 {
+		t.Errorf("stale bundles retained: %v", have)
+	}
+	if have := pool.Bundles(big.NewInt(2), 15); len(have) != 1 {
+		t.Errorf("future bundles dropped: have %d, want %d", len(have), 1)
+	}
+}
+
+// Tests that conditional transactions are withheld from the public pool, rejected
+// if their conditions already fail, and dropped once they cannot be met anymore.
+func TestTransactionConditional(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+
+	var (
+		contract = common.HexToAddress("0xc0de")
+		slot     = common.HexToHash("0x01")
+		value    = common.HexToHash("0xff")
+	)
+	statedb.SetState(contract, slot, value)
+
+	// Reject transactions whose conditions already fail
+	if err := pool.AddConditional(transaction(0, 100000, key), &TxConditions{BlockNumberMax: big.NewInt(0)}); err != ErrConditionExpired {
+		t.Fatalf("expired condition error mismatch: have %v, want %v", err, ErrConditionExpired)
+	}
+	if err := pool.AddConditional(transaction(0, 100000, key), &TxConditions{Nonces: map[common.Address]uint64{contract: 1}}); !errors.Is(err, ErrConditionState) {
+		t.Fatalf("state condition error mismatch: have %v, want %v", err, ErrConditionState)
+	}
+	// Add transactions with valid and premature conditions
+	txs := types.Transactions{transaction(0, 100000, key), transaction(1, 100000, key), transaction(2, 100000, key)}
+	conditions := []*TxConditions{
+		{Storage: map[common.Address]map[common.Hash]common.Hash{contract: {slot: value}}},
+		{BlockNumberMin: big.NewInt(3)},
+		{BlockNumberMax: big.NewInt(2)},
+	}
+	for i, tx := range txs {
+		if err := pool.AddConditional(tx, conditions[i]); err != nil {
+			t.Fatalf("failed to add conditional transaction %d: %v", i, err)
+		}
+	}
+	if err := pool.AddConditional(txs[0], conditions[0]); err != ErrAlreadyKnown {
+		t.Fatalf("duplicate conditional transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("conditional transactions leaked into the pool: pending %d, queued %d", pending, queued)
+	}
+	if conditional := pool.Conditional(); len(conditional[from]) != 3 {
+		t.Fatalf("conditional transaction count mismatch: have %d, want %d", len(conditional[from]), 3)
+	}
+	// Change the watched storage slot and ensure the dependent transaction is dropped
+	statedb.SetState(contract, slot, common.Hash{})
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: 1000000})
+
+	if conditional := pool.Conditional(); len(conditional) != 0 {
+		t.Fatalf("gapped conditional transactions returned: %v", conditional)
+	}
+	if len(pool.conditional) != 2 {
+		t.Fatalf("conditional transaction count mismatch: have %d, want %d", len(pool.conditional), 2)
+	}
+	// Pass the maximum block of the last transaction and ensure only the premature one is kept
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(2), GasLimit: 1000000})
+
+	if len(pool.conditional) != 1 || pool.conditional[txs[1].Hash()] == nil {
+		t.Fatalf("conditional transactions mismatch: have %v, want %x", pool.conditional, txs[1].Hash())
+	}
+	// Include a transaction with the same nonce and ensure it's dropped as stale
+	statedb.SetNonce(from, 2)
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(3), GasLimit: 1000000})
+
+	if len(pool.conditional) != 0 {
+		t.Fatalf("stale conditional transactions retained: %v", pool.conditional)
+	}
+}
+
 // Test the transaction slots consumption is computed correctly
 func TestTransactionSlotCount(t *testing.T) {
 	t.Parallel()
@@ -2008,3 +2503,76 @@ func benchmarkPoolBatchInsert(b *testing.B, size int, local bool) {
 		}
 	}
 }
+
+// minedTestBlockChain is a testBlockChain also serving a set of mined blocks.
+type minedTestBlockChain struct {
+	*testBlockChain
+	blocks map[common.Hash]*types.Block
+}
+
+// mine creates a block on top of parent including the given transactions.
+func (bc *minedTestBlockChain) mine(parent *types.Header, txs ...*types.Transaction) *types.Block {
+	block := types.NewBlock(&types.Header{ParentHash: parent.Hash(), Number: new(big.Int).Add(parent.Number, common.Big1), GasLimit: parent.GasLimit}, txs, nil, nil, new(trie.Trie))
+	if bc.blocks == nil {
+		bc.blocks = make(map[common.Hash]*types.Block)
+	}
+	bc.blocks[block.Hash()] = block
+	return block
+}
+
+func (bc *minedTestBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block, ok := bc.blocks[hash]; ok {
+		return block
+	}
+	return bc.testBlockChain.GetBlock(hash, number)
+}
+
+// Tests that transactions included in a new block are not announced as dropped,
+// while the ones whose nonce was used up by other transactions are.
+func TestTransactionDropEventsMined(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &minedTestBlockChain{testBlockChain: &testBlockChain{statedb, 1000000, new(event.Feed)}}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+
+	drops := make(chan DroppedTxsEvent, 16)
+	sub := pool.SubscribeDroppedTxsEvent(drops)
+	defer sub.Unsubscribe()
+
+	tx0 := pricedTransaction(0, 100000, big.NewInt(1), key)
+	tx1 := pricedTransaction(1, 100000, big.NewInt(1), key)
+	for _, tx := range []*types.Transaction{tx0, tx1} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	// Mine a block including tx0 and another transaction using up the nonce of tx1
+	other := pricedTransaction(1, 100000, big.NewInt(2), key)
+	parent := blockchain.CurrentBlock().Header()
+	mined := blockchain.mine(parent, tx0, other)
+
+	statedb.SetNonce(from, 2)
+	<-pool.requestReset(parent, mined.Header())
+
+	select {
+	case ev := <-drops:
+		want := []*DroppedTx{{Hash: tx1.Hash(), Reason: TxDropNonceTooLow}}
+		if !reflect.DeepEqual(ev.Txs, want) {
+			t.Fatalf("dropped transactions mismatch: have %v, want %v", ev.Txs, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drop event timeout")
+	}
+	select {
+	case ev := <-drops:
+		t.Fatalf("unexpected drop event: %v", ev.Txs)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
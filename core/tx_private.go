@@ -0,0 +1,144 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/log"
+	"github.com/ccm-chain/ccmchain/metrics"
+)
+
+// ErrPrivatePoolFull is returned if a private transaction is submitted while the
+// private sub-pool already holds the maximum number of transactions.
+var ErrPrivatePoolFull = errors.New("private transaction pool full")
+
+var (
+	privateGauge        = metrics.NewRegisteredGauge("txpool/private", nil)
+	privateStaleMeter   = metrics.NewRegisteredMeter("txpool/private/stale", nil)
+	privateExpiredMeter = metrics.NewRegisteredMeter("txpool/private/expired", nil)
+)
+
+// privateTx is a transaction submitted for inclusion by the local miner only,
+// along with the block number at which it is released into the public pool.
+type privateTx struct {
+	tx     *types.Transaction
+	from   common.Address
+	expiry uint64
+}
+
+// AddPrivate validates a transaction and places it into the private sub-pool.
+// Private transactions are never announced to the network: they are only picked
+// up by the local miner, and once the configured number of blocks passed without
+// them being included, they are submitted to the public pool as local ones.
+func (pool *TxPool) AddPrivate(tx *types.Transaction) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	hash := tx.Hash()
+	if _, ok := pool.private[hash]; ok || pool.all.Get(hash) != nil {
+		knownTxMeter.Mark(1)
+		return ErrAlreadyKnown
+	}
+	if err := pool.validateTx(tx, true); err != nil {
+		invalidTxMeter.Mark(1)
+		return err
+	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	for old, ptx := range pool.private {
+		if ptx.from != from || ptx.tx.Nonce() != tx.Nonce() {
+			continue
+		}
+		// Replace an existing private transaction only with the usual price bump
+		threshold := new(big.Int).Div(new(big.Int).Mul(ptx.tx.GasPrice(), big.NewInt(100+int64(pool.config.PriceBump))), big.NewInt(100))
+		if tx.GasPriceIntCmp(threshold) < 0 {
+			return ErrReplaceUnderpriced
+		}
+		delete(pool.private, old)
+	}
+	if uint64(len(pool.private)) >= pool.config.GlobalSlots {
+		return ErrPrivatePoolFull
+	}
+	pool.private[hash] = &privateTx{
+		tx:     tx,
+		from:   from,
+		expiry: pool.chain.CurrentBlock().NumberU64() + pool.config.PrivateLifetime,
+	}
+	privateGauge.Update(int64(len(pool.private)))
+
+	log.Trace("Added private transaction", "hash", hash, "from", from, "nonce", tx.Nonce())
+	return nil
+}
+
+// Private retrieves the executable private transactions, grouped by origin
+// account and sorted by nonce.
+func (pool *TxPool) Private() map[common.Address]types.Transactions {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	private := make(map[common.Address]types.Transactions)
+	for _, ptx := range pool.private {
+		private[ptx.from] = append(private[ptx.from], ptx.tx)
+	}
+	for addr, txs := range private {
+		sort.Sort(types.TxByNonce(txs))
+
+		// Only keep the gapless sequence starting at the current account nonce
+		next := pool.currentState.GetNonce(addr)
+		for i, tx := range txs {
+			if tx.Nonce() != next {
+				txs = txs[:i]
+				break
+			}
+			next++
+		}
+		if len(txs) == 0 {
+			delete(private, addr)
+		} else {
+			private[addr] = txs
+		}
+	}
+	return private
+}
+
+// resetPrivate drops the private transactions invalidated by the new chain head,
+// either because they were included or superseded by another transaction, and
+// returns the ones that expired, to be submitted publicly. The pool lock must be
+// held.
+func (pool *TxPool) resetPrivate(head *types.Header) types.Transactions {
+	var expired types.Transactions
+	for hash, ptx := range pool.private {
+		switch {
+		case pool.currentState.GetNonce(ptx.from) > ptx.tx.Nonce():
+			log.Trace("Removing stale private transaction", "hash", hash)
+			privateStaleMeter.Mark(1)
+			delete(pool.private, hash)
+
+		case head.Number.Uint64() >= ptx.expiry:
+			log.Debug("Publishing expired private transaction", "hash", hash, "expiry", ptx.expiry)
+			privateExpiredMeter.Mark(1)
+			expired = append(expired, ptx.tx)
+			delete(pool.private, hash)
+		}
+	}
+	privateGauge.Update(int64(len(pool.private)))
+	return expired
+}
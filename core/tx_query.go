@@ -0,0 +1,184 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/core/types"
+)
+
+// TxPoolCursor is a position within the transactions of the pool, which are
+// ordered by sender and nonce. It remains valid across pool changes.
+type TxPoolCursor struct {
+	From  common.Address
+	Nonce uint64
+}
+
+// TxPoolQuery is a set of filters to select transactions of the pool with. Nil
+// filters match all transactions.
+type TxPoolQuery struct {
+	From        *common.Address // Sender of the transactions
+	To          *common.Address // Recipient of the transactions
+	MinGasPrice *big.Int        // Minimum gas price of the transactions
+	NonceGap    *bool           // Whether the transactions are blocked by a missing nonce
+	Local       *bool           // Whether the transactions are local ones
+	Pending     *bool           // Whether the transactions are pending or queued
+
+	After *TxPoolCursor // Position after which to start returning transactions
+	Limit int           // Maximum number of transactions to return (0 = no limit)
+}
+
+// TxPoolEntry is a transaction of the pool along with its status.
+type TxPoolEntry struct {
+	Tx       *types.Transaction
+	From     common.Address
+	Local    bool   // Whether the transaction is treated as local
+	Pending  bool   // Whether the transaction is executable
+	NonceGap bool   // Whether the transaction is blocked by a missing nonce
+	Reason   string // Reason why a queued transaction is not executable
+}
+
+// Query retrieves the transactions of the pool matching the given filters in
+// sender and nonce order, along with the cursor to continue the iteration at if
+// more transactions might match.
+func (pool *TxPool) Query(query TxPoolQuery) ([]*TxPoolEntry, *TxPoolCursor) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	// Gather and order the accounts to iterate over
+	var addrs []common.Address
+	if query.From != nil {
+		addrs = append(addrs, *query.From)
+	} else {
+		seen := make(map[common.Address]struct{}, len(pool.pending)+len(pool.queue))
+		for addr := range pool.pending {
+			seen[addr] = struct{}{}
+		}
+		for addr := range pool.queue {
+			seen[addr] = struct{}{}
+		}
+		for addr := range seen {
+			addrs = append(addrs, addr)
+		}
+		sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	}
+	var entries []*TxPoolEntry
+	for _, addr := range addrs {
+		if query.After != nil && bytes.Compare(addr[:], query.After.From[:]) < 0 {
+			continue
+		}
+		for _, entry := range pool.accountEntries(addr) {
+			if query.After != nil && addr == query.After.From && entry.Tx.Nonce() <= query.After.Nonce {
+				continue
+			}
+			if !query.matches(entry) {
+				continue
+			}
+			if query.Limit > 0 && len(entries) == query.Limit {
+				last := entries[len(entries)-1]
+				return entries, &TxPoolCursor{From: last.From, Nonce: last.Tx.Nonce()}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// matches checks whether a pool entry satisfies all the filters of the query.
+func (query *TxPoolQuery) matches(entry *TxPoolEntry) bool {
+	if query.To != nil {
+		if to := entry.Tx.To(); to == nil || *to != *query.To {
+			return false
+		}
+	}
+	if query.MinGasPrice != nil && entry.Tx.GasPriceIntCmp(query.MinGasPrice) < 0 {
+		return false
+	}
+	if query.NonceGap != nil && entry.NonceGap != *query.NonceGap {
+		return false
+	}
+	if query.Local != nil && entry.Local != *query.Local {
+		return false
+	}
+	if query.Pending != nil && entry.Pending != *query.Pending {
+		return false
+	}
+	return true
+}
+
+// accountEntries assembles the nonce ordered pool entries of an account, deriving
+// the reason why queued transactions are not executable from the same nonce and
+// balance checks used by the pool when promoting them. The pool lock must be held.
+func (pool *TxPool) accountEntries(addr common.Address) []*TxPoolEntry {
+	var (
+		local   = pool.locals.contains(addr)
+		entries []*TxPoolEntry
+	)
+	if list := pool.pending[addr]; list != nil {
+		for _, tx := range list.Flatten() {
+			entries = append(entries, &TxPoolEntry{Tx: tx, From: addr, Local: local, Pending: true})
+		}
+	}
+	list := pool.queue[addr]
+	if list == nil {
+		return entries
+	}
+	var (
+		nonce   = pool.currentState.GetNonce(addr)
+		next    = pool.pendingNonces.get(addr)
+		balance = pool.currentState.GetBalance(addr)
+		blocker string
+		gapped  bool
+	)
+	for _, tx := range list.Flatten() {
+		entry := &TxPoolEntry{Tx: tx, From: addr, Local: local}
+		switch {
+		case tx.Nonce() < nonce:
+			entry.Reason = ErrNonceTooLow.Error()
+
+		case gapped || tx.Nonce() > next:
+			if !gapped {
+				blocker, gapped = fmt.Sprintf("nonce gap: missing nonce %d", next), true
+			}
+			entry.NonceGap, entry.Reason = true, blocker
+
+		case blocker != "":
+			entry.Reason = blocker
+
+		case balance.Cmp(tx.Cost()) < 0:
+			entry.Reason = ErrInsufficientFunds.Error()
+			blocker = fmt.Sprintf("blocked by nonce %d: %s", tx.Nonce(), entry.Reason)
+
+		case tx.Gas() > pool.currentMaxGas:
+			entry.Reason = ErrGasLimit.Error()
+			blocker = fmt.Sprintf("blocked by nonce %d: %s", tx.Nonce(), entry.Reason)
+
+		default:
+			entry.Reason = "awaiting promotion"
+		}
+		if tx.Nonce() == next {
+			next++
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
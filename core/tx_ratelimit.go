@@ -0,0 +1,112 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/metrics"
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/time/rate"
+)
+
+// ErrTxRateLimited is returned if a transaction is submitted by a source which
+// exceeded its configured admission rate.
+var ErrTxRateLimited = errors.New("transaction rate limit exceeded")
+
+// senderRateLimitMeter counts the remote transactions rejected because their
+// sender exceeded its admission rate.
+var senderRateLimitMeter = metrics.NewRegisteredMeter("txpool/ratelimit/sender", nil)
+
+const (
+	// txRateBurstWindow is the number of seconds worth of transactions a source
+	// is allowed to submit in a single burst.
+	txRateBurstWindow = 10
+
+	// txRateLimiterCache is the maximum number of sources whose admission rate
+	// is tracked at the same time. The least recently active ones are forgotten.
+	txRateLimiterCache = 4096
+)
+
+// TxRateLimiter tracks the transaction admission rate of a set of sources, such
+// as peers, accounts or RPC clients, using a token bucket for each of them.
+type TxRateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	buckets *lru.Cache // Token buckets of the recently active sources
+	lock    sync.Mutex // Lock protecting the creation of buckets
+}
+
+// NewTxRateLimiter creates a rate limiter admitting the given number of
+// transactions per second from each source, or nil if the limit is zero.
+func NewTxRateLimiter(perSecond float64) *TxRateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	buckets, _ := lru.New(txRateLimiterCache)
+	return &TxRateLimiter{
+		limit:   rate.Limit(perSecond),
+		burst:   int(math.Max(1, math.Ceil(perSecond*txRateBurstWindow))),
+		buckets: buckets,
+	}
+}
+
+// Allow reports whether the given number of transactions may be admitted from a
+// source, consuming its allowance if so. A nil limiter admits everything.
+func (l *TxRateLimiter) Allow(source interface{}, n int) bool {
+	if l == nil {
+		return true
+	}
+	return l.bucket(source).AllowN(time.Now(), n)
+}
+
+// Admit admits as many of the given number of transactions from a source as its
+// allowance permits, one by one, returning the number admitted. Contrary to
+// Allow, batches larger than the burst allowance are admitted partially instead
+// of being rejected as a whole. A nil limiter admits everything.
+func (l *TxRateLimiter) Admit(source interface{}, n int) int {
+	if l == nil {
+		return n
+	}
+	var (
+		bucket = l.bucket(source)
+		now    = time.Now()
+	)
+	for i := 0; i < n; i++ {
+		if !bucket.AllowN(now, 1) {
+			return i
+		}
+	}
+	return n
+}
+
+// bucket returns the token bucket of a source, creating it if missing.
+func (l *TxRateLimiter) bucket(source interface{}) *rate.Limiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bucket, ok := l.buckets.Get(source)
+	if !ok {
+		bucket = rate.NewLimiter(l.limit, l.burst)
+		l.buckets.Add(source, bucket)
+	}
+	return bucket.(*rate.Limiter)
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/log"
+	"github.com/ccm-chain/ccmchain/rlp"
+)
+
+// snapshotTx is the disk representation of a remote transaction, along with the
+// time it was first seen by the node.
+type snapshotTx struct {
+	Tx   *types.Transaction
+	Time uint64 // Arrival time in unix nanoseconds
+}
+
+// txSnapshot is a point in time dump of the remote transactions of the pool, with
+// the aim of allowing a restarted node to warm up its pool without waiting for
+// the network to rebroadcast them. Contrary to the local journal, the snapshot
+// is not appended to, but fully regenerated on every write.
+type txSnapshot struct {
+	path string // Filesystem path to store the transactions at
+}
+
+// newTxSnapshot creates a new remote transaction snapshot.
+func newTxSnapshot(path string) *txSnapshot {
+	return &txSnapshot{
+		path: path,
+	}
+}
+
+// load parses a transaction snapshot from disk, restoring the arrival times of
+// the transactions and loading them into the specified pool. The transactions
+// are fully revalidated by the pool against its current head.
+func (snapshot *txSnapshot) load(add func([]*types.Transaction) []error) error {
+	// Skip the parsing if the snapshot file doesn't exist at all
+	if _, err := os.Stat(snapshot.path); os.IsNotExist(err) {
+		return nil
+	}
+	input, err := os.Open(snapshot.path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	var (
+		stream         = rlp.NewStream(input, 0)
+		total, dropped = 0, 0
+		failure        error
+		batch          types.Transactions
+	)
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range add(txs) {
+			if err != nil {
+				log.Trace("Failed to add snapshotted transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	for {
+		// Parse the next transaction and terminate on error
+		var stx snapshotTx
+		if err = stream.Decode(&stx); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			if batch.Len() > 0 {
+				loadBatch(batch)
+			}
+			break
+		}
+		stx.Tx.SetTime(time.Unix(0, int64(stx.Time)))
+		total++
+
+		if batch = append(batch, stx.Tx); batch.Len() > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	log.Info("Loaded remote transaction snapshot", "transactions", total, "dropped", dropped)
+
+	return failure
+}
+
+// save regenerates the transaction snapshot from the given pending and queued
+// transactions, storing at most the given number of each.
+func (snapshot *txSnapshot) save(pending, queued map[common.Address]types.Transactions, maxPending, maxQueued uint64) error {
+	replacement, err := os.OpenFile(snapshot.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	// Write the executable transactions first, so they are reloaded first
+	var written, skipped int
+	for _, set := range []struct {
+		txs   map[common.Address]types.Transactions
+		limit uint64
+	}{
+		{pending, maxPending},
+		{queued, maxQueued},
+	} {
+		var count uint64
+		for _, txs := range set.txs {
+			for _, tx := range txs {
+				if count >= set.limit {
+					skipped++
+					continue
+				}
+				if err = rlp.Encode(replacement, &snapshotTx{Tx: tx, Time: uint64(tx.Time().UnixNano())}); err != nil {
+					replacement.Close()
+					return err
+				}
+				count++
+			}
+		}
+		written += int(count)
+	}
+	replacement.Close()
+
+	// Replace the previous snapshot with the newly generated one
+	if err = os.Rename(snapshot.path+".new", snapshot.path); err != nil {
+		return err
+	}
+	log.Info("Saved remote transaction snapshot", "transactions", written, "skipped", skipped)
+	return nil
+}
@@ -273,6 +273,17 @@ func (tx *Transaction) RawSignatureValues() (v, r, s *big.Int) {
 	return tx.data.V, tx.data.R, tx.data.S
 }
 
+// Time returns the time the transaction was first seen locally.
+func (tx *Transaction) Time() time.Time {
+	return tx.time
+}
+
+// SetTime overrides the time the transaction was first seen locally. It should
+// only be used when restoring previously seen transactions from disk.
+func (tx *Transaction) SetTime(t time.Time) {
+	tx.time = t
+}
+
 // Transactions is a Transaction slice type for basic sorting.
 type Transactions []*Transaction
 
@@ -405,6 +416,85 @@ func (t *TransactionsByPriceAndNonce) Pop() {
 	heap.Pop(&t.heads)
 }
 
+// TxByTime implements both the sort and the heap interface, ordering transactions
+// by the time they were first seen locally.
+type TxByTime Transactions
+
+func (s TxByTime) Len() int           { return len(s) }
+func (s TxByTime) Less(i, j int) bool { return s[i].time.Before(s[j].time) }
+func (s TxByTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (s *TxByTime) Push(x interface{}) {
+	*s = append(*s, x.(*Transaction))
+}
+
+func (s *TxByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
+
+// TransactionsByTimeAndNonce represents a set of transactions that can return
+// transactions in the order they were first seen, while honouring the nonce
+// ordering of each account.
+type TransactionsByTimeAndNonce struct {
+	txs    map[common.Address]Transactions // Per account nonce-sorted list of transactions
+	heads  TxByTime                        // Next transaction for each unique account (time heap)
+	signer Signer                          // Signer for the set of transactions
+}
+
+// NewTransactionsByTimeAndNonce creates a transaction set that can retrieve
+// arrival time sorted transactions in a nonce-honouring way.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func NewTransactionsByTimeAndNonce(signer Signer, txs map[common.Address]Transactions) *TransactionsByTimeAndNonce {
+	heads := make(TxByTime, 0, len(txs))
+	for from, accTxs := range txs {
+		heads = append(heads, accTxs[0])
+		// Ensure the sender address is from the signer
+		acc, _ := Sender(signer, accTxs[0])
+		txs[acc] = accTxs[1:]
+		if from != acc {
+			delete(txs, from)
+		}
+	}
+	heap.Init(&heads)
+
+	return &TransactionsByTimeAndNonce{
+		txs:    txs,
+		heads:  heads,
+		signer: signer,
+	}
+}
+
+// Peek returns the next transaction by arrival time.
+func (t *TransactionsByTimeAndNonce) Peek() *Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0]
+}
+
+// Shift replaces the current head with the next one from the same account.
+func (t *TransactionsByTimeAndNonce) Shift() {
+	acc, _ := Sender(t.signer, t.heads[0])
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads[0], t.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+// Pop removes the current head, *not* replacing it with the next one from the
+// same account.
+func (t *TransactionsByTimeAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
+
 // Message is a fully derived transaction and implements core.Message
 //
 // NOTE: In a future PR this will be removed.
@@ -0,0 +1,179 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/crypto"
+)
+
+// AccountAccess is the part of an account accessed by an execution.
+type AccountAccess struct {
+	Code  bool                     // Whether the code of the account was accessed
+	Slots map[common.Hash]struct{} // Storage slots of the account accessed
+}
+
+// StateAccess is the set of accounts accessed by an execution.
+type StateAccess map[common.Address]*AccountAccess
+
+// account returns the access to an account, adding it to the set if missing.
+func (s StateAccess) account(addr common.Address) *AccountAccess {
+	access, ok := s[addr]
+	if !ok {
+		access = &AccountAccess{Slots: make(map[common.Hash]struct{})}
+		s[addr] = access
+	}
+	return access
+}
+
+// Equal returns whether two sets contain the same accesses.
+func (s StateAccess) Equal(other StateAccess) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for addr, access := range s {
+		match, ok := other[addr]
+		if !ok || access.Code != match.Code || len(access.Slots) != len(match.Slots) {
+			return false
+		}
+		for slot := range access.Slots {
+			if _, ok := match.Slots[slot]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// AccessTracer is a Tracer recording the accounts, code and storage slots read
+// and written by an execution. Accounts are read when their balance, nonce or
+// code is queried and written when their balance, nonce or code changes. The
+// accesses of reverted calls are recorded too, as a slight change of the state
+// may prevent the revert. The fees credited to the coinbase are not recorded.
+type AccessTracer struct {
+	reads  StateAccess
+	writes StateAccess
+}
+
+// NewAccessTracer creates a new tracer recording the state accessed by an
+// execution.
+func NewAccessTracer() *AccessTracer {
+	return &AccessTracer{
+		reads:  make(StateAccess),
+		writes: make(StateAccess),
+	}
+}
+
+// Reads returns the state read by the execution.
+func (t *AccessTracer) Reads() StateAccess {
+	return t.reads
+}
+
+// Writes returns the state written by the execution.
+func (t *AccessTracer) Writes() StateAccess {
+	return t.writes
+}
+
+// CaptureStart implements the Tracer interface to record the accounts of the
+// sender and recipient of the execution.
+func (t *AccessTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.writes.account(from)
+	if create {
+		t.writes.account(to).Code = true
+		return nil
+	}
+	t.reads.account(to).Code = true
+	if value.Sign() > 0 {
+		t.writes.account(to)
+	}
+	return nil
+}
+
+// CaptureState implements the Tracer interface to record the state accessed by
+// an opcode before it is executed.
+func (t *AccessTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, rStack *ReturnStack, rData []byte, contract *Contract, depth int, err error) error {
+	if err != nil {
+		return nil
+	}
+	self := contract.Address()
+	switch op {
+	case SLOAD:
+		if stack.len() >= 1 {
+			t.reads.account(self).Slots[common.Hash(stack.Back(0).Bytes32())] = struct{}{}
+		}
+	case SSTORE:
+		if stack.len() >= 1 {
+			t.writes.account(self).Slots[common.Hash(stack.Back(0).Bytes32())] = struct{}{}
+		}
+	case BALANCE:
+		if stack.len() >= 1 {
+			t.reads.account(common.Address(stack.Back(0).Bytes20()))
+		}
+	case SELFBALANCE:
+		t.reads.account(self)
+	case EXTCODESIZE, EXTCODECOPY, EXTCODEHASH:
+		if stack.len() >= 1 {
+			t.reads.account(common.Address(stack.Back(0).Bytes20())).Code = true
+		}
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		if stack.len() >= 2 {
+			addr := common.Address(stack.Back(1).Bytes20())
+			t.reads.account(addr).Code = true
+
+			// Only plain calls move value between accounts
+			if op == CALL && stack.len() >= 3 && !stack.Back(2).IsZero() {
+				t.writes.account(self)
+				t.writes.account(addr)
+			}
+		}
+	case CREATE:
+		t.writes.account(self)
+		t.writes.account(crypto.CreateAddress(self, env.StateDB.GetNonce(self))).Code = true
+	case CREATE2:
+		if stack.len() >= 4 {
+			var (
+				offset = stack.Back(1)
+				size   = stack.Back(2)
+				salt   = stack.Back(3).Bytes32()
+				code   = memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+			)
+			t.writes.account(self)
+			t.writes.account(crypto.CreateAddress2(self, salt, crypto.Keccak256(code))).Code = true
+		}
+	case SELFDESTRUCT:
+		t.writes.account(self).Code = true
+		if stack.len() >= 1 {
+			t.writes.account(common.Address(stack.Back(0).Bytes20()))
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface, the accesses of a faulting
+// opcode were already recorded before its execution.
+func (t *AccessTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, rStack *ReturnStack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the Tracer interface, nothing is recorded at the end of
+// the execution.
+func (t *AccessTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
@@ -721,3 +721,58 @@ func BenchmarkSimpleLoop(b *testing.B) {
 	//benchmarkNonModifyingCode(10000000, staticCallIdentity, "staticcall-identity-10M", b)
 	//benchmarkNonModifyingCode(10000000, loopingCode, "loop-10M", b)
 }
+
+func TestAccessTracer(t *testing.T) {
+	state, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	address := common.HexToAddress("0x0a")
+	state.SetBalance(address, big.NewInt(1))
+	state.SetCode(address, []byte{
+		byte(vm.PUSH1), 1, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 7, byte(vm.PUSH1), 2, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0xaa, byte(vm.BALANCE), byte(vm.POP),
+		byte(vm.PUSH1), 0xbb, byte(vm.EXTCODESIZE), byte(vm.POP),
+		// Transfer value to 0xcc
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0xcc, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		// Read storage from 0xdd without transferring value
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0xdd, byte(vm.GAS), byte(vm.STATICCALL), byte(vm.POP),
+	})
+	state.SetCode(common.HexToAddress("0xdd"), []byte{byte(vm.PUSH1), 3, byte(vm.SLOAD)})
+
+	tracer := vm.NewAccessTracer()
+	origin := common.HexToAddress("0x01")
+	_, _, err := Call(address, nil, &Config{
+		State:     state,
+		Origin:    origin,
+		EVMConfig: vm.Config{Debug: true, Tracer: tracer},
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	slots := func(keys ...byte) map[common.Hash]struct{} {
+		set := make(map[common.Hash]struct{})
+		for _, key := range keys {
+			set[common.BytesToHash([]byte{key})] = struct{}{}
+		}
+		return set
+	}
+	reads := vm.StateAccess{
+		address:                     {Code: true, Slots: slots(1)},
+		common.HexToAddress("0xaa"): {Slots: slots()},
+		common.HexToAddress("0xbb"): {Code: true, Slots: slots()},
+		common.HexToAddress("0xcc"): {Code: true, Slots: slots()},
+		common.HexToAddress("0xdd"): {Code: true, Slots: slots(3)},
+	}
+	writes := vm.StateAccess{
+		origin:                      {Slots: slots()},
+		address:                     {Slots: slots(2)},
+		common.HexToAddress("0xcc"): {Slots: slots()},
+	}
+	if !tracer.Reads().Equal(reads) {
+		t.Errorf("reads mismatch: have %v, want %v", tracer.Reads(), reads)
+	}
+	if !tracer.Writes().Equal(writes) {
+		t.Errorf("writes mismatch: have %v, want %v", tracer.Writes(), writes)
+	}
+}
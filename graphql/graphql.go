@@ -18,8 +18,10 @@
 package graphql
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ccm-chain/ccmchain"
@@ -27,18 +29,27 @@ import (
 	"github.com/ccm-chain/ccmchain/common/hexutil"
 	"github.com/ccm-chain/ccmchain/core/rawdb"
 	"github.com/ccm-chain/ccmchain/core/state"
+	"github.com/ccm-chain/ccmchain/core/state/snapshot"
 	"github.com/ccm-chain/ccmchain/core/types"
-	"github.com/ccm-chain/ccmchain/core/vm"
+	"github.com/ccm-chain/ccmchain/crypto"
 	"github.com/ccm-chain/ccmchain/internal/api"
 	"github.com/ccm-chain/ccmchain/protocol/filters"
 	"github.com/ccm-chain/ccmchain/rlp"
 	"github.com/ccm-chain/ccmchain/rpc"
+	"github.com/ccm-chain/ccmchain/trie"
 )
 
 var (
 	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
 )
 
+// maxStorageRange is the maximum number of slots returned by a storage range.
+const maxStorageRange = 1024
+
+// maxHistoryScan is the maximum number of blocks walked back when looking up the
+// history of an account.
+const maxHistoryScan = 4096
+
 // Account represents an Ethereum account at a particular block.
 type Account struct {
 	backend       api.Backend
@@ -88,6 +99,312 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 	return state.GetState(a.address, args.Slot), nil
 }
 
+func (a *Account) CodeHash(ctx context.Context) (common.Hash, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// Non-existent accounts have no code, report the hash of the empty code
+	if !state.Exist(a.address) {
+		return crypto.Keccak256Hash(nil), nil
+	}
+	return state.GetCodeHash(a.address), nil
+}
+
+func (a *Account) CodeSize(ctx context.Context) (hexutil.Uint64, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(state.GetCodeSize(a.address)), nil
+}
+
+// StorageSlot represents a storage slot of a contract account.
+type StorageSlot struct {
+	hash  common.Hash
+	key   *common.Hash
+	value common.Hash
+}
+
+func (s *StorageSlot) Hash() common.Hash {
+	return s.hash
+}
+
+func (s *StorageSlot) Key() *common.Hash {
+	return s.key
+}
+
+func (s *StorageSlot) Value() common.Hash {
+	return s.value
+}
+
+// StorageRange represents a range of the storage of a contract account.
+type StorageRange struct {
+	slots []*StorageSlot
+	next  *common.Hash
+}
+
+func (r *StorageRange) Slots() []*StorageSlot {
+	return r.slots
+}
+
+func (r *StorageRange) Next() *common.Hash {
+	return r.next
+}
+
+// StorageRange iterates the storage of the account from the given slot hash. The
+// snapshot is iterated if it covers the state of the account, otherwise the
+// storage trie is.
+func (a *Account) StorageRange(ctx context.Context, args struct {
+	Start *common.Hash
+	Count int32
+}) (*StorageRange, error) {
+	if args.Count <= 0 || args.Count > maxStorageRange {
+		return nil, fmt.Errorf("storage range count must be between 1 and %d", maxStorageRange)
+	}
+	state, header, err := a.backend.StateAndHeaderByNumberOrHash(ctx, a.blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	st := state.StorageTrie(a.address)
+	if st == nil {
+		return &StorageRange{slots: []*StorageSlot{}}, nil
+	}
+	var start common.Hash
+	if args.Start != nil {
+		start = *args.Start
+	}
+	var it storageIterator
+	if snaps := a.backend.Snapshots(); snaps != nil && !snaps.Generating() {
+		if snapIt, err := snaps.StorageIterator(header.Root, crypto.Keccak256Hash(a.address.Bytes()), start); err == nil {
+			it = &snapshotStorageIterator{it: snapIt}
+		}
+	}
+	if it == nil {
+		it = &trieStorageIterator{it: trie.NewIterator(st.NodeIterator(start.Bytes()))}
+	}
+	defer it.Release()
+
+	result := &StorageRange{slots: []*StorageSlot{}}
+	for len(result.slots) < int(args.Count) && it.Next() {
+		_, content, _, err := rlp.Split(it.Value())
+		if err != nil {
+			return nil, err
+		}
+		slot := &StorageSlot{hash: it.Hash(), value: common.BytesToHash(content)}
+		if preimage := st.GetKey(slot.hash.Bytes()); preimage != nil {
+			key := common.BytesToHash(preimage)
+			slot.key = &key
+		}
+		result.slots = append(result.slots, slot)
+	}
+	if it.Next() {
+		next := it.Hash()
+		result.next = &next
+	}
+	return result, it.Error()
+}
+
+// AccountProof represents the Merkle proof of an account and some of its storage
+// slots.
+type AccountProof struct {
+	accountProof [][]byte
+	storageHash  common.Hash
+	storageProof []*StorageProof
+}
+
+func (p *AccountProof) AccountProof() []hexutil.Bytes {
+	return toBytesList(p.accountProof)
+}
+
+func (p *AccountProof) StorageHash() common.Hash {
+	return p.storageHash
+}
+
+func (p *AccountProof) StorageProof() []*StorageProof {
+	return p.storageProof
+}
+
+// StorageProof represents the Merkle proof of a storage slot.
+type StorageProof struct {
+	key   common.Hash
+	value common.Hash
+	proof [][]byte
+}
+
+func (p *StorageProof) Key() common.Hash {
+	return p.key
+}
+
+func (p *StorageProof) Value() common.Hash {
+	return p.value
+}
+
+func (p *StorageProof) Proof() []hexutil.Bytes {
+	return toBytesList(p.proof)
+}
+
+// toBytesList converts a list of trie nodes to their GraphQL representation.
+func toBytesList(nodes [][]byte) []hexutil.Bytes {
+	list := make([]hexutil.Bytes, len(nodes))
+	for i, node := range nodes {
+		list[i] = node
+	}
+	return list
+}
+
+// Proof returns the Merkle proof of the account and the given storage slots, the
+// same way eth_getProof does.
+func (a *Account) Proof(ctx context.Context, args struct{ Slots *[]common.Hash }) (*AccountProof, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accountProof, err := state.GetProof(a.address)
+	if err != nil {
+		return nil, err
+	}
+	result := &AccountProof{
+		accountProof: accountProof,
+		storageHash:  types.EmptyRootHash,
+		storageProof: []*StorageProof{},
+	}
+	st := state.StorageTrie(a.address)
+	if st != nil {
+		result.storageHash = st.Hash()
+	}
+	if args.Slots != nil {
+		for _, slot := range *args.Slots {
+			proof := &StorageProof{key: slot, proof: [][]byte{}}
+			if st != nil {
+				if proof.proof, err = state.GetStorageProof(a.address, slot); err != nil {
+					return nil, err
+				}
+				proof.value = state.GetState(a.address, slot)
+			}
+			result.storageProof = append(result.storageProof, proof)
+		}
+	}
+	return result, state.Error()
+}
+
+// FirstSeenBlock returns the block since which the account has continuously
+// existed, so the block it was last created at if it was ever deleted.
+func (a *Account) FirstSeenBlock(ctx context.Context) (*Block, error) {
+	return a.searchHistory(ctx, func(current, enc []byte) bool {
+		return enc == nil
+	})
+}
+
+// LastModifiedBlock returns the block since which the account has been in its
+// current state.
+func (a *Account) LastModifiedBlock(ctx context.Context) (*Block, error) {
+	return a.searchHistory(ctx, func(current, enc []byte) bool {
+		return !bytes.Equal(enc, current)
+	})
+}
+
+// searchHistory walks the chain back from the block the account is queried at
+// (or the latest one, if the pending state is queried), checking the encoded
+// account as stored in the state trie of every parent block. It returns the
+// oldest block of the run of blocks leading up to the queried one in which the
+// given condition didn't hold, so the block following the first parent found to
+// differ. Nil is returned if the account doesn't exist. As the history of an
+// account isn't monotonic, every block is checked: an error is returned instead
+// of a wrong block if the state of a block was pruned, or if the walk exceeds
+// maxHistoryScan blocks. Every block walked is charged to the query complexity,
+// as it opens the state trie of the block.
+func (a *Account) searchHistory(ctx context.Context, changed func(current, enc []byte) bool) (*Block, error) {
+	blockNrOrHash := a.blockNrOrHash
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		blockNrOrHash = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	}
+	statedb, header, err := a.backend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	db := statedb.Database()
+	accountAt := func(header *types.Header) ([]byte, error) {
+		tr, err := db.OpenTrie(header.Root)
+		if err != nil {
+			return nil, fmt.Errorf("state of block #%d unavailable: %v", header.Number, err)
+		}
+		enc, err := tr.TryGet(a.address.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("state of block #%d unavailable: %v", header.Number, err)
+		}
+		return enc, nil
+	}
+	current, err := accountAt(header)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	for depth := 0; header.Number.Sign() > 0; depth++ {
+		if depth >= maxHistoryScan {
+			return nil, fmt.Errorf("account history exceeds %d blocks", maxHistoryScan)
+		}
+		if err := chargeQuery(ctx, 1); err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		parent, err := a.backend.HeaderByHash(ctx, header.ParentHash)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, fmt.Errorf("block #%d not found", header.Number.Uint64()-1)
+		}
+		enc, err := accountAt(parent)
+		if err != nil {
+			return nil, err
+		}
+		if changed(current, enc) {
+			break
+		}
+		header = parent
+	}
+	numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), false)
+	return &Block{
+		backend:      a.backend,
+		numberOrHash: &numberOrHash,
+		hash:         header.Hash(),
+		header:       header,
+	}, nil
+}
+
+// storageIterator iterates over the slots of a storage trie, in hash order.
+type storageIterator interface {
+	Next() bool
+	Hash() common.Hash
+	Value() []byte // RLP encoded slot value
+	Error() error
+	Release()
+}
+
+// snapshotStorageIterator is a storageIterator over the state snapshot.
+type snapshotStorageIterator struct {
+	it snapshot.StorageIterator
+}
+
+func (it *snapshotStorageIterator) Next() bool        { return it.it.Next() }
+func (it *snapshotStorageIterator) Hash() common.Hash { return it.it.Hash() }
+func (it *snapshotStorageIterator) Value() []byte     { return it.it.Slot() }
+func (it *snapshotStorageIterator) Error() error      { return it.it.Error() }
+func (it *snapshotStorageIterator) Release()          { it.it.Release() }
+
+// trieStorageIterator is a storageIterator over the storage trie.
+type trieStorageIterator struct {
+	it *trie.Iterator
+}
+
+func (it *trieStorageIterator) Next() bool        { return it.it.Next() }
+func (it *trieStorageIterator) Hash() common.Hash { return common.BytesToHash(it.it.Key) }
+func (it *trieStorageIterator) Value() []byte     { return it.it.Value }
+func (it *trieStorageIterator) Error() error      { return it.it.Err }
+func (it *trieStorageIterator) Release()          {}
+
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
 	backend     api.Backend
@@ -637,6 +954,9 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	if err != nil || block == nil {
 		return nil, err
 	}
+	if err := chargeQuery(ctx, len(block.Transactions())); err != nil {
+		return nil, err
+	}
 	ret := make([]*Transaction, 0, len(block.Transactions()))
 	for i, tx := range block.Transactions() {
 		ret = append(ret, &Transaction{
@@ -713,6 +1033,9 @@ func runFilter(ctx context.Context, be api.Backend, filter *filters.Filter) ([]*
 	if err != nil || logs == nil {
 		return nil, err
 	}
+	if err := chargeQuery(ctx, len(logs)); err != nil {
+		return nil, err
+	}
 	ret := make([]*Log, 0, len(logs))
 	for _, log := range logs {
 		ret = append(ret, &Log{
@@ -803,7 +1126,7 @@ func (b *Block) Call(ctx context.Context, args struct {
 			return nil, err
 		}
 	}
-	result, err := api.DoCall(ctx, b.backend, args.Data, *b.numberOrHash, nil, vm.Config{}, 5*time.Second, b.backend.RPCGasCap())
+	result, err := api.DoCall(ctx, b.backend, args.Data, *b.numberOrHash, nil, nil, 5*time.Second, b.backend.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
@@ -873,7 +1196,7 @@ func (p *Pending) Call(ctx context.Context, args struct {
 	Data api.CallArgs
 }) (*CallResult, error) {
 	pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
-	result, err := api.DoCall(ctx, p.backend, args.Data, pendingBlockNr, nil, vm.Config{}, 5*time.Second, p.backend.RPCGasCap())
+	result, err := api.DoCall(ctx, p.backend, args.Data, pendingBlockNr, nil, nil, 5*time.Second, p.backend.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
@@ -904,9 +1227,25 @@ type Resolver struct {
 func (r *Resolver) Block(ctx context.Context, args struct {
 	Number *hexutil.Uint64
 	Hash   *common.Hash
+	Tag    *string
 }) (*Block, error) {
 	var block *Block
-	if args.Number != nil {
+	if args.Tag != nil {
+		var number rpc.BlockNumber
+		switch *args.Tag {
+		case "SAFE":
+			number = rpc.SafeBlockNumber
+		case "FINALIZED":
+			number = rpc.FinalizedBlockNumber
+		default:
+			number = rpc.LatestBlockNumber
+		}
+		numberOrHash := rpc.BlockNumberOrHashWithNumber(number)
+		block = &Block{
+			backend:      r.backend,
+			numberOrHash: &numberOrHash,
+		}
+	} else if args.Number != nil {
 		number := rpc.BlockNumber(uint64(*args.Number))
 		numberOrHash := rpc.BlockNumberOrHashWithNumber(number)
 		block = &Block{
@@ -953,6 +1292,9 @@ func (r *Resolver) Blocks(ctx context.Context, args struct {
 	if to < from {
 		return []*Block{}, nil
 	}
+	if err := chargeQuery(ctx, int(to-from+1)); err != nil {
+		return nil, err
+	}
 	ret := make([]*Block, 0, to-from+1)
 	for i := from; i <= to; i++ {
 		numberOrHash := rpc.BlockNumberOrHashWithNumber(i)
@@ -1092,3 +1434,136 @@ func (r *Resolver) Syncing() (*SyncState, error) {
 	// Otherwise gather the block sync stats
 	return &SyncState{progress}, nil
 }
+
+// Subscription is the top-level object of the subscription schema, streaming the
+// events of the filter system to the subscribers.
+type Subscription struct {
+	*Resolver
+	events *filters.EventSystem
+}
+
+// NewHeads streams the blocks imported into the canonical chain, until the
+// subscription is cancelled.
+func (s *Subscription) NewHeads(ctx context.Context) (<-chan *Block, error) {
+	var (
+		headers = make(chan *types.Header)
+		sub     = s.events.SubscribeNewHeads(headers)
+		blocks  = make(chan *Block)
+	)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), false)
+				block := &Block{
+					backend:      s.backend,
+					numberOrHash: &numberOrHash,
+					hash:         header.Hash(),
+					header:       header,
+				}
+				if !admitEvent(ctx) {
+					return
+				}
+				select {
+				case blocks <- block:
+				case <-ctx.Done():
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+// Logs streams the log entries of newly imported blocks matching the filter,
+// until the subscription is cancelled. Logs removed by chain reorganisations
+// are not delivered, the logs of the new canonical blocks are.
+func (s *Subscription) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit ccmchain.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	matches := make(chan []*types.Log)
+	sub, err := s.events.SubscribeLogs(crit, matches)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(chan *Log)
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-matches:
+				for _, log := range batch {
+					if log.Removed {
+						continue
+					}
+					if !admitEvent(ctx) {
+						return
+					}
+					select {
+					case logs <- &Log{
+						backend:     s.backend,
+						transaction: &Transaction{backend: s.backend, hash: log.TxHash},
+						log:         log,
+					}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+// PendingTransactions streams the transactions entering the pending state, until
+// the subscription is cancelled.
+func (s *Subscription) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	var (
+		hashes = make(chan []common.Hash)
+		sub    = s.events.SubscribePendingTxs(hashes)
+		txs    = make(chan *Transaction)
+	)
+	go func() {
+		defer close(txs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-hashes:
+				for _, hash := range batch {
+					if !admitEvent(ctx) {
+						return
+					}
+					select {
+					case txs <- &Transaction{backend: s.backend, hash: hash}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs, nil
+}
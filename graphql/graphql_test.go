@@ -17,14 +17,30 @@
 package graphql
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/common/hexutil"
+	"github.com/ccm-chain/ccmchain/consensus/ethash"
+	"github.com/ccm-chain/ccmchain/core"
+	"github.com/ccm-chain/ccmchain/core/rawdb"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/crypto"
 	"github.com/ccm-chain/ccmchain/node"
+	"github.com/ccm-chain/ccmchain/params"
 	"github.com/ccm-chain/ccmchain/protocol"
+	"github.com/ccm-chain/ccmchain/trie"
+	"github.com/gorilla/websocket"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,7 +50,7 @@ func TestBuildSchema(t *testing.T) {
 		t.Fatalf("could not create new node: %v", err)
 	}
 	// Make sure the schema can be parsed and matched up to the object model.
-	if err := newHandler(stack, nil, []string{}, []string{}); err != nil {
+	if err := newHandler(stack, nil, false, []string{}, []string{}, Limits{}); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
@@ -64,6 +80,32 @@ func TestGraphQLHTTPOnSamePort_GQLRequest_Successful(t *testing.T) {
 	assert.Equal(t, expected, string(bodyBytes))
 }
 
+// Tests that blocks can be requested by the safe and finalized tags
+func TestGraphQLBlockByTag(t *testing.T) {
+	stack := createNode(t, true)
+	defer stack.Close()
+	// start node
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	for _, tag := range []string{"LATEST", "SAFE", "FINALIZED"} {
+		body := strings.NewReader(fmt.Sprintf("{\"query\": \"{block(tag: %s){number}}\",\"variables\": null}", tag))
+		gqlReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/graphql", "127.0.0.1:9393"), body)
+		if err != nil {
+			t.Error("could not issue new http request ", err)
+		}
+		gqlReq.Header.Set("Content-Type", "application/json")
+		// read from response
+		resp := doHTTPRequest(t, gqlReq)
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("could not read from response body: %v", err)
+		}
+		expected := "{\"data\":{\"block\":{\"number\":\"0x0\"}}}"
+		assert.Equal(t, expected, string(bodyBytes), "tag %s", tag)
+	}
+}
+
 // Tests that a graphQL request is not handled successfully when graphql is not enabled on the specified endpoint
 func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	stack := createNode(t, false)
@@ -90,6 +132,226 @@ func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	assert.Equal(t, "404 page not found\n", string(bodyBytes))
 }
 
+// Tests that subscriptions, as well as queries, are served over WebSocket
+// connections to the graphql endpoint using the graphql-ws protocol.
+func TestGraphQLSubscriptions(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		key2, _ = crypto.GenerateKey()
+		sender2 = crypto.PubkeyToAddress(key2.PublicKey)
+		emitter = common.HexToAddress("0xe1") // Contract emitting an empty log
+		signer  = types.HomesteadSigner{}
+		funds   = big.NewInt(1000000000000000000)
+	)
+	genesis := &core.Genesis{
+		Config:   params.AllEthashProtocolChanges,
+		GasLimit: 10000000,
+		Alloc: core.GenesisAlloc{
+			sender:  {Balance: funds},
+			sender2: {Balance: funds},
+			emitter: {Code: common.FromHex("60006000a000"), Balance: new(big.Int)},
+		},
+	}
+	// Generate a chain emitting a log in every block
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), emitter, new(big.Int), 100000, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	// Start a node serving graphql on the generated genesis
+	stack, ethBackend := createGQLChain(t, genesis, nil, Limits{})
+	defer stack.Close()
+
+	// Connect over graphql-ws and subscribe to all the events
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws://127.0.0.1:9393/graphql", nil)
+	if err != nil {
+		t.Fatalf("could not dial graphql websocket: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol() != wsProtocol {
+		t.Fatalf("subprotocol mismatch: have %q, want %q", conn.Subprotocol(), wsProtocol)
+	}
+	messages := make(chan *wsMessage, 64)
+	go func() {
+		defer close(messages)
+		for {
+			msg := new(wsMessage)
+			if err := conn.ReadJSON(msg); err != nil {
+				return
+			}
+			if msg.Type != gqlConnectionKeepAlive {
+				messages <- msg
+			}
+		}
+	}()
+	send := func(id, typ string, payload interface{}) {
+		msg := map[string]interface{}{"id": id, "type": typ, "payload": payload}
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("failed to send %s message: %v", typ, err)
+		}
+	}
+	send("", gqlConnectionInit, nil)
+	if msg := <-messages; msg == nil || msg.Type != gqlConnectionAck {
+		t.Fatalf("connection not acknowledged: %v", msg)
+	}
+	subscriptions := map[string]string{
+		"heads":   "subscription { newHeads { number } }",
+		"logs":    fmt.Sprintf("subscription { logs(filter: {addresses: [\"%s\"]}) { account { address } transaction { hash } } }", emitter.Hex()),
+		"pending": "subscription { pendingTransactions { hash } }",
+	}
+	for id, query := range subscriptions {
+		send(id, gqlStart, map[string]interface{}{"query": query})
+	}
+	// Queries are executed and completed right away
+	send("query", gqlStart, map[string]interface{}{"query": "{ block { number } }"})
+
+	received := make(map[string][]string)
+	for completed := false; !completed; {
+		select {
+		case msg := <-messages:
+			if msg == nil {
+				t.Fatalf("connection closed")
+			}
+			if msg.ID != "query" {
+				t.Fatalf("unexpected %s message for %s: %s", msg.Type, msg.ID, msg.Payload)
+			}
+			switch msg.Type {
+			case gqlData:
+				received[msg.ID] = append(received[msg.ID], string(msg.Payload))
+			case gqlComplete:
+				completed = true
+			default:
+				t.Fatalf("unexpected %s message: %s", msg.Type, msg.Payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("query not completed")
+		}
+	}
+	if want := []string{`{"data":{"block":{"number":"0x0"}}}`}; !assert.Equal(t, want, received["query"]) {
+		return
+	}
+	// Import blocks and submit transactions until all subscriptions were installed
+	// and notified
+	var (
+		pending []*types.Transaction
+		i       int
+	)
+	for ; len(received["heads"]) == 0 || len(received["logs"]) == 0 || len(received["pending"]) == 0; i++ {
+		if i == len(blocks)-1 {
+			t.Fatalf("subscriptions not notified: %v", received)
+		}
+		if _, err := ethBackend.BlockChain().InsertChain(blocks[i : i+1]); err != nil {
+			t.Fatalf("failed to import block %d: %v", i+1, err)
+		}
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), sender2, new(big.Int), params.TxGas, big.NewInt(1), nil), signer, key2)
+		if err := ethBackend.APIBackend.SendTx(context.Background(), tx); err != nil {
+			t.Fatalf("failed to submit transaction %d: %v", i, err)
+		}
+		pending = append(pending, tx)
+
+		timeout := time.After(250 * time.Millisecond)
+	wait:
+		for {
+			select {
+			case msg := <-messages:
+				if msg == nil {
+					t.Fatalf("connection closed")
+				}
+				if msg.Type != gqlData {
+					t.Fatalf("unexpected %s message for %s: %s", msg.Type, msg.ID, msg.Payload)
+				}
+				received[msg.ID] = append(received[msg.ID], string(msg.Payload))
+			case <-timeout:
+				break wait
+			}
+		}
+	}
+	// Check the notifications against the imported blocks and submitted transactions
+	for _, payload := range received["heads"] {
+		var res struct {
+			Data struct {
+				NewHeads struct{ Number hexutil.Uint64 }
+			}
+		}
+		if err := json.Unmarshal([]byte(payload), &res); err != nil {
+			t.Fatalf("invalid newHeads payload %s: %v", payload, err)
+		}
+		if n := uint64(res.Data.NewHeads.Number); n < 1 || n > uint64(len(blocks)) {
+			t.Errorf("unexpected head notification: %s", payload)
+		}
+	}
+	for _, payload := range received["logs"] {
+		var res struct {
+			Data struct {
+				Logs struct {
+					Account     struct{ Address common.Address }
+					Transaction struct{ Hash common.Hash }
+				}
+			}
+		}
+		if err := json.Unmarshal([]byte(payload), &res); err != nil {
+			t.Fatalf("invalid logs payload %s: %v", payload, err)
+		}
+		if res.Data.Logs.Account.Address != emitter {
+			t.Errorf("log address mismatch: have %x, want %x", res.Data.Logs.Account.Address, emitter)
+		}
+		if tx, _, _, _ := rawdb.ReadTransaction(ethBackend.ChainDb(), res.Data.Logs.Transaction.Hash); tx == nil || *tx.To() != emitter {
+			t.Errorf("log transaction mismatch: %s", payload)
+		}
+	}
+	for _, payload := range received["pending"] {
+		var res struct {
+			Data struct{ PendingTransactions struct{ Hash common.Hash } }
+		}
+		if err := json.Unmarshal([]byte(payload), &res); err != nil {
+			t.Fatalf("invalid pendingTransactions payload %s: %v", payload, err)
+		}
+		var found bool
+		for _, tx := range pending {
+			found = found || tx.Hash() == res.Data.PendingTransactions.Hash
+		}
+		if !found {
+			t.Errorf("unexpected pending transaction notification: %s", payload)
+		}
+	}
+	// Stopped subscriptions are not notified anymore
+	send("heads", gqlStop, nil)
+	send("sync", gqlStart, map[string]interface{}{"query": "{ block { number } }"})
+	for completed := false; !completed; {
+		select {
+		case msg := <-messages:
+			if msg == nil {
+				t.Fatalf("connection closed")
+			}
+			completed = msg.ID == "sync" && msg.Type == gqlComplete
+		case <-time.After(5 * time.Second):
+			t.Fatalf("query not completed")
+		}
+	}
+	if _, err := ethBackend.BlockChain().InsertChain(blocks[i : i+1]); err != nil {
+		t.Fatalf("failed to import block %d: %v", i+1, err)
+	}
+	for logs := 0; logs == 0; {
+		select {
+		case msg := <-messages:
+			if msg == nil {
+				t.Fatalf("connection closed")
+			}
+			if msg.ID == "heads" {
+				t.Fatalf("stopped subscription notified: %s", msg.Payload)
+			}
+			if msg.ID == "logs" {
+				logs++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("logs subscription not notified")
+		}
+	}
+}
+
 func createNode(t *testing.T, gqlEnabled bool) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost: "127.0.0.1",
@@ -109,6 +371,486 @@ func createNode(t *testing.T, gqlEnabled bool) *node.Node {
 	return stack
 }
 
+// Tests the account state queries: code metadata, storage ranges, proofs and the
+// history lookups.
+func TestGraphQLAccountState(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0")
+		fresh    = common.HexToAddress("0xf0")
+		revived  = common.HexToAddress("0xd0")
+		code     = common.FromHex("6000546001015b")
+		signer   = types.HomesteadSigner{}
+		storage  = map[common.Hash]common.Hash{
+			common.HexToHash("0x01"): common.HexToHash("0x11"),
+			common.HexToHash("0x02"): common.HexToHash("0x22"),
+			common.HexToHash("0x03"): common.HexToHash("0x33"),
+		}
+	)
+	genesis := &core.Genesis{
+		Config:   params.AllEthashProtocolChanges,
+		GasLimit: 10000000,
+		Alloc: core.GenesisAlloc{
+			sender:   {Balance: big.NewInt(1000000000000000000)},
+			contract: {Code: code, Storage: storage, Balance: new(big.Int)},
+			revived:  {Code: common.FromHex("33ff"), Balance: big.NewInt(1)},
+		},
+	}
+	// Create fresh in block 2, destroy revived in block 3 and create it again in
+	// block 4, modifying the sender last in block 4
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 4, func(i int, gen *core.BlockGen) {
+		var (
+			to    common.Address
+			value = big.NewInt(1)
+			gas   = params.TxGas
+		)
+		switch i {
+		case 1:
+			to = fresh
+		case 2:
+			to, value, gas = revived, new(big.Int), 50000
+		case 3:
+			to = revived
+		default:
+			return
+		}
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), to, value, gas, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	stack, _ := createGQLChain(t, genesis, blocks, Limits{})
+	defer stack.Close()
+
+	query := func(query string, result interface{}) {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		resp, err := http.Post("http://127.0.0.1:9393/graphql", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("could not issue graphql request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var res struct {
+			Data   json.RawMessage
+			Errors []struct{ Message string }
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("could not decode graphql response: %v", err)
+		}
+		if len(res.Errors) > 0 {
+			t.Fatalf("query %q failed: %v", query, res.Errors)
+		}
+		if err := json.Unmarshal(res.Data, result); err != nil {
+			t.Fatalf("could not decode graphql data %s: %v", res.Data, err)
+		}
+	}
+	// Check the code metadata
+	var meta struct {
+		Block struct {
+			Account struct {
+				CodeHash common.Hash
+				CodeSize hexutil.Uint64
+			}
+		}
+	}
+	query(fmt.Sprintf(`{ block { account(address: "%s") { codeHash codeSize } } }`, contract.Hex()), &meta)
+	if have, want := meta.Block.Account.CodeHash, crypto.Keccak256Hash(code); have != want {
+		t.Errorf("code hash mismatch: have %x, want %x", have, want)
+	}
+	if have, want := uint64(meta.Block.Account.CodeSize), uint64(len(code)); have != want {
+		t.Errorf("code size mismatch: have %d, want %d", have, want)
+	}
+	// Iterate the storage in pages and check it against the allocation
+	type storageRange struct {
+		Block struct {
+			Account struct {
+				StorageRange struct {
+					Slots []struct {
+						Hash  common.Hash
+						Key   *common.Hash
+						Value common.Hash
+					}
+					Next *common.Hash
+				}
+			}
+		}
+	}
+	var (
+		start = common.Hash{}
+		seen  = make(map[common.Hash]common.Hash)
+		prev  common.Hash
+	)
+	for pages := 0; ; pages++ {
+		if pages > len(storage) {
+			t.Fatalf("storage range not terminated")
+		}
+		var res storageRange
+		query(fmt.Sprintf(`{ block { account(address: "%s") { storageRange(start: "%s", count: 2) { slots { hash key value } next } } } }`, contract.Hex(), start.Hex()), &res)
+
+		for _, slot := range res.Block.Account.StorageRange.Slots {
+			if bytes.Compare(slot.Hash[:], prev[:]) <= 0 && prev != (common.Hash{}) {
+				t.Errorf("storage range out of order: %x after %x", slot.Hash, prev)
+			}
+			prev = slot.Hash
+			if slot.Key == nil || crypto.Keccak256Hash(slot.Key[:]) != slot.Hash {
+				t.Errorf("slot %x: invalid key %v", slot.Hash, slot.Key)
+				continue
+			}
+			seen[*slot.Key] = slot.Value
+		}
+		if res.Block.Account.StorageRange.Next == nil {
+			break
+		}
+		start = *res.Block.Account.StorageRange.Next
+	}
+	assert.Equal(t, storage, seen)
+
+	// Verify the account and storage proofs against the state root
+	var proof struct {
+		Block struct {
+			StateRoot common.Hash
+			Account   struct {
+				Proof struct {
+					AccountProof []hexutil.Bytes
+					StorageHash  common.Hash
+					StorageProof []struct {
+						Key   common.Hash
+						Value common.Hash
+						Proof []hexutil.Bytes
+					}
+				}
+			}
+		}
+	}
+	query(fmt.Sprintf(`{ block { stateRoot account(address: "%s") { proof(slots: ["0x%064x", "0x%064x"]) { accountProof storageHash storageProof { key value proof } } } } }`, contract.Hex(), 2, 4), &proof)
+
+	verify := func(root common.Hash, key []byte, nodes []hexutil.Bytes) []byte {
+		proofDb := rawdb.NewMemoryDatabase()
+		for _, node := range nodes {
+			proofDb.Put(crypto.Keccak256(node), node)
+		}
+		value, err := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+		if err != nil {
+			t.Fatalf("invalid proof for %x: %v", key, err)
+		}
+		return value
+	}
+	if verify(proof.Block.StateRoot, contract.Bytes(), proof.Block.Account.Proof.AccountProof) == nil {
+		t.Errorf("account proof proves absence")
+	}
+	for _, slot := range proof.Block.Account.Proof.StorageProof {
+		value := verify(proof.Block.Account.Proof.StorageHash, slot.Key.Bytes(), slot.Proof)
+		if slot.Value != storage[slot.Key] {
+			t.Errorf("slot %x: value mismatch: have %x, want %x", slot.Key, slot.Value, storage[slot.Key])
+		}
+		if (value == nil) != (storage[slot.Key] == common.Hash{}) {
+			t.Errorf("slot %x: proven value mismatch: %x", slot.Key, value)
+		}
+	}
+	// Look up the history of the accounts
+	for _, tt := range []struct {
+		address      common.Address
+		firstSeen    *hexutil.Uint64
+		lastModified *hexutil.Uint64
+	}{
+		{sender, newUint64(0), newUint64(4)},
+		{contract, newUint64(0), newUint64(0)},
+		{fresh, newUint64(2), newUint64(2)},
+		{revived, newUint64(4), newUint64(4)},
+		{common.HexToAddress("0xdead"), nil, nil},
+	} {
+		var res struct {
+			Block struct {
+				Account struct {
+					FirstSeenBlock    *struct{ Number hexutil.Uint64 }
+					LastModifiedBlock *struct{ Number hexutil.Uint64 }
+				}
+			}
+		}
+		query(fmt.Sprintf(`{ block { account(address: "%s") { firstSeenBlock { number } lastModifiedBlock { number } } } }`, tt.address.Hex()), &res)
+
+		var firstSeen, lastModified *hexutil.Uint64
+		if res.Block.Account.FirstSeenBlock != nil {
+			firstSeen = &res.Block.Account.FirstSeenBlock.Number
+		}
+		if res.Block.Account.LastModifiedBlock != nil {
+			lastModified = &res.Block.Account.LastModifiedBlock.Number
+		}
+		assert.Equal(t, tt.firstSeen, firstSeen, "first seen block of %x", tt.address)
+		assert.Equal(t, tt.lastModified, lastModified, "last modified block of %x", tt.address)
+	}
+}
+
+// Tests that queries exceeding the configured limits are rejected with structured
+// errors, while the ones within the limits are served.
+func TestGraphQLQueryLimits(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		idle    = common.HexToAddress("0x1d") // Account never modified
+		signer  = types.HomesteadSigner{}
+		genesis = &core.Genesis{
+			Config:   params.AllEthashProtocolChanges,
+			GasLimit: 10000000,
+			Alloc: core.GenesisAlloc{
+				sender: {Balance: big.NewInt(1000000000000000000)},
+				idle:   {Balance: big.NewInt(1)},
+			},
+		}
+	)
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 8, func(i int, gen *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), common.Address{}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	tests := []struct {
+		limits   Limits
+		accepted string
+		rejected string
+		code     string
+	}{
+		{
+			limits:   Limits{MaxDepth: 3},
+			accepted: "{block{parent{number}}}",
+			rejected: "{block{parent{parent{number}}}}",
+			code:     codeDepthLimit,
+		},
+		// Fields resolved over nested lists exhaust the budget while executing
+		{
+			limits:   Limits{MaxComplexity: 40},
+			accepted: "{blocks(from:7){number transactions{hash}}}",
+			rejected: "{blocks(from:0){number transactions{hash}}}",
+			code:     codeComplexityLimit,
+		},
+		// Large lists are rejected before being assembled
+		{
+			limits:   Limits{MaxComplexity: 5},
+			accepted: "{blocks(from:7){number}}",
+			rejected: "{blocks(from:0,to:1000000000){number}}",
+			code:     codeComplexityLimit,
+		},
+		// Every block walked back over to look up the history of an account is charged
+		{
+			limits:   Limits{MaxComplexity: 6},
+			accepted: fmt.Sprintf("{block{account(address:\"%s\"){lastModifiedBlock{number}}}}", sender.Hex()),
+			rejected: fmt.Sprintf("{block{account(address:\"%s\"){firstSeenBlock{number}}}}", idle.Hex()),
+			code:     codeComplexityLimit,
+		},
+		{
+			limits:   Limits{MaxResultSize: 256},
+			accepted: "{blocks(from:7){hash}}",
+			rejected: "{blocks(from:0){hash}}",
+			code:     codeResultSizeLimit,
+		},
+		{
+			limits:   Limits{Timeout: time.Nanosecond},
+			rejected: "{block{number}}",
+			code:     codeTimeout,
+		},
+	}
+	for i, tt := range tests {
+		stack, _ := createGQLChain(t, genesis, blocks, tt.limits)
+
+		query := func(query string) (json.RawMessage, []*gqlerrors.QueryError) {
+			body, _ := json.Marshal(map[string]string{"query": query})
+			resp, err := http.Post("http://127.0.0.1:9393/graphql", "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("test %d: could not issue graphql request: %v", i, err)
+			}
+			defer resp.Body.Close()
+
+			var res struct {
+				Data   json.RawMessage
+				Errors []*gqlerrors.QueryError
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+				t.Fatalf("test %d: could not decode graphql response: %v", i, err)
+			}
+			return res.Data, res.Errors
+		}
+		if tt.accepted != "" {
+			if data, errs := query(tt.accepted); len(errs) > 0 || len(data) == 0 {
+				t.Errorf("test %d: query %q failed: %v", i, tt.accepted, errs)
+			}
+		}
+		data, errs := query(tt.rejected)
+		if data != nil && string(data) != "null" {
+			t.Errorf("test %d: rejected query %q returned data: %s", i, tt.rejected, data)
+		}
+		if len(errs) != 1 {
+			t.Errorf("test %d: rejected query %q error count mismatch: have %d, want 1: %v", i, tt.rejected, len(errs), errs)
+		} else if code := errs[0].Extensions["code"]; code != tt.code {
+			t.Errorf("test %d: rejected query %q error code mismatch: have %v, want %v", i, tt.rejected, code, tt.code)
+		}
+		stack.Close()
+	}
+}
+
+// Tests that every event of a subscription is resolved within the complexity
+// budget of a query, ending the subscription once an event exceeds it.
+func TestGraphQLSubscriptionLimits(t *testing.T) {
+	genesis := &core.Genesis{
+		Config:   params.AllEthashProtocolChanges,
+		GasLimit: 10000000,
+	}
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 10, nil)
+
+	stack, ethBackend := createGQLChain(t, genesis, nil, Limits{MaxComplexity: 2})
+	defer stack.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws://127.0.0.1:9393/graphql", nil)
+	if err != nil {
+		t.Fatalf("could not dial graphql websocket: %v", err)
+	}
+	defer conn.Close()
+
+	messages := make(chan *wsMessage, 64)
+	go func() {
+		defer close(messages)
+		for {
+			msg := new(wsMessage)
+			if err := conn.ReadJSON(msg); err != nil {
+				return
+			}
+			if msg.Type != gqlConnectionKeepAlive {
+				messages <- msg
+			}
+		}
+	}()
+	send := func(id, typ string, payload interface{}) {
+		msg := map[string]interface{}{"id": id, "type": typ, "payload": payload}
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("failed to send %s message: %v", typ, err)
+		}
+	}
+	send("", gqlConnectionInit, nil)
+	if msg := <-messages; msg == nil || msg.Type != gqlConnectionAck {
+		t.Fatalf("connection not acknowledged: %v", msg)
+	}
+	send("cheap", gqlStart, map[string]interface{}{"query": "subscription { newHeads { number } }"})
+	send("costly", gqlStart, map[string]interface{}{"query": "subscription { newHeads { number hash parent { hash } } }"})
+
+	// Import blocks until the cheap subscription was notified of several events,
+	// each charged separately, and the costly one was rejected
+	var (
+		heads    int
+		rejected []*gqlerrors.QueryError
+		complete bool
+	)
+	for i := 0; heads < 3 || !complete; i++ {
+		if i == len(blocks) {
+			t.Fatalf("subscriptions not notified: heads %d, rejected %v, complete %v", heads, rejected, complete)
+		}
+		if _, err := ethBackend.BlockChain().InsertChain(blocks[i : i+1]); err != nil {
+			t.Fatalf("failed to import block %d: %v", i+1, err)
+		}
+		timeout := time.After(250 * time.Millisecond)
+	wait:
+		for {
+			select {
+			case msg := <-messages:
+				if msg == nil {
+					t.Fatalf("connection closed")
+				}
+				switch {
+				case msg.ID == "cheap" && msg.Type == gqlData:
+					var res struct{ Errors []*gqlerrors.QueryError }
+					if err := json.Unmarshal(msg.Payload, &res); err != nil || len(res.Errors) > 0 {
+						t.Fatalf("cheap subscription failed: %s", msg.Payload)
+					}
+					heads++
+				case msg.ID == "costly" && msg.Type == gqlData:
+					if complete || rejected != nil {
+						t.Fatalf("rejected subscription notified: %s", msg.Payload)
+					}
+					var res struct{ Errors []*gqlerrors.QueryError }
+					if err := json.Unmarshal(msg.Payload, &res); err != nil {
+						t.Fatalf("invalid costly subscription payload %s: %v", msg.Payload, err)
+					}
+					rejected = res.Errors
+				case msg.ID == "costly" && msg.Type == gqlComplete:
+					complete = true
+				default:
+					t.Fatalf("unexpected %s message for %s: %s", msg.Type, msg.ID, msg.Payload)
+				}
+			case <-timeout:
+				break wait
+			}
+		}
+	}
+	if len(rejected) != 1 {
+		t.Fatalf("rejection error count mismatch: have %d, want 1: %v", len(rejected), rejected)
+	}
+	if code := rejected[0].Extensions["code"]; code != codeComplexityLimit {
+		t.Errorf("rejection error code mismatch: have %v, want %v", code, codeComplexityLimit)
+	}
+}
+
+// Tests that the type of the operation to run is found in GraphQL documents,
+// regardless of their names, fragments, comments and strings.
+func TestOperationType(t *testing.T) {
+	tests := []struct {
+		document  string
+		operation string
+		want      string
+	}{
+		{document: "{ block { number } }", want: "query"},
+		{document: "query { block { number } }", want: "query"},
+		{document: "subscription { newHeads { number } }", want: "subscription"},
+		{document: "mutation Send($data: Bytes!) { sendRawTransaction(data: $data) }", want: "mutation"},
+		{document: "# subscription\nsubscription Heads{newHeads{...Number}} fragment Number on Block{number}", want: "subscription"},
+		{document: `query Q($f: FilterCriteria = {addresses: ["subscription"]}) @a(b: "{") { logs(filter: $f) { data } }`, want: "query"},
+		{document: `query Q @d(s: """ subscription \""" { """) { block { number } }`, want: "query"},
+		{document: "query A { block { number } } subscription B { newHeads { number } }", operation: "B", want: "subscription"},
+		{document: "query A { block { number } } subscription B { newHeads { number } }", operation: "A", want: "query"},
+		{document: "query A { block { number } } subscription B { newHeads { number } }", want: ""},
+		{document: "subscription A { newHeads { number } }", operation: "B", want: ""},
+		{document: "subscription { newHeads { number }", want: "subscription"},
+		{document: "subscription { newHeads { number } } }", want: ""},
+		{document: `subscription { logs(filter: {addresses: ["0x00]}) { data } }`, want: ""},
+		{document: "schema { query: Query }", want: ""},
+	}
+	for i, tt := range tests {
+		if have := operationType(tt.document, tt.operation); have != tt.want {
+			t.Errorf("test %d: operation type mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
+
+func newUint64(n uint64) *hexutil.Uint64 {
+	v := hexutil.Uint64(n)
+	return &v
+}
+
+// createGQLChain starts a node serving graphql on a chain with the given genesis,
+// importing the given blocks.
+func createGQLChain(t *testing.T, genesis *core.Genesis, blocks []*types.Block, limits Limits) (*node.Node, *protocol.Ethereum) {
+	stack := createNode(t, false)
+
+	config := protocol.DefaultConfig
+	config.Genesis = genesis
+	config.Ethash.PowMode = ethash.ModeFake
+
+	ethBackend, err := protocol.New(stack, &config)
+	if err != nil {
+		t.Fatalf("could not create protocol backend: %v", err)
+	}
+	if err := New(stack, ethBackend.APIBackend, false, []string{}, []string{}, limits); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	if _, err := ethBackend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("could not import chain: %v", err)
+	}
+	return stack, ethBackend
+}
+
 func createGQLService(t *testing.T, stack *node.Node, endpoint string) {
 	// create backend
 	ethBackend, err := protocol.New(stack, &protocol.DefaultConfig)
@@ -117,7 +859,7 @@ func createGQLService(t *testing.T, stack *node.Node, endpoint string) {
 	}
 
 	// create gql service
-	err = New(stack, ethBackend.APIBackend, []string{}, []string{})
+	err = New(stack, ethBackend.APIBackend, false, []string{}, []string{}, Limits{})
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
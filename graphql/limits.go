@@ -0,0 +1,343 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/metrics"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/trace"
+)
+
+// Limits bounds the resources a single GraphQL query may consume. Zero values
+// disable the respective limit.
+//
+// The complexity of a query is the number of fields it resolves, plus the number
+// of elements of the block, transaction and log lists it requests. The lists are
+// charged before being assembled, so nested selections over large ranges are
+// rejected without resolving them. The complexity and timeout limits apply to
+// every event of a subscription, the subscription being ended by the first event
+// exceeding them.
+type Limits struct {
+	MaxDepth      int           // Maximum nesting depth of the selections of a query
+	MaxComplexity int           // Maximum complexity of a query
+	MaxResultSize int           // Maximum size in bytes of the encoded result of a query
+	Timeout       time.Duration // Maximum time spent executing a query
+}
+
+// Error codes reported in the extensions of the errors of rejected queries.
+const (
+	codeDepthLimit      = "DEPTH_LIMIT_EXCEEDED"
+	codeComplexityLimit = "COMPLEXITY_LIMIT_EXCEEDED"
+	codeResultSizeLimit = "RESULT_SIZE_LIMIT_EXCEEDED"
+	codeTimeout         = "TIMEOUT"
+)
+
+// maxDepthRule is the validation rule of graphql-go rejecting queries nested
+// deeper than the maximum depth.
+const maxDepthRule = "MaxDepthExceeded"
+
+var (
+	queryMeter          = metrics.NewRegisteredMeter("graphql/queries", nil)
+	queryTimer          = metrics.NewRegisteredTimer("graphql/queries/duration", nil)
+	complexityHistogram = metrics.NewRegisteredHistogram("graphql/queries/complexity", nil, metrics.NewExpDecaySample(1028, 0.015))
+	resultSizeHistogram = metrics.NewRegisteredHistogram("graphql/queries/size", nil, metrics.NewExpDecaySample(1028, 0.015))
+
+	rejectedDepthMeter      = metrics.NewRegisteredMeter("graphql/rejected/depth", nil)
+	rejectedComplexityMeter = metrics.NewRegisteredMeter("graphql/rejected/complexity", nil)
+	rejectedSizeMeter       = metrics.NewRegisteredMeter("graphql/rejected/size", nil)
+	rejectedTimeoutMeter    = metrics.NewRegisteredMeter("graphql/rejected/timeout", nil)
+)
+
+// limitError is the error of a query exceeding one of its limits.
+type limitError struct {
+	code    string
+	message string
+	limit   interface{}
+}
+
+// Error implements error.
+func (e *limitError) Error() string {
+	return e.message
+}
+
+// Extensions returns the structured details of the error, included by graphql-go
+// in the errors of the response.
+func (e *limitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":  e.code,
+		"limit": e.limit,
+	}
+}
+
+// response returns a response rejecting the query with the error.
+func (e *limitError) response() *graphql.Response {
+	return &graphql.Response{
+		Errors: []*gqlerrors.QueryError{{Message: e.message, Extensions: e.Extensions()}},
+	}
+}
+
+// queryCost tracks the complexity of a running query.
+type queryCost struct {
+	used   int64              // Complexity charged so far (atomic)
+	limit  int64              // Maximum complexity allowed, 0 if unlimited
+	cancel context.CancelFunc // Cancels the query once its budget is exhausted
+}
+
+// queryCostKey is the context key of the cost of the running query.
+type queryCostKey struct{}
+
+// exceeded reports whether the query exhausted its complexity budget.
+func (c *queryCost) exceeded() bool {
+	return c.limit > 0 && atomic.LoadInt64(&c.used) > c.limit
+}
+
+// error returns the error of a query exceeding its complexity budget.
+func (c *queryCost) error() *limitError {
+	return &limitError{
+		code:    codeComplexityLimit,
+		message: fmt.Sprintf("query complexity exceeds limit of %d", c.limit),
+		limit:   c.limit,
+	}
+}
+
+// chargeQuery adds the given complexity to the query running in the context. If
+// the query exhausted its budget, it gets cancelled and an error is returned.
+// Queries executed without a budget are not charged.
+func chargeQuery(ctx context.Context, n int) error {
+	cost, ok := ctx.Value(queryCostKey{}).(*queryCost)
+	if !ok {
+		return nil
+	}
+	if atomic.AddInt64(&cost.used, int64(n)); cost.exceeded() {
+		cost.cancel()
+		return cost.error()
+	}
+	return nil
+}
+
+// timeoutError returns the error of a query exceeding its timeout.
+func timeoutError(timeout time.Duration) *limitError {
+	return &limitError{
+		code:    codeTimeout,
+		message: fmt.Sprintf("query execution exceeds timeout of %v", timeout),
+		limit:   timeout.String(),
+	}
+}
+
+// eventBudget bounds the resources spent resolving the events of a subscription.
+// Events are admitted one at a time, each getting the full complexity budget and
+// timeout of a query.
+type eventBudget struct {
+	cost    *queryCost    // Complexity of the event being resolved
+	timeout time.Duration // Maximum time spent resolving an event, 0 if unlimited
+	ready   chan struct{} // Signalled once the previous event has been resolved
+
+	timer   *time.Timer // Timer cancelling the subscription once the event times out
+	expired bool        // Whether the event being resolved timed out
+	lock    sync.Mutex  // Lock protecting the timer and its expiry
+}
+
+// eventBudgetKey is the context key of the event budget of the running subscription.
+type eventBudgetKey struct{}
+
+// newEventBudget creates a budget for the events of a subscription, cancelled
+// through the given function once an event exceeds its limits.
+func newEventBudget(limits Limits, cancel context.CancelFunc) *eventBudget {
+	b := &eventBudget{
+		cost:    &queryCost{limit: int64(limits.MaxComplexity), cancel: cancel},
+		timeout: limits.Timeout,
+		ready:   make(chan struct{}, 1),
+	}
+	b.ready <- struct{}{}
+	return b
+}
+
+// context returns a context charging the fields resolved by the subscription to
+// the budget of its current event.
+func (b *eventBudget) context(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, queryCostKey{}, b.cost)
+	return context.WithValue(ctx, eventBudgetKey{}, b)
+}
+
+// admitEvent waits until the previous event of the subscription running in the
+// context has been resolved, then starts accounting for the next one. It returns
+// false if the subscription ended in the meantime. Subscriptions without a budget
+// admit all events immediately.
+func admitEvent(ctx context.Context) bool {
+	b, ok := ctx.Value(eventBudgetKey{}).(*eventBudget)
+	if !ok {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-b.ready:
+	case <-ctx.Done():
+		return false
+	}
+	atomic.StoreInt64(&b.cost.used, 0)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.timeout > 0 {
+		b.timer = time.AfterFunc(b.timeout, func() {
+			b.lock.Lock()
+			b.expired = true
+			b.lock.Unlock()
+
+			b.cost.cancel()
+		})
+	}
+	return true
+}
+
+// resolved stops accounting for the event being resolved and admits the next
+// one, unless the event exceeded its limits, in which case the error is returned.
+func (b *eventBudget) resolved() *limitError {
+	b.lock.Lock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	expired := b.expired
+	b.lock.Unlock()
+
+	complexityHistogram.Update(atomic.LoadInt64(&b.cost.used))
+	if b.cost.exceeded() {
+		rejectedComplexityMeter.Mark(1)
+		return b.cost.error()
+	}
+	if expired {
+		rejectedTimeoutMeter.Mark(1)
+		return timeoutError(b.timeout)
+	}
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// costTracer is a tracer charging every resolved field to the complexity budget
+// of its query. Resolvers are not run anymore once the budget is exhausted, as
+// graphql-go skips them when the context gets cancelled.
+type costTracer struct {
+	trace.OpenTracingTracer
+}
+
+// TraceField implements trace.Tracer.
+func (t costTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	chargeQuery(ctx, 1)
+	return t.OpenTracingTracer.TraceField(ctx, label, typeName, fieldName, trivial, args)
+}
+
+// executor runs queries against a schema within the configured limits, serving
+// them to HTTP requests the same way relay.Handler does.
+type executor struct {
+	schema *graphql.Schema
+	limits Limits
+}
+
+// exec runs a query, replacing its response with a structured error if it
+// exceeds its complexity budget or timeout.
+func (e *executor) exec(ctx context.Context, query string, operationName string, variables map[string]interface{}) *graphql.Response {
+	start := time.Now()
+	queryMeter.Mark(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cost := &queryCost{limit: int64(e.limits.MaxComplexity), cancel: cancel}
+	if e.limits.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, e.limits.Timeout)
+		defer cancelTimeout()
+	}
+	response := e.schema.Exec(context.WithValue(ctx, queryCostKey{}, cost), query, operationName, variables)
+
+	queryTimer.UpdateSince(start)
+	complexityHistogram.Update(atomic.LoadInt64(&cost.used))
+
+	if cost.exceeded() {
+		rejectedComplexityMeter.Mark(1)
+		return cost.error().response()
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		rejectedTimeoutMeter.Mark(1)
+		return timeoutError(e.limits.Timeout).response()
+	}
+	var rejected bool
+	for _, err := range response.Errors {
+		if err.Rule == maxDepthRule {
+			err.Extensions = (&limitError{code: codeDepthLimit, limit: e.limits.MaxDepth}).Extensions()
+			rejected = true
+		}
+	}
+	if rejected {
+		rejectedDepthMeter.Mark(1)
+	}
+	return response
+}
+
+// encode marshals a response, replacing it with a structured error if it exceeds
+// the maximum result size.
+func (e *executor) encode(response *graphql.Response) ([]byte, error) {
+	blob, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	resultSizeHistogram.Update(int64(len(blob)))
+
+	if e.limits.MaxResultSize > 0 && len(blob) > e.limits.MaxResultSize {
+		rejectedSizeMeter.Mark(1)
+		return json.Marshal((&limitError{
+			code:    codeResultSizeLimit,
+			message: fmt.Sprintf("query result size exceeds limit of %d bytes", e.limits.MaxResultSize),
+			limit:   e.limits.MaxResultSize,
+		}).response())
+	}
+	return blob, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (e *executor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := e.exec(r.Context(), params.Query, params.OperationName, params.Variables)
+	responseJSON, err := e.encode(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
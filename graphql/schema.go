@@ -16,6 +16,9 @@
 
 package graphql
 
+// schema is the GraphQL schema served over HTTP and to the queries and mutations
+// of WebSocket connections. Its root operation types are the Query and Mutation
+// types.
 const schema string = `
     # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
     scalar Bytes32
@@ -31,11 +34,6 @@ const schema string = `
     # Long is a 64 bit unsigned integer.
     scalar Long
 
-    schema {
-        query: Query
-        mutation: Mutation
-    }
-
     # Account is an Ethereum account at a particular block.
     type Account {
         # Address is the address owning the account.
@@ -52,6 +50,72 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # CodeHash is the Keccak256 hash of the code of this account.
+        codeHash: Bytes32!
+        # CodeSize is the size of the code of this account, in bytes.
+        codeSize: Long!
+        # StorageRange iterates the storage of a contract account in the order of
+        # the slot hashes, starting at the given hash (or the first slot if none
+        # is supplied) and returning at most count slots.
+        storageRange(start: Bytes32, count: Int!): StorageRange!
+        # Proof returns the Merkle proof of this account and of the given storage
+        # slots, as returned by eth_getProof.
+        proof(slots: [Bytes32!]): AccountProof!
+        # FirstSeenBlock is the block since which this account has continuously
+        # existed, or null if it doesn't exist. It's looked up by walking back
+        # over the historical states, failing if they aren't available or if
+        # the account is older than the maximum number of blocks walked (4096).
+        # As only archive nodes keep the states of old blocks, other nodes
+        # can only look up accounts modified within the last 128 blocks. Each
+        # block walked adds 1 to the complexity of the query.
+        firstSeenBlock: Block
+        # LastModifiedBlock is the block since which this account has been in
+        # its current state, or null if it doesn't exist. It's looked up like
+        # firstSeenBlock.
+        lastModifiedBlock: Block
+    }
+
+    # StorageSlot is a storage slot of a contract account.
+    type StorageSlot {
+        # Hash is the Keccak256 hash of the slot identifier, by which the storage
+        # is ordered.
+        hash: Bytes32!
+        # Key is the slot identifier, or null if its preimage is not known.
+        key: Bytes32
+        # Value is the value stored in the slot.
+        value: Bytes32!
+    }
+
+    # StorageRange is a range of the storage of a contract account.
+    type StorageRange {
+        # Slots are the storage slots in the range, ordered by hash.
+        slots: [StorageSlot!]!
+        # Next is the hash to continue iterating the storage from, or null if
+        # the end of the storage was reached.
+        next: Bytes32
+    }
+
+    # AccountProof is the Merkle proof of an account and of some of its storage
+    # slots.
+    type AccountProof {
+        # AccountProof is the list of state trie nodes on the path to the account,
+        # starting with the root node.
+        accountProof: [Bytes!]!
+        # StorageHash is the root hash of the storage trie of the account.
+        storageHash: Bytes32!
+        # StorageProof contains the proofs of the requested storage slots.
+        storageProof: [StorageProof!]!
+    }
+
+    # StorageProof is the Merkle proof of a storage slot.
+    type StorageProof {
+        # Key is the slot identifier.
+        key: Bytes32!
+        # Value is the value stored in the slot.
+        value: Bytes32!
+        # Proof is the list of storage trie nodes on the path to the slot,
+        # starting with the root node.
+        proof: [Bytes!]!
     }
 
     # Log is an Ethereum event log.
@@ -294,10 +358,21 @@ const schema string = `
       estimateGas(data: CallData!): Long!
     }
 
+    # BlockTag is a symbolic reference to a block relative to the chain head.
+    enum BlockTag {
+      # Latest is the most recent block of the canonical chain.
+      LATEST
+      # Safe is the most recent block that is unlikely to be reorged out.
+      SAFE
+      # Finalized is the most recent block that is not expected to ever be
+      # reorged out.
+      FINALIZED
+    }
+
     type Query {
-        # Block fetches an Ethereum block by number or by hash. If neither is
-        # supplied, the most recent known block is returned.
-        block(number: Long, hash: Bytes32): Block
+        # Block fetches an Ethereum block by number, by hash or by tag. If none
+        # is supplied, the most recent known block is returned.
+        block(number: Long, hash: Bytes32, tag: BlockTag): Block
         # Blocks returns all the blocks between two numbers, inclusive. If
         # to is not supplied, it defaults to the most recent known block.
         blocks(from: Long!, to: Long): [Block!]!
@@ -323,3 +398,33 @@ const schema string = `
         sendRawTransaction(data: Bytes!): Bytes32!
     }
 `
+
+// subscriptionSchema extends the object types of the regular schema with the
+// subscriptions served to WebSocket connections. The root types of a schema
+// are resolved by a single object, which cannot serve both the logs query and
+// the logs subscription, hence subscriptions are executed against a schema of
+// their own.
+const subscriptionSchema string = `
+    schema {
+        query: SubscriptionQuery
+        subscription: Subscription
+    }
+
+    # SubscriptionQuery is the query root of the subscription schema. Queries
+    # sent over WebSocket connections are executed against the regular schema.
+    type SubscriptionQuery {
+        # ChainID returns the current chain ID for transaction replay protection.
+        chainID: BigInt!
+    }
+
+    type Subscription {
+        # NewHeads streams the blocks imported into the canonical chain.
+        newHeads: Block!
+        # Logs streams the log entries of newly imported blocks matching the
+        # provided filter.
+        logs(filter: BlockFilterCriteria!): Log!
+        # PendingTransactions streams the transactions entering the pending
+        # state.
+        pendingTransactions: Transaction!
+    }
+`
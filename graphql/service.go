@@ -19,30 +19,41 @@ package graphql
 import (
 	"github.com/ccm-chain/ccmchain/internal/api"
 	"github.com/ccm-chain/ccmchain/node"
+	"github.com/ccm-chain/ccmchain/protocol/filters"
 	"github.com/graph-gophers/graphql-go"
-	"github.com/graph-gophers/graphql-go/relay"
 )
 
-// New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend api.Backend, cors, vhosts []string) error {
+// New constructs a new GraphQL service instance, running queries within the
+// given limits.
+func New(stack *node.Node, backend api.Backend, lightMode bool, cors, vhosts []string, limits Limits) error {
 	if backend == nil {
 		panic("missing backend")
 	}
 	// check if http server with given endpoint exists and enable graphQL on it
-	return newHandler(stack, backend, cors, vhosts)
+	return newHandler(stack, backend, lightMode, cors, vhosts, limits)
 }
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend api.Backend, cors, vhosts []string) error {
+// It additionally exports an interactive query browser on the / endpoint, and
+// serves subscriptions to WebSocket connections upgraded from the GraphQL one.
+func newHandler(stack *node.Node, backend api.Backend, lightMode bool, cors, vhosts []string, limits Limits) error {
 	q := Resolver{backend}
 
-	s, err := graphql.ParseSchema(schema, &q)
+	opts := []graphql.SchemaOpt{graphql.MaxDepth(limits.MaxDepth), graphql.Tracer(costTracer{})}
+	s, err := graphql.ParseSchema(schema, &q, opts...)
 	if err != nil {
 		return err
 	}
-	h := &relay.Handler{Schema: s}
-	handler := node.NewHTTPHandlerStack(h, cors, vhosts)
+	sub := Subscription{Resolver: &q}
+	if backend != nil {
+		sub.events = filters.NewEventSystem(backend, lightMode)
+	}
+	subs, err := graphql.ParseSchema(schema+subscriptionSchema, &sub, opts...)
+	if err != nil {
+		return err
+	}
+	h := &executor{schema: s, limits: limits}
+	handler := newWebsocketHandler(h, subs, cors, node.NewHTTPHandlerStack(h, cors, vhosts))
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
@@ -0,0 +1,433 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// wsProtocol is the WebSocket subprotocol of the GraphQL over WebSocket
+	// transport, as specified by https://github.com/apollographql/subscriptions-transport-ws.
+	wsProtocol = "graphql-ws"
+
+	wsReadLimit         = 1024 * 1024      // Maximum size of a message sent by the client
+	wsWriteTimeout      = 10 * time.Second // Maximum time to write a message to the client
+	wsKeepAliveInterval = 30 * time.Second // Interval of the keep alive messages sent to the client
+	wsMaxOperations     = 64               // Maximum number of operations running on a connection
+)
+
+// Message types of the graphql-ws protocol.
+const (
+	gqlConnectionInit      = "connection_init"      // Client -> Server
+	gqlConnectionTerminate = "connection_terminate" // Client -> Server
+	gqlStart               = "start"                // Client -> Server
+	gqlStop                = "stop"                 // Client -> Server
+	gqlConnectionAck       = "connection_ack"       // Server -> Client
+	gqlConnectionError     = "connection_error"     // Server -> Client
+	gqlConnectionKeepAlive = "ka"                   // Server -> Client
+	gqlData                = "data"                 // Server -> Client
+	gqlError               = "error"                // Server -> Client
+	gqlComplete            = "complete"             // Server -> Client
+)
+
+var (
+	errOperationInUse    = errors.New("operation id already in use")
+	errTooManyOperations = errors.New("too many operations")
+)
+
+// wsMessage is a message of the graphql-ws protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsStartPayload is the payload of a message starting an operation.
+type wsStartPayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsErrorPayload is the payload of a message reporting an error.
+type wsErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// wsHandler serves GraphQL over the WebSocket connections upgraded from requests
+// to the GraphQL endpoint, passing all other requests to the HTTP handler.
+type wsHandler struct {
+	executor      *executor       // Executor of queries and mutations
+	subscriptions *graphql.Schema // Schema executing subscriptions
+	upgrader      websocket.Upgrader
+	next          http.Handler
+}
+
+// newWebsocketHandler creates a handler serving GraphQL over WebSocket, accepting
+// connections from the given origins. If no origins are given, only same origin
+// connections are accepted.
+func newWebsocketHandler(executor *executor, subscriptions *graphql.Schema, origins []string, next http.Handler) *wsHandler {
+	h := &wsHandler{
+		executor:      executor,
+		subscriptions: subscriptions,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{wsProtocol},
+		},
+		next: next,
+	}
+	if len(origins) > 0 {
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			for _, allowed := range origins {
+				if allowed == "*" || strings.EqualFold(allowed, origin) {
+					return true
+				}
+			}
+			log.Warn("Rejected GraphQL WebSocket connection", "origin", origin)
+			return false
+		}
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL WebSocket upgrade failed", "err", err)
+		return
+	}
+	c := &wsConn{
+		handler: h,
+		conn:    conn,
+		ops:     make(map[string]context.CancelFunc),
+	}
+	c.serve()
+}
+
+// wsConn is a WebSocket connection running GraphQL operations.
+type wsConn struct {
+	handler *wsHandler
+	conn    *websocket.Conn
+
+	ops     map[string]context.CancelFunc // Cancel functions of the running operations
+	opsLock sync.Mutex                    // Lock protecting the running operations
+	wg      sync.WaitGroup                // Wait group of the goroutines serving the connection
+
+	writeLock sync.Mutex // Lock serialising the messages sent to the client
+}
+
+// serve reads the messages of the client until the connection is terminated,
+// then stops all running operations.
+func (c *wsConn) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.conn.Close()
+		c.wg.Wait()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+
+	var initialised bool
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.sendError("", gqlConnectionError, err)
+			continue
+		}
+		switch msg.Type {
+		case gqlConnectionInit:
+			c.send(&wsMessage{Type: gqlConnectionAck})
+			if !initialised {
+				initialised = true
+				c.wg.Add(1)
+				go c.keepAlive(ctx)
+			}
+		case gqlStart:
+			c.start(ctx, msg.ID, msg.Payload)
+		case gqlStop:
+			c.stop(msg.ID)
+		case gqlConnectionTerminate:
+			return
+		default:
+			c.sendError(msg.ID, gqlError, fmt.Errorf("unknown message type %q", msg.Type))
+		}
+	}
+}
+
+// keepAlive periodically sends keep alive messages to the client, until the
+// connection is terminated.
+func (c *wsConn) keepAlive(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(wsKeepAliveInterval)
+	defer ticker.Stop()
+
+	c.send(&wsMessage{Type: gqlConnectionKeepAlive})
+	for {
+		select {
+		case <-ticker.C:
+			c.send(&wsMessage{Type: gqlConnectionKeepAlive})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// start launches a new operation with the given id.
+func (c *wsConn) start(ctx context.Context, id string, payload json.RawMessage) {
+	var start wsStartPayload
+	if err := json.Unmarshal(payload, &start); err != nil {
+		c.sendError(id, gqlError, err)
+		return
+	}
+	c.opsLock.Lock()
+	if _, ok := c.ops[id]; ok {
+		c.opsLock.Unlock()
+		c.sendError(id, gqlError, errOperationInUse)
+		return
+	}
+	if len(c.ops) >= wsMaxOperations {
+		c.opsLock.Unlock()
+		c.sendError(id, gqlError, errTooManyOperations)
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.ops[id] = cancel
+	c.opsLock.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.stop(id)
+
+		c.run(ctx, id, &start)
+	}()
+}
+
+// stop cancels the operation with the given id, if it's still running.
+func (c *wsConn) stop(id string) {
+	c.opsLock.Lock()
+	defer c.opsLock.Unlock()
+
+	if cancel, ok := c.ops[id]; ok {
+		cancel()
+		delete(c.ops, id)
+	}
+}
+
+// run executes an operation, sending its results to the client. Queries and
+// mutations are executed within the query limits against the regular schema,
+// subscriptions (which the regular schema refuses to execute) against the
+// subscription schema, each of their events within the query limits. Operations
+// stopped by the client are not completed.
+func (c *wsConn) run(ctx context.Context, id string, start *wsStartPayload) {
+	if operationType(start.Query, start.OperationName) != "subscription" {
+		response := c.handler.executor.exec(ctx, start.Query, start.OperationName, start.Variables)
+		c.sendResponse(id, response)
+		c.send(&wsMessage{ID: id, Type: gqlComplete})
+		return
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	budget := newEventBudget(c.handler.executor.limits, cancel)
+	responses, err := c.handler.subscriptions.Subscribe(budget.context(subCtx), start.Query, start.OperationName, start.Variables)
+	if err != nil {
+		c.sendError(id, gqlError, err)
+		return
+	}
+	var rejected bool
+	for response := range responses {
+		// Drain the responses of ended subscriptions, releasing graphql-go
+		if ctx.Err() != nil || rejected {
+			continue
+		}
+		if err := budget.resolved(); err != nil {
+			c.sendResponse(id, err.response())
+			rejected = true
+			continue
+		}
+		c.sendResponse(id, response.(*graphql.Response))
+	}
+	if ctx.Err() == nil {
+		c.send(&wsMessage{ID: id, Type: gqlComplete})
+	}
+}
+
+// sendResponse sends the result of an operation to the client, unless it exceeds
+// the maximum result size.
+func (c *wsConn) sendResponse(id string, response *graphql.Response) {
+	payload, err := c.handler.executor.encode(response)
+	if err != nil {
+		c.sendError(id, gqlError, err)
+		return
+	}
+	c.send(&wsMessage{ID: id, Type: gqlData, Payload: payload})
+}
+
+// sendError sends an error message of the given type to the client.
+func (c *wsConn) sendError(id string, typ string, err error) {
+	payload, _ := json.Marshal(&wsErrorPayload{Message: err.Error()})
+	c.send(&wsMessage{ID: id, Type: typ, Payload: payload})
+}
+
+// send writes a message to the client, closing the connection if it fails.
+func (c *wsConn) send(msg *wsMessage) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Debug("Failed to send GraphQL WebSocket message", "err", err)
+		c.conn.Close()
+	}
+}
+
+// operationType returns the type of the operation of a GraphQL document to run,
+// the one with the given name or else the only one of the document: "query",
+// "mutation" or "subscription". The document is only scanned as far as needed
+// to find its operations, so an empty string is returned if it is malformed or
+// the operation isn't found, leaving it to graphql-go to reject it.
+func operationType(document string, operationName string) string {
+	var (
+		types, names []string // Types and names of the operations of the document
+		depth        int      // Nesting depth of the selection sets and object values
+		parens       int      // Nesting depth of the arguments and variable definitions
+		inDef        bool     // Whether the scanner is within a definition
+		named        bool     // Whether the name of an operation may follow
+	)
+	for i := 0; i < len(document); {
+		c := document[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+			continue
+
+		case c == '#':
+			for i < len(document) && document[i] != '\n' && document[i] != '\r' {
+				i++
+			}
+			continue
+
+		case strings.HasPrefix(document[i:], `"""`):
+			for i += 3; !strings.HasPrefix(document[i:], `"""`); i++ {
+				if i >= len(document) {
+					return ""
+				}
+				if strings.HasPrefix(document[i:], `\"""`) {
+					i += 3
+				}
+			}
+			i += 3
+
+		case c == '"':
+			for i++; i >= len(document) || document[i] != '"'; i++ {
+				if i >= len(document) || document[i] == '\n' || document[i] == '\r' {
+					return ""
+				}
+				if document[i] == '\\' {
+					i++
+				}
+			}
+			i++
+
+		case c == '(' || c == ')':
+			if c == '(' {
+				parens++
+			} else if parens--; parens < 0 {
+				return ""
+			}
+			i++
+
+		case c == '{':
+			if depth == 0 && parens == 0 && !inDef {
+				types, names = append(types, "query"), append(names, "")
+			}
+			depth, inDef = depth+1, true
+			i++
+
+		case c == '}':
+			if depth--; depth < 0 {
+				return ""
+			}
+			if depth == 0 && parens == 0 {
+				inDef = false
+			}
+			i++
+
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(document) && (document[i] == '_' || (document[i] >= 'a' && document[i] <= 'z') || (document[i] >= 'A' && document[i] <= 'Z') || (document[i] >= '0' && document[i] <= '9')) {
+				i++
+			}
+			name := document[start:i]
+			if depth > 0 || parens > 0 {
+				break
+			}
+			if named {
+				names[len(names)-1] = name
+				break
+			}
+			if !inDef {
+				switch name {
+				case "query", "mutation", "subscription":
+					types, names = append(types, name), append(names, "")
+					inDef, named = true, true
+					continue
+				case "fragment":
+					inDef = true
+				default:
+					return ""
+				}
+			}
+
+		default:
+			i++
+		}
+		named = false
+	}
+	for i, name := range names {
+		if (operationName == "" && len(names) == 1) || (operationName != "" && name == operationName) {
+			return types[i]
+		}
+	}
+	return ""
+}
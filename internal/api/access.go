@@ -0,0 +1,125 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/common/hexutil"
+	"github.com/ccm-chain/ccmchain/core"
+	"github.com/ccm-chain/ccmchain/core/vm"
+	"github.com/ccm-chain/ccmchain/rpc"
+)
+
+// AccessEntry is the state of an account accessed by a call.
+type AccessEntry struct {
+	Address     common.Address `json:"address"`
+	Code        bool           `json:"code"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// StateAccessResult is the state accessed by a call.
+type StateAccessResult struct {
+	Reads      []AccessEntry  `json:"reads"`
+	Writes     []AccessEntry  `json:"writes"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Iterations hexutil.Uint64 `json:"iterations"`
+	Stable     bool           `json:"stable"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// newAccessEntries converts a set of accessed state into a list sorted by address
+// and storage key.
+func newAccessEntries(access vm.StateAccess) []AccessEntry {
+	entries := make([]AccessEntry, 0, len(access))
+	for addr, account := range access {
+		keys := make([]common.Hash, 0, len(account.Slots))
+		for key := range account.Slots {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+		entries = append(entries, AccessEntry{Address: addr, Code: account.Code, StorageKeys: keys})
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].Address[:], entries[j].Address[:]) < 0 })
+	return entries
+}
+
+// GetStateAccess executes the given call on the state of the given block and
+// returns the accounts, code and storage slots it reads and writes, along with
+// the gas it uses. The call doesn't need to succeed, a reverting call reports
+// the state accessed up to the revert.
+//
+// As the path taken by a call may depend on the gas it is given, the state
+// accessed when executing with the gas cap may differ from the state accessed
+// by a transaction sent with an estimated gas limit. If iterate is set and the
+// call has no gas limit, a successful call is executed a second time with the
+// estimated gas limit, reporting the state accessed by that execution. The
+// access is stable if both executions accessed the same state, so it doesn't
+// depend on the gas given to the call. A single execution is always stable.
+//
+// Note, this function doesn't make any changes in the state/blockchain.
+func (s *PublicBlockChainAPI) GetStateAccess(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, iterate *bool) (*StateAccessResult, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	execute := func(args CallArgs) (*core.ExecutionResult, *vm.AccessTracer, error) {
+		tracer := vm.NewAccessTracer()
+		result, err := DoCall(ctx, s.b, args, bNrOrHash, nil, &vm.Config{Debug: true, Tracer: tracer}, 5*time.Second, s.b.RPCGasCap())
+		return result, tracer, err
+	}
+	result, tracer, err := execute(args)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		reads, writes = tracer.Reads(), tracer.Writes()
+		iterations    = 1
+		stable        = true
+	)
+	// Execute the call again with an estimated gas limit if requested
+	if iterate != nil && *iterate && args.Gas == nil && !result.Failed() {
+		gas, err := DoEstimateGas(ctx, s.b, args, bNrOrHash, s.b.RPCGasCap())
+		if err != nil {
+			return nil, err
+		}
+		args.Gas = &gas
+		if result, tracer, err = execute(args); err != nil {
+			return nil, err
+		}
+		stable = tracer.Reads().Equal(reads) && tracer.Writes().Equal(writes)
+		reads, writes, iterations = tracer.Reads(), tracer.Writes(), 2
+	}
+	access := &StateAccessResult{
+		Reads:      newAccessEntries(reads),
+		Writes:     newAccessEntries(writes),
+		GasUsed:    hexutil.Uint64(result.UsedGas),
+		Iterations: hexutil.Uint64(iterations),
+		Stable:     stable,
+	}
+	if result.Failed() {
+		access.Error = result.Err.Error()
+		if len(result.Revert()) > 0 {
+			access.Error = newRevertError(result).Error()
+		}
+	}
+	return access, nil
+}
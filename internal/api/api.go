@@ -19,9 +19,11 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
+	"net"
 	"strings"
 	"time"
 
@@ -35,10 +37,12 @@ import (
 	"github.com/ccm-chain/ccmchain/consensus/clique"
 	"github.com/ccm-chain/ccmchain/consensus/ethash"
 	"github.com/ccm-chain/ccmchain/core"
+	"github.com/ccm-chain/ccmchain/core/state"
 	"github.com/ccm-chain/ccmchain/core/types"
 	"github.com/ccm-chain/ccmchain/core/vm"
 	"github.com/ccm-chain/ccmchain/crypto"
 	"github.com/ccm-chain/ccmchain/log"
+	"github.com/ccm-chain/ccmchain/metrics"
 	"github.com/ccm-chain/ccmchain/p2p"
 	"github.com/ccm-chain/ccmchain/params"
 	"github.com/ccm-chain/ccmchain/rlp"
@@ -47,6 +51,10 @@ import (
 	"github.com/tyler-smith/go-bip39"
 )
 
+// clientTxRateLimitMeter counts the raw transactions rejected because the RPC
+// client submitting them exceeded its admission rate.
+var clientTxRateLimitMeter = metrics.NewRegisteredMeter("txpool/ratelimit/client", nil)
+
 // PublicEthereumAPI provides an API to access Ethereum related information.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicEthereumAPI struct {
@@ -139,6 +147,113 @@ func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	}
 }
 
+const (
+	// defaultTxPoolQueryLimit is the number of transactions returned by a pool
+	// query if no limit is requested.
+	defaultTxPoolQueryLimit = 100
+
+	// maxTxPoolQueryLimit is the maximum number of transactions returned by a
+	// single pool query.
+	maxTxPoolQueryLimit = 1000
+)
+
+// TxPoolQueryArgs represents the filters and pagination arguments of a pool query.
+type TxPoolQueryArgs struct {
+	From        *common.Address `json:"from"`
+	To          *common.Address `json:"to"`
+	MinGasPrice *hexutil.Big    `json:"minGasPrice"`
+	NonceGap    *bool           `json:"nonceGap"`
+	Local       *bool           `json:"local"`
+	Status      *string         `json:"status"` // "pending" or "queued"
+	Cursor      *hexutil.Bytes  `json:"cursor"`
+	Limit       *hexutil.Uint   `json:"limit"`
+}
+
+// TxPoolQueryEntry is a transaction of the pool along with its status.
+type TxPoolQueryEntry struct {
+	Transaction *RPCTransaction `json:"transaction"`
+	Status      string          `json:"status"`
+	Local       bool            `json:"local"`
+	NonceGap    bool            `json:"nonceGap"`
+	Reason      string          `json:"reason,omitempty"`
+}
+
+// TxPoolQueryResult is a page of transactions matching a pool query, along with
+// the cursor to request the next page with, if any.
+type TxPoolQueryResult struct {
+	Transactions []*TxPoolQueryEntry `json:"transactions"`
+	Cursor       *hexutil.Bytes      `json:"cursor"`
+}
+
+// Query retrieves a page of the transactions of the pool matching the given
+// filters, ordered by sender and nonce. Queued transactions are annotated with
+// the reason why they are not executable. The returned cursor can be passed to
+// a subsequent query to retrieve the next page.
+func (s *PublicTxPoolAPI) Query(args TxPoolQueryArgs) (*TxPoolQueryResult, error) {
+	query := core.TxPoolQuery{
+		From:     args.From,
+		To:       args.To,
+		NonceGap: args.NonceGap,
+		Local:    args.Local,
+		Limit:    defaultTxPoolQueryLimit,
+	}
+	if args.MinGasPrice != nil {
+		query.MinGasPrice = args.MinGasPrice.ToInt()
+	}
+	if args.Status != nil {
+		switch *args.Status {
+		case "pending":
+			query.Pending = new(bool)
+			*query.Pending = true
+		case "queued":
+			query.Pending = new(bool)
+		default:
+			return nil, fmt.Errorf("invalid status %q, want \"pending\" or \"queued\"", *args.Status)
+		}
+	}
+	if args.Cursor != nil {
+		if len(*args.Cursor) != common.AddressLength+8 {
+			return nil, errors.New("invalid cursor")
+		}
+		query.After = &core.TxPoolCursor{
+			From:  common.BytesToAddress((*args.Cursor)[:common.AddressLength]),
+			Nonce: binary.BigEndian.Uint64((*args.Cursor)[common.AddressLength:]),
+		}
+	}
+	if args.Limit != nil {
+		if *args.Limit == 0 || *args.Limit > maxTxPoolQueryLimit {
+			return nil, fmt.Errorf("invalid limit %d, want 1-%d", *args.Limit, maxTxPoolQueryLimit)
+		}
+		query.Limit = int(*args.Limit)
+	}
+	entries, cursor, err := s.b.TxPoolQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	result := &TxPoolQueryResult{
+		Transactions: make([]*TxPoolQueryEntry, len(entries)),
+	}
+	for i, entry := range entries {
+		result.Transactions[i] = &TxPoolQueryEntry{
+			Transaction: newRPCPendingTransaction(entry.Tx),
+			Status:      "queued",
+			Local:       entry.Local,
+			NonceGap:    entry.NonceGap,
+			Reason:      entry.Reason,
+		}
+		if entry.Pending {
+			result.Transactions[i].Status = "pending"
+		}
+	}
+	if cursor != nil {
+		enc := make(hexutil.Bytes, common.AddressLength+8)
+		copy(enc, cursor.From[:])
+		binary.BigEndian.PutUint64(enc[common.AddressLength:], cursor.Nonce)
+		result.Cursor = &enc
+	}
+	return result, nil
+}
+
 // Inspect retrieves the content of the transaction pool and flattens it into an
 // easily inspectable list.
 func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
@@ -677,6 +792,9 @@ func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, number rpc.B
 				response[field] = nil
 			}
 		}
+		if err == nil && number >= 0 {
+			rpc.MarkCacheable(ctx, block.NumberU64(), block.Hash())
+		}
 		return response, err
 	}
 	return nil, err
@@ -687,6 +805,7 @@ func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, number rpc.B
 func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fullTx bool) (map[string]interface{}, error) {
 	block, err := s.b.BlockByHash(ctx, hash)
 	if block != nil {
+		rpc.MarkCacheable(ctx, block.NumberU64(), block.Hash())
 		return s.rpcMarshalBlock(ctx, block, true, fullTx)
 	}
 	return nil, err
@@ -827,41 +946,49 @@ type account struct {
 	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
 }
 
-func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides map[common.Address]account, vmCfg vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
-
-	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
-	if state == nil || err != nil {
-		return nil, err
-	}
-	// Override the fields of specified contracts before execution.
+// applyStateOverrides overrides the fields of the specified accounts in the state.
+func applyStateOverrides(statedb *state.StateDB, overrides map[common.Address]account) error {
 	for addr, account := range overrides {
 		// Override account nonce.
 		if account.Nonce != nil {
-			state.SetNonce(addr, uint64(*account.Nonce))
+			statedb.SetNonce(addr, uint64(*account.Nonce))
 		}
 		// Override account(contract) code.
 		if account.Code != nil {
-			state.SetCode(addr, *account.Code)
+			statedb.SetCode(addr, *account.Code)
 		}
 		// Override account balance.
 		if account.Balance != nil {
-			state.SetBalance(addr, (*big.Int)(*account.Balance))
+			statedb.SetBalance(addr, (*big.Int)(*account.Balance))
 		}
 		if account.State != nil && account.StateDiff != nil {
-			return nil, fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
 		}
 		// Replace entire state if caller requires.
 		if account.State != nil {
-			state.SetStorage(addr, *account.State)
+			statedb.SetStorage(addr, *account.State)
 		}
 		// Apply state diff into specified accounts.
 		if account.StateDiff != nil {
 			for key, value := range *account.StateDiff {
-				state.SetState(addr, key, value)
+				statedb.SetState(addr, key, value)
 			}
 		}
 	}
+	return nil
+}
+
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides map[common.Address]account, vmCfg *vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Override the fields of specified contracts before execution.
+	if err := applyStateOverrides(state, overrides); err != nil {
+		return nil, err
+	}
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
 	var cancel context.CancelFunc
@@ -876,7 +1003,7 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 
 	// Get a new instance of the EVM.
 	msg := args.ToMessage(globalGasCap)
-	evm, vmError, err := b.GetEVM(ctx, msg, state, header)
+	evm, vmError, err := b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -945,7 +1072,7 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOr
 	if overrides != nil {
 		accounts = *overrides
 	}
-	result, err := DoCall(ctx, s.b, args, blockNrOrHash, accounts, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
+	result, err := DoCall(ctx, s.b, args, blockNrOrHash, accounts, nil, 5*time.Second, s.b.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
@@ -1031,7 +1158,7 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash
 	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
 		args.Gas = (*hexutil.Uint64)(&gas)
 
-		result, err := DoCall(ctx, b, args, blockNrOrHash, nil, vm.Config{}, 0, gasCap)
+		result, err := DoCall(ctx, b, args, blockNrOrHash, nil, nil, 0, gasCap)
 		if err != nil {
 			if errors.Is(err, core.ErrIntrinsicGas) {
 				return true, nil, nil // Special case, raise gas limit
@@ -1440,8 +1567,41 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if len(receipts) <= int(index) {
 		return nil, nil
 	}
-	receipt := receipts[index]
+	fields := marshalReceipt(receipts[index], blockHash, blockNumber, tx, index)
+	rpc.MarkCacheable(ctx, blockNumber, blockHash)
+	return fields, nil
+}
+
+// GetBlockReceipts returns the receipts of all the transactions in the given
+// block, in the same format as GetTransactionReceipt.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		// Unknown blocks are reported as null, as for the other block queries,
+		// and failures to resolve them as errors
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	fields := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		fields[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), txs[i], uint64(i))
+	}
+	if number, ok := blockNrOrHash.Number(); !ok || number >= 0 {
+		rpc.MarkCacheable(ctx, block.NumberU64(), block.Hash())
+	}
+	return fields, nil
+}
 
+// marshalReceipt converts the receipt of a transaction into the RPC representation,
+// including the fields derived from the transaction and its block.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, tx *types.Transaction, index uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
 		signer = types.NewEIP155Signer(tx.ChainId())
@@ -1451,7 +1611,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1475,7 +1635,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1657,9 +1817,94 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encod
 	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
 		return common.Hash{}, err
 	}
+	if ip := rpcClientIP(ctx); ip != "" && !s.b.RPCTxRateLimiter().Allow(ip, 1) {
+		clientTxRateLimitMeter.Mark(1)
+		return common.Hash{}, core.ErrTxRateLimited
+	}
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// rpcClientIP returns the IP address of the remote client issuing an RPC request,
+// or an empty string if unknown (e.g. over IPC).
+func rpcClientIP(ctx context.Context) string {
+	remote, _ := ctx.Value("remote").(string)
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
+
+// SendPrivateTransaction will add the signed transaction to the private pool of
+// the node. The transaction is never broadcast to the network, it is only included
+// by the local miner. If it is not mined within the configured number of blocks,
+// it is submitted to the public transaction pool instead.
+func (s *PublicTransactionPoolAPI) SendPrivateTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.b.SendPrivateTx(ctx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted private transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To())
+	return tx.Hash(), nil
+}
+
+// TransactionConditions are the preconditions attached to a conditional
+// transaction. Omitted fields impose no restriction.
+type TransactionConditions struct {
+	BlockNumberMin *hexutil.Big                                   `json:"blockNumberMin"`
+	BlockNumberMax *hexutil.Big                                   `json:"blockNumberMax"`
+	TimestampMax   *hexutil.Uint64                                `json:"timestampMax"`
+	Nonces         map[common.Address]hexutil.Uint64              `json:"nonces"`
+	Storage        map[common.Address]map[common.Hash]common.Hash `json:"storage"`
+}
+
+// toConditions converts the RPC preconditions into their transaction pool form.
+func (args *TransactionConditions) toConditions() *core.TxConditions {
+	conditions := &core.TxConditions{
+		BlockNumberMin: (*big.Int)(args.BlockNumberMin),
+		BlockNumberMax: (*big.Int)(args.BlockNumberMax),
+		TimestampMax:   (*uint64)(args.TimestampMax),
+		Storage:        args.Storage,
+	}
+	if len(args.Nonces) > 0 {
+		conditions.Nonces = make(map[common.Address]uint64, len(args.Nonces))
+		for addr, nonce := range args.Nonces {
+			conditions.Nonces[addr] = uint64(nonce)
+		}
+	}
+	return conditions
+}
+
+// SendRawTransactionConditional will add the signed transaction to the
+// conditional pool of the node, along with the preconditions of its inclusion:
+// a block number range, a maximum block timestamp and the expected values of
+// account nonces and storage slots. The transaction is never broadcast to the
+// network, it is only included by the local miner while its conditions hold,
+// and dropped as soon as they cannot be met anymore.
+func (s *PublicTransactionPoolAPI) SendRawTransactionConditional(ctx context.Context, encodedTx hexutil.Bytes, conditions TransactionConditions) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+	if ip := rpcClientIP(ctx); ip != "" && !s.b.RPCTxRateLimiter().Allow(ip, 1) {
+		clientTxRateLimitMeter.Mark(1)
+		return common.Hash{}, core.ErrTxRateLimited
+	}
+	if err := s.b.SendConditionalTx(ctx, tx, conditions.toConditions()); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted conditional transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To())
+	return tx.Hash(), nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -26,7 +26,9 @@ import (
 	"github.com/ccm-chain/ccmchain/consensus"
 	"github.com/ccm-chain/ccmchain/core"
 	"github.com/ccm-chain/ccmchain/core/bloombits"
+	"github.com/ccm-chain/ccmchain/core/rawdb"
 	"github.com/ccm-chain/ccmchain/core/state"
+	"github.com/ccm-chain/ccmchain/core/state/snapshot"
 	"github.com/ccm-chain/ccmchain/core/types"
 	"github.com/ccm-chain/ccmchain/core/vm"
 	"github.com/ccm-chain/ccmchain/database"
@@ -49,6 +51,9 @@ type Backend interface {
 	RPCGasCap() uint64    // global gas cap for eth_call over rpc: DoS protection
 	RPCTxFeeCap() float64 // global tx fee cap for all transaction related APIs
 
+	// RPCTxRateLimiter returns the per client IP admission limiter of raw transactions.
+	RPCTxRateLimiter() *core.TxRateLimiter
+
 	// Blockchain API
 	SetHead(number uint64)
 	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
@@ -61,22 +66,32 @@ type Backend interface {
 	BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error)
 	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
+
+	// Snapshots returns the state snapshot tree, or nil if the node doesn't
+	// maintain snapshots.
+	Snapshots() *snapshot.Tree
+
 	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
 	GetTd(ctx context.Context, hash common.Hash) *big.Int
-	GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error)
+	GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error)
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 	SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription
 
 	// Transaction pool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
+	SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error
+	SendBundle(ctx context.Context, bundle *core.TxBundle) error
+	SendConditionalTx(ctx context.Context, signedTx *types.Transaction, conditions *core.TxConditions) error
 	GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error)
 	GetPoolTransactions() (types.Transactions, error)
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
+	TxPoolQuery(query core.TxPoolQuery) ([]*core.TxPoolEntry, *core.TxPoolCursor, error)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeDroppedTxsEvent(chan<- core.DroppedTxsEvent) event.Subscription
 
 	// Filter API
 	BloomStatus() (uint64, uint64)
@@ -108,6 +123,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
 			Public:    true,
+		}, {
+			Namespace: "ccm",
+			Version:   "1.0",
+			Service:   NewPublicBundleAPI(apiBackend),
+			Public:    true,
 		}, {
 			Namespace: "txpool",
 			Version:   "1.0",
@@ -135,3 +155,32 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		},
 	}
 }
+
+// InvalidateResponseCache keeps an RPC response cache consistent with the chain
+// of the backend. Cached responses are checked against the canonical chain when
+// served, covering rewinds and the delivery delay of reorg events, and the ones
+// derived from blocks reorganized out of the canonical chain are dropped until
+// the returned subscription is unsubscribed. Side chain blocks which were never
+// canonical have no cached responses, so importing them drops nothing.
+func InvalidateResponseCache(b Backend, cache *rpc.ResponseCache) event.Subscription {
+	db := b.ChainDb()
+	cache.SetCanonical(func(number uint64) common.Hash {
+		return rawdb.ReadCanonicalHash(db, number)
+	})
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		sideCh := make(chan core.ChainSideEvent, 16)
+		sub := b.SubscribeChainSideEvent(sideCh)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-sideCh:
+				cache.Invalidate(ev.Block.Hash())
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
+}
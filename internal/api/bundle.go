@@ -0,0 +1,207 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/common/hexutil"
+	"github.com/ccm-chain/ccmchain/core"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/crypto"
+	"github.com/ccm-chain/ccmchain/log"
+	"github.com/ccm-chain/ccmchain/rlp"
+	"github.com/ccm-chain/ccmchain/rpc"
+)
+
+// PublicBundleAPI offers an API to submit and simulate transaction bundles, lists
+// of transactions which are included together and in order, or not at all.
+type PublicBundleAPI struct {
+	b Backend
+}
+
+// NewPublicBundleAPI creates a new transaction bundle API.
+func NewPublicBundleAPI(b Backend) *PublicBundleAPI {
+	return &PublicBundleAPI{b}
+}
+
+// decodeTransactions decodes a list of RLP encoded signed transactions.
+func decodeTransactions(encodedTxs []hexutil.Bytes) (types.Transactions, error) {
+	if len(encodedTxs) == 0 {
+		return nil, errors.New("bundle missing transactions")
+	}
+	txs := make(types.Transactions, len(encodedTxs))
+	for i, encodedTx := range encodedTxs {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		txs[i] = tx
+	}
+	return txs, nil
+}
+
+// bundleHash returns the hash identifying a bundle, the hash of the concatenated
+// hashes of its transactions.
+func bundleHash(txs types.Transactions) common.Hash {
+	hashes := make([]byte, 0, len(txs)*common.HashLength)
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// SendBundle submits a bundle of signed transactions to be included atomically
+// and in order into the given block by the local miner, optionally only if the
+// block's timestamp falls into the given range. The bundle is never broadcast.
+func (s *PublicBundleAPI) SendBundle(ctx context.Context, encodedTxs []hexutil.Bytes, blockNumber rpc.BlockNumber, minTimestamp, maxTimestamp *hexutil.Uint64) (common.Hash, error) {
+	txs, err := decodeTransactions(encodedTxs)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if blockNumber < 0 {
+		return common.Hash{}, errors.New("bundle target must be an explicit block number")
+	}
+	for i, tx := range txs {
+		if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+			return common.Hash{}, fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	bundle := &core.TxBundle{
+		Txs:         txs,
+		BlockNumber: big.NewInt(blockNumber.Int64()),
+	}
+	if minTimestamp != nil {
+		bundle.MinTimestamp = uint64(*minTimestamp)
+	}
+	if maxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*maxTimestamp)
+	}
+	if err := s.b.SendBundle(ctx, bundle); err != nil {
+		return common.Hash{}, err
+	}
+	hash := bundleHash(txs)
+	log.Info("Submitted transaction bundle", "hash", hash, "txs", len(txs), "block", bundle.BlockNumber)
+	return hash, nil
+}
+
+// CallBundle simulates the execution of a bundle of signed transactions in order
+// on top of the state of stateBlockNrOrHash, as if they were included in the given
+// block, and returns the results of the individual transactions. The execution
+// of the bundle continues past failing transactions so that all of them can be
+// inspected.
+func (s *PublicBundleAPI) CallBundle(ctx context.Context, encodedTxs []hexutil.Bytes, blockNumber rpc.BlockNumber, stateBlockNrOrHash rpc.BlockNumberOrHash, timestamp *hexutil.Uint64) (map[string]interface{}, error) {
+	defer func(start time.Time) { log.Debug("Executing bundle call finished", "runtime", time.Since(start)) }(time.Now())
+
+	txs, err := decodeTransactions(encodedTxs)
+	if err != nil {
+		return nil, err
+	}
+	state, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, stateBlockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Assemble the header of the block the bundle is simulated in
+	number := new(big.Int).Add(parent.Number, common.Big1)
+	if blockNumber >= 0 {
+		number = big.NewInt(blockNumber.Int64())
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		Difficulty: parent.Difficulty,
+		Number:     number,
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+	}
+	if timestamp != nil {
+		header.Time = uint64(*timestamp)
+	}
+	// Setup context so it may be cancelled when the call has completed
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var (
+		signer       = types.MakeSigner(s.b.ChainConfig(), header.Number)
+		gp           = new(core.GasPool).AddGas(header.GasLimit)
+		coinbaseFrom = state.GetBalance(header.Coinbase)
+		totalGasUsed uint64
+		results      = make([]map[string]interface{}, 0, len(txs))
+	)
+	for i, tx := range txs {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		state.Prepare(tx.Hash(), common.Hash{}, i)
+
+		evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, nil)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			<-ctx.Done()
+			evm.Cancel()
+		}()
+		result, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", 5*time.Second)
+		}
+		fields := map[string]interface{}{
+			"txHash":      tx.Hash(),
+			"fromAddress": msg.From(),
+			"toAddress":   tx.To(),
+			"gasPrice":    (*hexutil.Big)(tx.GasPrice()),
+		}
+		if err != nil {
+			// Consensus errors invalidate the transaction, no state was touched
+			fields["error"] = err.Error()
+			results = append(results, fields)
+			continue
+		}
+		state.Finalise(s.b.ChainConfig().IsEIP158(header.Number))
+		totalGasUsed += result.UsedGas
+
+		fields["gasUsed"] = hexutil.Uint64(result.UsedGas)
+		if result.Failed() {
+			fields["error"] = result.Err.Error()
+			if len(result.Revert()) > 0 {
+				fields["revert"] = hexutil.Bytes(result.Revert())
+			}
+		} else {
+			fields["value"] = hexutil.Bytes(result.Return())
+		}
+		results = append(results, fields)
+	}
+	coinbaseDiff := new(big.Int).Sub(state.GetBalance(header.Coinbase), coinbaseFrom)
+
+	return map[string]interface{}{
+		"bundleHash":       bundleHash(txs),
+		"results":          results,
+		"totalGasUsed":     hexutil.Uint64(totalGasUsed),
+		"coinbaseDiff":     (*hexutil.Big)(coinbaseDiff),
+		"stateBlockNumber": (*hexutil.Big)(parent.Number),
+	}, nil
+}
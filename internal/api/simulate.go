@@ -0,0 +1,275 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/common/hexutil"
+	"github.com/ccm-chain/ccmchain/common/math"
+	"github.com/ccm-chain/ccmchain/core"
+	"github.com/ccm-chain/ccmchain/core/state"
+	"github.com/ccm-chain/ccmchain/core/types"
+	"github.com/ccm-chain/ccmchain/crypto"
+	"github.com/ccm-chain/ccmchain/rpc"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks simulated by a single
+	// simulation request.
+	maxSimulateBlocks = 256
+
+	// simulateTimeout is the maximum time spent executing a simulation request.
+	simulateTimeout = 5 * time.Second
+)
+
+// BlockOverrides is the set of header fields of a simulated block overriding the
+// defaults derived from its parent.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`     // Defaults to the parent number + 1
+	Time       *hexutil.Uint64 `json:"time"`       // Defaults to the parent time + 1
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`   // Defaults to the parent gas limit
+	Coinbase   *common.Address `json:"coinbase"`   // Defaults to the parent coinbase
+	Difficulty *hexutil.Big    `json:"difficulty"` // Defaults to the parent difficulty
+}
+
+// apply overrides the fields of a simulated header.
+func (o *BlockOverrides) apply(header *types.Header) {
+	if o == nil {
+		return
+	}
+	if o.Number != nil {
+		header.Number = o.Number.ToInt()
+	}
+	if o.Time != nil {
+		header.Time = uint64(*o.Time)
+	}
+	if o.GasLimit != nil {
+		header.GasLimit = uint64(*o.GasLimit)
+	}
+	if o.Coinbase != nil {
+		header.Coinbase = *o.Coinbase
+	}
+	if o.Difficulty != nil {
+		header.Difficulty = o.Difficulty.ToInt()
+	}
+}
+
+// SimulateCall is a call executed in a simulated block. The nonce is only used
+// if the calls are validated, defaulting to the nonce of the sender.
+type SimulateCall struct {
+	CallArgs
+	Nonce *hexutil.Uint64 `json:"nonce"`
+}
+
+// SimulateBlock is a block of calls executed on top of the state left by the
+// calls of the previous blocks, after applying the state overrides.
+type SimulateBlock struct {
+	BlockOverrides *BlockOverrides             `json:"blockOverrides"`
+	StateOverrides *map[common.Address]account `json:"stateOverrides"`
+	Calls          []SimulateCall              `json:"calls"`
+}
+
+// SimulateOpts are the arguments of a simulation.
+//
+// Without validation, calls are executed like ccm_call: from any sender without
+// requiring a signature, ignoring nonces and the gas limit of their block. With
+// validation, calls are checked like transactions: their nonces have to follow
+// the ones of their senders, and their gas has to fit into their block.
+type SimulateOpts struct {
+	Blocks     []SimulateBlock `json:"blocks"`
+	Validation bool            `json:"validation"`
+}
+
+// SimulateCallResult is the outcome of a simulated call. Calls failing during
+// execution, such as reverted ones, don't abort the simulation.
+type SimulateCallResult struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Logs       []*types.Log   `json:"logs"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// SimulateBlockResult is the outcome of a simulated block. Its hash is derived
+// from the simulated header and only identifies the block within a simulation.
+type SimulateBlockResult struct {
+	Number   *hexutil.Big         `json:"number"`
+	Hash     common.Hash          `json:"hash"`
+	Time     hexutil.Uint64       `json:"timestamp"`
+	GasLimit hexutil.Uint64       `json:"gasLimit"`
+	GasUsed  hexutil.Uint64       `json:"gasUsed"`
+	Coinbase common.Address       `json:"miner"`
+	Calls    []SimulateCallResult `json:"calls"`
+}
+
+// Simulate executes sequences of calls across multiple simulated blocks on top of
+// the state of the given block, returning the outcome of each call. The calls
+// are executed cumulatively on a copy of the state, so every call sees the
+// effects of the previous ones. Each block may override the state before its
+// calls, and the fields of its header, such as its number and timestamp.
+//
+// Note, this function doesn't make any changes in the state/blockchain.
+func (s *PublicBlockChainAPI) Simulate(ctx context.Context, opts SimulateOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]*SimulateBlockResult, error) {
+	if len(opts.Blocks) == 0 {
+		return nil, errors.New("empty simulation")
+	}
+	if len(opts.Blocks) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many simulated blocks: %d > %d", len(opts.Blocks), maxSimulateBlocks)
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	statedb, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, bNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, simulateTimeout)
+	defer cancel()
+
+	results := make([]*SimulateBlockResult, 0, len(opts.Blocks))
+	for i, block := range opts.Blocks {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       parent.Time + 1,
+			GasLimit:   parent.GasLimit,
+			Coinbase:   parent.Coinbase,
+			Difficulty: parent.Difficulty,
+			Root:       parent.Root,
+		}
+		block.BlockOverrides.apply(header)
+		if header.Number.Cmp(parent.Number) <= 0 {
+			return nil, fmt.Errorf("block %d: number %v not above parent %v", i, header.Number, parent.Number)
+		}
+		if header.Time <= parent.Time {
+			return nil, fmt.Errorf("block %d: timestamp %d not above parent %d", i, header.Time, parent.Time)
+		}
+		if block.StateOverrides != nil {
+			if err := applyStateOverrides(statedb, *block.StateOverrides); err != nil {
+				return nil, fmt.Errorf("block %d: %v", i, err)
+			}
+		}
+		result, err := s.simulateBlock(ctx, statedb, header, block.Calls, opts.Validation)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		results = append(results, result)
+		parent = header
+	}
+	return results, nil
+}
+
+// simulateBlock executes the calls of a simulated block on the state.
+func (s *PublicBlockChainAPI) simulateBlock(ctx context.Context, statedb *state.StateDB, header *types.Header, calls []SimulateCall, validation bool) (*SimulateBlockResult, error) {
+	var (
+		hash     = header.Hash()
+		gasLimit = uint64(math.MaxUint64)
+		gasUsed  uint64
+		logIndex uint
+	)
+	if validation {
+		gasLimit = header.GasLimit
+	}
+	gp := new(core.GasPool).AddGas(gasLimit)
+
+	results := make([]SimulateCallResult, len(calls))
+	for i, call := range calls {
+		msg := call.ToMessage(s.b.RPCGasCap())
+		if validation {
+			nonce := statedb.GetNonce(msg.From())
+			if call.Nonce != nil {
+				nonce = uint64(*call.Nonce)
+			}
+			msg = types.NewMessage(msg.From(), msg.To(), nonce, msg.Value(), msg.Gas(), msg.GasPrice(), msg.Data(), true)
+		}
+		// Identify the call by a synthetic transaction hash to collect its logs
+		var id [16]byte
+		binary.BigEndian.PutUint64(id[:8], header.Number.Uint64())
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+		txHash := crypto.Keccak256Hash(id[:])
+		statedb.Prepare(txHash, hash, i)
+
+		evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, nil)
+		if err != nil {
+			return nil, err
+		}
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		result, err := core.ApplyMessage(evm, msg, gp)
+		close(done)
+
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", simulateTimeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		statedb.Finalise(s.b.ChainConfig().IsEIP158(header.Number))
+		gasUsed += result.UsedGas
+
+		logs := statedb.GetLogs(txHash)
+		for _, log := range logs {
+			log.BlockNumber = header.Number.Uint64()
+			log.Index = logIndex
+			logIndex++
+		}
+		if logs == nil {
+			logs = []*types.Log{}
+		}
+		results[i] = SimulateCallResult{
+			ReturnData: result.Return(),
+			Logs:       logs,
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			Status:     hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if result.Failed() {
+			results[i].Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if len(result.Revert()) > 0 {
+				results[i].ReturnData = result.Revert()
+				results[i].Error = newRevertError(result).Error()
+			} else {
+				results[i].Error = result.Err.Error()
+			}
+		}
+	}
+	return &SimulateBlockResult{
+		Number:   (*hexutil.Big)(header.Number),
+		Hash:     hash,
+		Time:     hexutil.Uint64(header.Time),
+		GasLimit: hexutil.Uint64(header.GasLimit),
+		GasUsed:  hexutil.Uint64(gasUsed),
+		Coinbase: header.Coinbase,
+		Calls:    results,
+	}, nil
+}
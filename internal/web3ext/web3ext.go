@@ -108,6 +108,12 @@ web3._extend({
 			call: 'clique_status',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'getDoubleSignEvidence',
+			call: 'clique_getDoubleSignEvidence',
+			params: 1,
+			inputFormatter: [null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -515,6 +521,28 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'sendPrivateTransaction',
+			call: 'ccm_sendPrivateTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sendRawTransactionConditional',
+			call: 'ccm_sendRawTransactionConditional',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'sendBundle',
+			call: 'ccm_sendBundle',
+			params: 4,
+			inputFormatter: [null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'callBundle',
+			call: 'ccm_callBundle',
+			params: 4,
+			inputFormatter: [null, null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'fillTransaction',
 			call: 'ccm_fillTransaction',
@@ -556,6 +584,24 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, function (val) { return !!val; }]
 		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: 'ccm_getBlockReceipts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getStateAccess',
+			call: 'ccm_getStateAccess',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'simulate',
+			call: 'ccm_simulate',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransaction',
 			call: 'ccm_getRawTransactionByHash',
@@ -638,6 +684,10 @@ web3._extend({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
 		}),
+		new web3._extend.Method({
+			name: 'simulateNextBlock',
+			call: 'miner_simulateNextBlock'
+		}),
 	],
 	properties: []
 });
@@ -772,7 +822,13 @@ web3._extend({
 const TxpoolJs = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'query',
+			call: 'txpool_query',
+			params: 1
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
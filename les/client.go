@@ -71,6 +71,9 @@ type LightEthereum struct {
 	accountManager *accounts.Manager
 	netRPCService  *api.PublicNetAPI
 
+	rpcCache    *rpc.ResponseCache // Cache of the RPC responses of the node, nil if disabled
+	rpcCacheSub event.Subscription // Subscription invalidating the RPC response cache on reorgs
+
 	p2pServer *p2p.Server
 }
 
@@ -104,7 +107,7 @@ func New(stack *node.Node, config *protocol.Config) (*LightEthereum, error) {
 		eventMux:       stack.EventMux(),
 		reqDist:        newRequestDistributor(peers, &mclock.System{}),
 		accountManager: stack.AccountManager(),
-		engine:         protocol.CreateConsensusEngine(stack, chainConfig, &config.Ethash, nil, false, chainDb),
+		engine:         protocol.CreateConsensusEngine(stack, chainConfig, config, nil, false, chainDb),
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   protocol.NewBloomIndexer(chainDb, params.BloomBitsBlocksClient, params.HelperTrieConfirmations),
 		valueTracker:   lpc.NewValueTracker(lespayDb, &mclock.System{}, requestList, time.Minute, 1/float64(time.Hour), 1/float64(time.Hour*100), 1/float64(time.Hour*1000)),
@@ -158,12 +161,13 @@ func New(stack *node.Node, config *protocol.Config) (*LightEthereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 
-	leth.ApiBackend = &LesApiBackend{stack.Config().ExtRPCEnabled(), leth, nil}
+	leth.ApiBackend = &LesApiBackend{stack.Config().ExtRPCEnabled(), leth, nil, core.NewTxRateLimiter(config.TxPool.ClientRateLimit)}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
 	}
 	leth.ApiBackend.gpo = gasprice.NewOracle(leth.ApiBackend, gpoParams)
+	leth.rpcCache = stack.RPCResponseCache()
 
 	leth.handler = newClientHandler(config.UltraLightServers, config.UltraLightFraction, checkpoint, leth)
 	if leth.handler.ulc != nil {
@@ -287,6 +291,10 @@ func (s *LightEthereum) Start() error {
 	s.startBloomHandlers(params.BloomBitsBlocksClient)
 	s.handler.start()
 
+	// Keep the RPC response cache consistent with the chain
+	if s.rpcCache != nil {
+		s.rpcCacheSub = api.InvalidateResponseCache(s.ApiBackend, s.rpcCache)
+	}
 	return nil
 }
 
@@ -294,6 +302,9 @@ func (s *LightEthereum) Start() error {
 // Ethereum protocol.
 func (s *LightEthereum) Stop() error {
 	close(s.closeCh)
+	if s.rpcCacheSub != nil {
+		s.rpcCacheSub.Unsubscribe()
+	}
 	s.serverPool.stop()
 	s.valueTracker.Stop()
 	s.peers.close()
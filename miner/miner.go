@@ -50,6 +50,10 @@ type Config struct {
 	GasPrice  *big.Int       // Minimum gas price for mining a transaction
 	Recommit  time.Duration  // The time interval for miner to re-create mining work.
 	Noverify  bool           // Disable remote mining solution verification(only useful in ethash).
+
+	Ordering          string           `toml:",omitempty"` // Transaction ordering policy (price or fifo, default = price)
+	SenderCap         int              `toml:",omitempty"` // Maximum number of transactions per sender in a block (0 = unlimited)
+	PriorityAddresses []common.Address `toml:",omitempty"` // Recipients whose transactions are included ahead of all others
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -179,11 +183,23 @@ func (miner *Miner) SetRecommitInterval(interval time.Duration) {
 	miner.worker.setRecommitInterval(interval)
 }
 
+// SetOrderingPolicy sets the policy ordering the transactions of the blocks
+// built from now on.
+func (miner *Miner) SetOrderingPolicy(policy OrderingPolicy) {
+	miner.worker.setOrderingPolicy(policy)
+}
+
 // Pending returns the currently pending block and associated state.
 func (miner *Miner) Pending() (*types.Block, *state.StateDB) {
 	return miner.worker.pending()
 }
 
+// Candidate returns the currently pending block along with the receipts of its
+// transactions and the transactions the miner considered but left out of it.
+func (miner *Miner) Candidate() (*types.Block, types.Receipts, []*SkippedTx) {
+	return miner.worker.candidate()
+}
+
 // PendingBlock returns the currently pending block.
 //
 // Note, to access both the pending block and the pending state
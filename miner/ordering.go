@@ -0,0 +1,220 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"fmt"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/core/types"
+)
+
+// TransactionSet is a set of transactions offered to the block being built one
+// at a time, honouring the nonce ordering of every account.
+type TransactionSet interface {
+	// Peek returns the next transaction to include, or nil if the set is drained.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same account.
+	Shift()
+
+	// Pop removes the current transaction, along with all the subsequent ones from
+	// the same account.
+	Pop()
+}
+
+// OrderingPolicy decides the order in which the executable transactions of the
+// pool are offered to the block being built.
+type OrderingPolicy interface {
+	// Order creates a transaction set out of the nonce sorted transactions of
+	// each account. The input map is reowned by the policy.
+	Order(signer types.Signer, txs map[common.Address]types.Transactions) TransactionSet
+
+	// SenderCap returns the maximum number of transactions of any single account
+	// included in a block, or 0 if unlimited. Policies wrapping others have to
+	// honour the cap of the wrapped ones.
+	SenderCap() int
+}
+
+// PriceNonceOrdering is the default ordering policy, offering the transactions
+// with the highest gas price first.
+type PriceNonceOrdering struct{}
+
+// Order implements OrderingPolicy.
+func (PriceNonceOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions) TransactionSet {
+	return types.NewTransactionsByPriceAndNonce(signer, txs)
+}
+
+// SenderCap implements OrderingPolicy.
+func (PriceNonceOrdering) SenderCap() int { return 0 }
+
+// FIFOOrdering offers the transactions strictly in the order they arrived at
+// the node, regardless of their gas price.
+type FIFOOrdering struct{}
+
+// Order implements OrderingPolicy.
+func (FIFOOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions) TransactionSet {
+	return types.NewTransactionsByTimeAndNonce(signer, txs)
+}
+
+// SenderCap implements OrderingPolicy.
+func (FIFOOrdering) SenderCap() int { return 0 }
+
+// SenderCapOrdering limits the number of transactions from any single account
+// offered to a block, ordering the remaining ones with a wrapped policy.
+//
+// As a block is filled from several transaction sets, the worker enforces the
+// cap across all of them too, counting the transactions already included.
+type SenderCapOrdering struct {
+	Policy OrderingPolicy // Policy ordering the capped transactions
+	Cap    int            // Maximum number of transactions per account
+}
+
+// Order implements OrderingPolicy.
+func (o SenderCapOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions) TransactionSet {
+	for addr, list := range txs {
+		if len(list) > o.Cap {
+			txs[addr] = list[:o.Cap]
+		}
+	}
+	return o.Policy.Order(signer, txs)
+}
+
+// SenderCap implements OrderingPolicy, returning the tighter of its own cap and
+// the one of the wrapped policy.
+func (o SenderCapOrdering) SenderCap() int {
+	if inner := o.Policy.SenderCap(); inner > 0 && inner < o.Cap {
+		return inner
+	}
+	return o.Cap
+}
+
+// PriorityOrdering offers the transactions calling any of a set of whitelisted
+// addresses ahead of all others, ordering both lanes with a wrapped policy.
+//
+// As nonces need to be honoured, transactions of an account preceding one of its
+// priority transactions are moved into the priority lane too.
+type PriorityOrdering struct {
+	Policy    OrderingPolicy              // Policy ordering the transactions within each lane
+	Addresses map[common.Address]struct{} // Recipients whose transactions are prioritised
+}
+
+// NewPriorityOrdering creates a priority lane policy for the given recipients.
+func NewPriorityOrdering(policy OrderingPolicy, addresses []common.Address) *PriorityOrdering {
+	o := &PriorityOrdering{
+		Policy:    policy,
+		Addresses: make(map[common.Address]struct{}, len(addresses)),
+	}
+	for _, addr := range addresses {
+		o.Addresses[addr] = struct{}{}
+	}
+	return o
+}
+
+// Order implements OrderingPolicy.
+func (o *PriorityOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions) TransactionSet {
+	priority := make(map[common.Address]types.Transactions)
+	for addr, list := range txs {
+		// Find the last prioritised transaction of the account
+		last := -1
+		for i, tx := range list {
+			if to := tx.To(); to != nil {
+				if _, ok := o.Addresses[*to]; ok {
+					last = i
+				}
+			}
+		}
+		if last < 0 {
+			continue
+		}
+		priority[addr] = list[:last+1]
+		if last+1 < len(list) {
+			txs[addr] = list[last+1:]
+		} else {
+			delete(txs, addr)
+		}
+	}
+	var lanes laneSet
+	if len(priority) > 0 {
+		lanes = append(lanes, o.Policy.Order(signer, priority))
+	}
+	if len(txs) > 0 {
+		lanes = append(lanes, o.Policy.Order(signer, txs))
+	}
+	return &lanes
+}
+
+// SenderCap implements OrderingPolicy.
+func (o *PriorityOrdering) SenderCap() int {
+	return o.Policy.SenderCap()
+}
+
+// laneSet is a list of transaction sets drained one after the other.
+type laneSet []TransactionSet
+
+// Peek implements TransactionSet, returning the next transaction of the first
+// lane that still has any.
+func (l *laneSet) Peek() *types.Transaction {
+	for len(*l) > 0 {
+		if tx := (*l)[0].Peek(); tx != nil {
+			return tx
+		}
+		*l = (*l)[1:]
+	}
+	return nil
+}
+
+// Shift implements TransactionSet.
+func (l *laneSet) Shift() {
+	if l.Peek() != nil {
+		(*l)[0].Shift()
+	}
+}
+
+// Pop implements TransactionSet.
+func (l *laneSet) Pop() {
+	if l.Peek() != nil {
+		(*l)[0].Pop()
+	}
+}
+
+// Names of the built-in ordering policies selectable via the configuration.
+const (
+	OrderingPriceNonce = "price"
+	OrderingFIFO       = "fifo"
+)
+
+// makeOrderingPolicy creates the transaction ordering policy requested by the
+// miner configuration.
+func makeOrderingPolicy(config *Config) (OrderingPolicy, error) {
+	var policy OrderingPolicy
+	switch config.Ordering {
+	case "", OrderingPriceNonce:
+		policy = PriceNonceOrdering{}
+	case OrderingFIFO:
+		policy = FIFOOrdering{}
+	default:
+		return nil, fmt.Errorf("unknown transaction ordering policy %q", config.Ordering)
+	}
+	if len(config.PriorityAddresses) > 0 {
+		policy = NewPriorityOrdering(policy, config.PriorityAddresses)
+	}
+	if config.SenderCap > 0 {
+		policy = SenderCapOrdering{Policy: policy, Cap: config.SenderCap}
+	}
+	return policy, nil
+}
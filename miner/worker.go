@@ -75,6 +75,15 @@ const (
 	staleThreshold = 7
 )
 
+var (
+	// errBundleReverted is returned if a transaction of a bundle reverts.
+	errBundleReverted = errors.New("bundle transaction reverted")
+
+	// errBundleUnprotected is returned if a bundle contains a replay protected
+	// transaction before the EIP155 fork.
+	errBundleUnprotected = errors.New("bundle transaction replay protected before eip155")
+)
+
 // environment is the worker's current environment and holds all of the current state information.
 type environment struct {
 	signer types.Signer
@@ -89,6 +98,28 @@ type environment struct {
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+	skipped  []*SkippedTx // transactions considered but left out of the block
+
+	senders   map[common.Address]int // number of transactions included per sender
+	senderCap int                    // maximum number of transactions per sender (0 = unlimited)
+}
+
+// Reasons for which the worker leaves transactions out of the block it builds.
+const (
+	SkipNonceTooLow     = "nonce too low"
+	SkipNonceGap        = "nonce gap"
+	SkipInsufficientGas = "insufficient block gas"
+	SkipReplayProtected = "replay protected"
+	SkipSenderCap       = "sender cap reached"
+	SkipBundleDiscarded = "bundle discarded"
+)
+
+// SkippedTx is a transaction the worker considered for inclusion into a block,
+// but left out of it.
+type SkippedTx struct {
+	Tx     *types.Transaction
+	From   common.Address
+	Reason string
 }
 
 // task contains all information for consensus engine sealing and result submitting.
@@ -153,16 +184,19 @@ type worker struct {
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, extra and ordering fields
 	coinbase common.Address
 	extra    []byte
+	ordering OrderingPolicy
 
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
 
-	snapshotMu    sync.RWMutex // The lock used to protect the block snapshot and state snapshot
-	snapshotBlock *types.Block
-	snapshotState *state.StateDB
+	snapshotMu       sync.RWMutex // The lock used to protect the block snapshot and state snapshot
+	snapshotBlock    *types.Block
+	snapshotState    *state.StateDB
+	snapshotReceipts types.Receipts
+	snapshotSkipped  []*SkippedTx
 
 	// atomic status counters
 	running int32 // The indicator whether the consensus engine is running or not.
@@ -209,6 +243,13 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		resubmitIntervalCh: make(chan time.Duration),
 		resubmitAdjustCh:   make(chan *intervalAdjust, resubmitAdjustChanSize),
 	}
+	ordering, err := makeOrderingPolicy(config)
+	if err != nil {
+		log.Error("Invalid transaction ordering policy, using default", "err", err)
+		ordering = PriceNonceOrdering{}
+	}
+	worker.ordering = ordering
+
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = eth.TxPool().SubscribeNewTxsEvent(worker.txsCh)
 	// Subscribe events for blockchain
@@ -248,6 +289,19 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// setOrderingPolicy sets the policy ordering the transactions of new blocks.
+func (w *worker) setOrderingPolicy(policy OrderingPolicy) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ordering = policy
+}
+
+// orderTransactions creates a transaction set ordered by the current policy.
+// The caller must hold the worker's lock.
+func (w *worker) orderTransactions(txs map[common.Address]types.Transactions) TransactionSet {
+	return w.ordering.Order(w.current.signer, txs)
+}
+
 // setRecommitInterval updates the interval for miner sealing work recommitting.
 func (w *worker) setRecommitInterval(interval time.Duration) {
 	w.resubmitIntervalCh <- interval
@@ -274,6 +328,14 @@ func (w *worker) pending() (*types.Block, *state.StateDB) {
 	return w.snapshotBlock, w.snapshotState.Copy()
 }
 
+// candidate returns the pending block along with the receipts of its transactions
+// and the transactions left out of it.
+func (w *worker) candidate() (*types.Block, types.Receipts, []*SkippedTx) {
+	w.snapshotMu.RLock()
+	defer w.snapshotMu.RUnlock()
+	return w.snapshotBlock, w.snapshotReceipts, w.snapshotSkipped
+}
+
 // pendingBlock returns pending block.
 func (w *worker) pendingBlock() *types.Block {
 	// return a snapshot to avoid contention on currentMu mutex
@@ -482,7 +544,7 @@ func (w *worker) mainLoop() {
 					continue
 				}
 				w.mu.RLock()
-				coinbase := w.coinbase
+				coinbase, ordering := w.coinbase, w.ordering
 				w.mu.RUnlock()
 
 				txs := make(map[common.Address]types.Transactions)
@@ -490,7 +552,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				txset := ordering.Order(w.current.signer, txs)
 				tcount := w.current.tcount
 				w.commitTransactions(txset, coinbase, nil)
 				// Only update the snapshot if any new transactons were added
@@ -647,6 +709,8 @@ func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 		family:    mapset.NewSet(),
 		uncles:    mapset.NewSet(),
 		header:    header,
+		senders:   make(map[common.Address]int),
+		senderCap: w.ordering.SenderCap(),
 	}
 
 	// when 08 is processed ancestors contain 07 (quick block)
@@ -715,6 +779,13 @@ func (w *worker) updateSnapshot() {
 	)
 
 	w.snapshotState = w.current.state.Copy()
+	w.snapshotReceipts = copyReceipts(w.current.receipts)
+	w.snapshotSkipped = append([]*SkippedTx(nil), w.current.skipped...)
+}
+
+// skip records a transaction left out of the block being built.
+func (w *worker) skip(tx *types.Transaction, from common.Address, reason string) {
+	w.current.skipped = append(w.current.skipped, &SkippedTx{Tx: tx, From: from, Reason: reason})
 }
 
 func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Address) ([]*types.Log, error) {
@@ -728,10 +799,13 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	w.current.txs = append(w.current.txs, tx)
 	w.current.receipts = append(w.current.receipts, receipt)
 
+	from, _ := types.Sender(w.current.signer, tx)
+	w.current.senders[from]++
+
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TransactionSet, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -764,9 +838,15 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			}
 			return atomic.LoadInt32(interrupt) == commitInterruptNewHead
 		}
-		// If we don't have enough gas for any further transactions then we're done
+		// If we don't have enough gas for any further transactions then we're done,
+		// the remaining ones don't fit into the block anymore
 		if w.current.gasPool.Gas() < params.TxGas {
 			log.Trace("Not enough gas for further transactions", "have", w.current.gasPool, "want", params.TxGas)
+			for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+				from, _ := types.Sender(w.current.signer, tx)
+				w.skip(tx, from, SkipInsufficientGas)
+				txs.Pop()
+			}
 			break
 		}
 		// Retrieve the next transaction and abort if all done
@@ -784,6 +864,14 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		if tx.Protected() && !w.chainConfig.IsEIP155(w.current.header.Number) {
 			log.Trace("Ignoring reply protected transaction", "hash", tx.Hash(), "eip155", w.chainConfig.EIP155Block)
 
+			w.skip(tx, from, SkipReplayProtected)
+			txs.Pop()
+			continue
+		}
+		// The sender cap spans the whole block, not just the current transaction set
+		if w.current.senderCap > 0 && w.current.senders[from] >= w.current.senderCap {
+			log.Trace("Skipping account at sender cap", "sender", from, "cap", w.current.senderCap)
+			w.skip(tx, from, SkipSenderCap)
 			txs.Pop()
 			continue
 		}
@@ -795,16 +883,19 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		case core.ErrGasLimitReached:
 			// Pop the current out-of-gas transaction without shifting in the next from the account
 			log.Trace("Gas limit exceeded for current block", "sender", from)
+			w.skip(tx, from, SkipInsufficientGas)
 			txs.Pop()
 
 		case core.ErrNonceTooLow:
 			// New head notification data race between the transaction pool and miner, shift
 			log.Trace("Skipping transaction with low nonce", "sender", from, "nonce", tx.Nonce())
+			w.skip(tx, from, SkipNonceTooLow)
 			txs.Shift()
 
 		case core.ErrNonceTooHigh:
 			// Reorg notification data race between the transaction pool and miner, skip account =
 			log.Trace("Skipping account with hight nonce", "sender", from, "nonce", tx.Nonce())
+			w.skip(tx, from, SkipNonceGap)
 			txs.Pop()
 
 		case nil:
@@ -817,11 +908,161 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			// Strange error, discard the transaction and get the next in line (note, the
 			// nonce-too-high clause will prevent us from executing in vain).
 			log.Debug("Transaction failed, account skipped", "hash", tx.Hash(), "err", err)
+			w.skip(tx, from, err.Error())
 			txs.Shift()
 		}
 	}
 
-	if !w.isRunning() && len(coalescedLogs) > 0 {
+	w.pushPendingLogs(coalescedLogs)
+
+	// Notify resubmit loop to decrease resubmitting interval if current interval is larger
+	// than the user-specified one.
+	if interrupt != nil {
+		w.resubmitAdjustCh <- &intervalAdjust{inc: false}
+	}
+	return false
+}
+
+// commitBundles applies the given transaction bundles on top of the pending state
+// one after the other. A bundle is only kept if all its transactions execute
+// successfully, otherwise it is discarded as a whole and all its transactions are
+// recorded as skipped.
+func (w *worker) commitBundles(bundles []*core.TxBundle, coinbase common.Address, interrupt *int32) bool {
+	// Short circuit if current is nil
+	if w.current == nil {
+		return true
+	}
+	if w.current.gasPool == nil {
+		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+	}
+	var coalescedLogs []*types.Log
+
+	for _, bundle := range bundles {
+		if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
+			return atomic.LoadInt32(interrupt) == commitInterruptNewHead
+		}
+		logs, err := w.commitBundle(bundle, coinbase)
+		if err != nil {
+			log.Debug("Bundle discarded", "txs", len(bundle.Txs), "first", bundle.Txs[0].Hash(), "err", err)
+			for _, tx := range bundle.Txs {
+				from, _ := types.Sender(w.current.signer, tx)
+				w.skip(tx, from, SkipBundleDiscarded)
+			}
+			continue
+		}
+		coalescedLogs = append(coalescedLogs, logs...)
+	}
+	w.pushPendingLogs(coalescedLogs)
+	return false
+}
+
+// commitBundle applies all the transactions of a bundle in order, reverting the
+// pending state to before the bundle if any of them fails or reverts.
+func (w *worker) commitBundle(bundle *core.TxBundle, coinbase common.Address) ([]*types.Log, error) {
+	// Transactions finalise the state, so journal snapshots cannot span the bundle
+	var (
+		state   = w.current.state.Copy()
+		gas     = w.current.gasPool.Gas()
+		gasUsed = w.current.header.GasUsed
+		txs     = len(w.current.txs)
+		tcount  = w.current.tcount
+	)
+	revert := func() {
+		// Uncount the senders of the included transactions from the sender cap
+		for _, tx := range w.current.txs[txs:] {
+			from, _ := types.Sender(w.current.signer, tx)
+			if w.current.senders[from]--; w.current.senders[from] == 0 {
+				delete(w.current.senders, from)
+			}
+		}
+		w.current.state = state
+		w.current.gasPool = new(core.GasPool).AddGas(gas)
+		w.current.header.GasUsed = gasUsed
+		w.current.txs = w.current.txs[:txs]
+		w.current.receipts = w.current.receipts[:txs]
+		w.current.tcount = tcount
+	}
+	var coalescedLogs []*types.Log
+	for _, tx := range bundle.Txs {
+		if tx.Protected() && !w.chainConfig.IsEIP155(w.current.header.Number) {
+			revert()
+			return nil, errBundleUnprotected
+		}
+		w.current.state.Prepare(tx.Hash(), common.Hash{}, w.current.tcount)
+
+		logs, err := w.commitTransaction(tx, coinbase)
+		if err != nil {
+			revert()
+			return nil, err
+		}
+		if w.current.receipts[len(w.current.receipts)-1].Status == types.ReceiptStatusFailed {
+			revert()
+			return nil, errBundleReverted
+		}
+		coalescedLogs = append(coalescedLogs, logs...)
+		w.current.tcount++
+	}
+	return coalescedLogs, nil
+}
+
+// commitConditional applies the conditional transactions of each account in nonce
+// order, checking their preconditions against the pending block and state right
+// before executing them. The remaining transactions of an account are skipped if
+// one of them cannot be included.
+func (w *worker) commitConditional(txs map[common.Address][]*core.ConditionalTx, coinbase common.Address, interrupt *int32) bool {
+	// Short circuit if current is nil
+	if w.current == nil {
+		return true
+	}
+	if w.current.gasPool == nil {
+		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+	}
+	var coalescedLogs []*types.Log
+
+	for from, list := range txs {
+		for _, entry := range list {
+			if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
+				return atomic.LoadInt32(interrupt) == commitInterruptNewHead
+			}
+			tx := entry.Tx
+			if w.current.gasPool.Gas() < params.TxGas {
+				w.skip(tx, from, SkipInsufficientGas)
+				break
+			}
+			if tx.Protected() && !w.chainConfig.IsEIP155(w.current.header.Number) {
+				w.skip(tx, from, SkipReplayProtected)
+				break
+			}
+			if w.current.senderCap > 0 && w.current.senders[from] >= w.current.senderCap {
+				log.Trace("Skipping account at sender cap", "sender", from, "cap", w.current.senderCap)
+				w.skip(tx, from, SkipSenderCap)
+				break
+			}
+			if err := entry.Conditions.Check(w.current.header.Number, w.current.header.Time, w.current.state); err != nil {
+				log.Trace("Skipping conditional transaction", "hash", tx.Hash(), "sender", from, "err", err)
+				w.skip(tx, from, err.Error())
+				break
+			}
+			w.current.state.Prepare(tx.Hash(), common.Hash{}, w.current.tcount)
+
+			logs, err := w.commitTransaction(tx, coinbase)
+			if err != nil {
+				log.Trace("Conditional transaction failed, account skipped", "hash", tx.Hash(), "err", err)
+				w.skip(tx, from, err.Error())
+				break
+			}
+			coalescedLogs = append(coalescedLogs, logs...)
+			w.current.tcount++
+		}
+	}
+	w.pushPendingLogs(coalescedLogs)
+	return false
+}
+
+// pushPendingLogs announces the logs of transactions newly added to the pending
+// block, unless the block is being mined.
+func (w *worker) pushPendingLogs(logs []*types.Log) {
+	if !w.isRunning() && len(logs) > 0 {
 		// We don't push the pendingLogsEvent while we are mining. The reason is that
 		// when we are mining, the worker will regenerate a mining block every 3 seconds.
 		// In order to avoid pushing the repeated pendingLog, we disable the pending log pushing.
@@ -829,19 +1070,13 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
 		// logs by filling in the block hash when the block was mined by the local miner. This can
 		// cause a race condition if a log was "upgraded" before the PendingLogsEvent is processed.
-		cpy := make([]*types.Log, len(coalescedLogs))
-		for i, l := range coalescedLogs {
+		cpy := make([]*types.Log, len(logs))
+		for i, l := range logs {
 			cpy[i] = new(types.Log)
 			*cpy[i] = *l
 		}
 		w.pendingLogsFeed.Send(cpy)
 	}
-	// Notify resubmit loop to decrease resubmitting interval if current interval is larger
-	// than the user-specified one.
-	if interrupt != nil {
-		w.resubmitAdjustCh <- &intervalAdjust{inc: false}
-	}
-	return false
 }
 
 // commitNewWork generates several new sealing tasks based on the parent block.
@@ -927,13 +1162,36 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		log.Error("Failed to fetch pending transactions", "err", err)
 		return
 	}
+	private := w.eth.TxPool().Private()
+	bundles := w.eth.TxPool().Bundles(header.Number, header.Time)
+	conditional := w.eth.TxPool().Conditional()
+
 	// Short circuit if there is no available pending transactions.
 	// But if we disable empty precommit already, ignore it. Since
 	// empty block is necessary to keep the liveness of the network.
-	if len(pending) == 0 && atomic.LoadUint32(&w.noempty) == 0 {
+	if len(pending) == 0 && len(private) == 0 && len(bundles) == 0 && len(conditional) == 0 && atomic.LoadUint32(&w.noempty) == 0 {
 		w.updateSnapshot()
 		return
 	}
+	// Include the bundles at the top of the block, each one atomically
+	if len(bundles) > 0 {
+		if w.commitBundles(bundles, w.coinbase, interrupt) {
+			return
+		}
+	}
+	// Include the private transactions first, they are only ever mined by us
+	if len(private) > 0 {
+		txs := w.orderTransactions(private)
+		if w.commitTransactions(txs, w.coinbase, interrupt) {
+			return
+		}
+	}
+	// Include the conditional transactions whose preconditions hold
+	if len(conditional) > 0 {
+		if w.commitConditional(conditional, w.coinbase, interrupt) {
+			return
+		}
+	}
 	// Split the pending transactions into locals and remotes
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
 	for _, account := range w.eth.TxPool().Locals() {
@@ -943,13 +1201,13 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
+		txs := w.orderTransactions(localTxs)
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
+		txs := w.orderTransactions(remoteTxs)
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
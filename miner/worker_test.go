@@ -17,8 +17,12 @@
 package miner
 
 import (
+	"crypto/ecdsa"
+	"fmt"
 	"math/big"
 	"math/rand"
+	"reflect"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -183,6 +187,38 @@ func newTestWorker(t *testing.T, chainConfig *params.ChainConfig, engine consens
 	return w, backend
 }
 
+// signTestTx signs a transaction with the key of the test bank.
+func signTestTx(tx *types.Transaction) *types.Transaction {
+	tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testBankKey)
+	return tx
+}
+
+// waitTestTask starts the worker without sealing and waits for the first task of
+// block 1 including any transactions.
+func waitTestTask(t *testing.T, w *worker) *task {
+	t.Helper()
+
+	taskCh := make(chan *task, 2)
+	w.newTaskHook = func(task *task) {
+		if task.block.NumberU64() == 1 && len(task.receipts) > 0 {
+			select {
+			case taskCh <- task:
+			default:
+			}
+		}
+	}
+	w.skipSealHook = func(task *task) bool { return true }
+	w.start()
+
+	select {
+	case task := <-taskCh:
+		return task
+	case <-time.NewTimer(3 * time.Second).C:
+		t.Fatal("new task timeout")
+	}
+	return nil
+}
+
 func TestGenerateBlockAndImportEthash(t *testing.T) {
 	testGenerateBlockAndImport(t, false)
 }
@@ -297,6 +333,178 @@ func testEmptyWork(t *testing.T, chainConfig *params.ChainConfig, engine consens
 	}
 }
 
+func TestCommitBundles(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+	)
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, db, 0)
+	defer w.close()
+
+	// The first bundle reverts in its last transaction, the second one is valid
+	bundles := []*core.TxBundle{
+		{
+			Txs: types.Transactions{
+				signTestTx(types.NewTransaction(0, testUserAddress, big.NewInt(5000), params.TxGas, nil, nil)),
+				signTestTx(types.NewContractCreation(1, big.NewInt(0), testGas, nil, common.FromHex("60006000fd"))),
+			},
+			BlockNumber: big.NewInt(1),
+		},
+		{
+			Txs: types.Transactions{
+				signTestTx(types.NewTransaction(0, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil)),
+				signTestTx(types.NewTransaction(1, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil)),
+			},
+			BlockNumber: big.NewInt(1),
+		},
+		{
+			Txs:          types.Transactions{signTestTx(types.NewTransaction(2, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil))},
+			BlockNumber:  big.NewInt(1),
+			MaxTimestamp: 1,
+		},
+	}
+	for i, bundle := range bundles {
+		if err := b.txPool.AddBundle(bundle); err != nil {
+			t.Fatalf("failed to add bundle %d: %v", i, err)
+		}
+	}
+	// Only the valid bundle is included, which supersedes the pending transaction
+	task := waitTestTask(t, w)
+	if len(task.receipts) != 2 {
+		t.Fatalf("receipt number mismatch: have %d, want %d", len(task.receipts), 2)
+	}
+	for i, tx := range task.block.Transactions() {
+		if tx.Hash() != bundles[1].Txs[i].Hash() {
+			t.Errorf("transaction %d: hash mismatch: have %x, want %x", i, tx.Hash(), bundles[1].Txs[i].Hash())
+		}
+	}
+	if balance := task.state.GetBalance(testUserAddress); balance.Cmp(big.NewInt(2000)) != 0 {
+		t.Errorf("account balance mismatch: have %d, want %d", balance, 2000)
+	}
+	if gasUsed := task.block.GasUsed(); gasUsed != 2*params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", gasUsed, 2*params.TxGas)
+	}
+}
+
+func TestCommitBundleSenderCap(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+	)
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, db, 0)
+	defer w.close()
+
+	// A discarded bundle of the bank must not count against its sender cap
+	w.setOrderingPolicy(SenderCapOrdering{Policy: FIFOOrdering{}, Cap: 1})
+	bundle := &core.TxBundle{
+		Txs: types.Transactions{
+			signTestTx(types.NewTransaction(0, testUserAddress, big.NewInt(5000), params.TxGas, nil, nil)),
+			signTestTx(types.NewContractCreation(1, big.NewInt(0), testGas, nil, common.FromHex("60006000fd"))),
+		},
+		BlockNumber: big.NewInt(1),
+	}
+	if err := b.txPool.AddBundle(bundle); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	task := waitTestTask(t, w)
+	if len(task.receipts) != 1 {
+		t.Fatalf("receipt number mismatch: have %d, want %d", len(task.receipts), 1)
+	}
+	if hash := task.block.Transactions()[0].Hash(); hash != pendingTxs[0].Hash() {
+		t.Errorf("transaction hash mismatch: have %x, want %x", hash, pendingTxs[0].Hash())
+	}
+}
+
+func TestCommitConditional(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+	)
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, db, 0)
+	defer w.close()
+
+	// The second transaction depends on the bank nonce before the first one is
+	// executed, so its condition fails within the pending block
+	txs := types.Transactions{
+		signTestTx(types.NewTransaction(0, testUserAddress, big.NewInt(5000), params.TxGas, nil, nil)),
+		signTestTx(types.NewTransaction(1, testUserAddress, big.NewInt(5000), params.TxGas, nil, nil)),
+	}
+	conditions := []*core.TxConditions{
+		{BlockNumberMax: big.NewInt(1), Nonces: map[common.Address]uint64{testUserAddress: 0}},
+		{Nonces: map[common.Address]uint64{testBankAddress: 0}},
+	}
+	for i, tx := range txs {
+		if err := b.txPool.AddConditional(tx, conditions[i]); err != nil {
+			t.Fatalf("failed to add conditional transaction %d: %v", i, err)
+		}
+	}
+	// Only the first conditional transaction is included, superseding the pending one
+	task := waitTestTask(t, w)
+	if len(task.receipts) != 1 {
+		t.Fatalf("receipt number mismatch: have %d, want %d", len(task.receipts), 1)
+	}
+	if hash := task.block.Transactions()[0].Hash(); hash != txs[0].Hash() {
+		t.Errorf("transaction hash mismatch: have %x, want %x", hash, txs[0].Hash())
+	}
+	if balance := task.state.GetBalance(testUserAddress); balance.Cmp(big.NewInt(5000)) != 0 {
+		t.Errorf("account balance mismatch: have %d, want %d", balance, 5000)
+	}
+}
+
+func TestWorkerCandidate(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+	)
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, db, 0)
+	defer w.close()
+
+	// The first conditional transaction supersedes the pending one, the second one
+	// fails its condition within the block
+	txs := types.Transactions{
+		signTestTx(types.NewTransaction(0, testUserAddress, big.NewInt(5000), params.TxGas, nil, nil)),
+		signTestTx(types.NewTransaction(1, testUserAddress, big.NewInt(5000), params.TxGas, nil, nil)),
+	}
+	conditions := []*core.TxConditions{
+		{},
+		{Nonces: map[common.Address]uint64{testBankAddress: 0}},
+	}
+	for i, tx := range txs {
+		if err := b.txPool.AddConditional(tx, conditions[i]); err != nil {
+			t.Fatalf("failed to add conditional transaction %d: %v", i, err)
+		}
+	}
+	w.commitNewWork(nil, true, time.Now().Unix())
+
+	block, receipts, skipped := w.candidate()
+	if block.NumberU64() != 1 {
+		t.Fatalf("candidate number mismatch: have %d, want %d", block.NumberU64(), 1)
+	}
+	if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != txs[0].Hash() {
+		t.Fatalf("candidate transactions mismatch: have %v, want %x", block.Transactions(), txs[0].Hash())
+	}
+	if len(receipts) != 1 || receipts[0].GasUsed != params.TxGas {
+		t.Fatalf("candidate receipts mismatch: have %v", receipts)
+	}
+	if len(skipped) != 2 {
+		t.Fatalf("skipped transaction count mismatch: have %d, want %d", len(skipped), 2)
+	}
+	if skipped[0].Tx.Hash() != txs[1].Hash() || !strings.Contains(skipped[0].Reason, core.ErrConditionState.Error()) {
+		t.Errorf("skipped conditional transaction mismatch: have %x (%s), want %x", skipped[0].Tx.Hash(), skipped[0].Reason, txs[1].Hash())
+	}
+	if skipped[1].Tx.Hash() != pendingTxs[0].Hash() || skipped[1].Reason != SkipNonceTooLow || skipped[1].From != testBankAddress {
+		t.Errorf("skipped pending transaction mismatch: have %x (%s), want %x (%s)", skipped[1].Tx.Hash(), skipped[1].Reason, pendingTxs[0].Hash(), SkipNonceTooLow)
+	}
+}
+
 func TestStreamUncleBlock(t *testing.T) {
 	ethash := ethash.NewFaker()
 	defer ethash.Close()
@@ -501,3 +709,226 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+// orderingTestTxs creates a fresh set of transactions from three accounts to test
+// the ordering policies with, returning them grouped by account along with the
+// priority recipient used by some of them.
+func orderingTestTxs(t *testing.T) (map[string]*types.Transaction, func() map[common.Address]types.Transactions, common.Address) {
+	var (
+		keys     = make(map[string]*ecdsa.PrivateKey)
+		priority = common.HexToAddress("0xc0ffee")
+		other    = common.HexToAddress("0xdead")
+		named    = make(map[string]*types.Transaction)
+		order    []string
+	)
+	for _, name := range []string{"A", "B", "C"} {
+		keys[name], _ = crypto.GenerateKey()
+	}
+	// Sign the transactions in their order of arrival
+	for _, spec := range []struct {
+		name  string
+		nonce uint64
+		price int64
+		to    common.Address
+	}{
+		{"A", 0, 1, other},
+		{"B", 0, 3, other},
+		{"A", 1, 5, priority},
+		{"C", 0, 2, other},
+		{"B", 1, 3, other},
+		{"B", 2, 3, other},
+	} {
+		tx, err := types.SignTx(types.NewTransaction(spec.nonce, spec.to, big.NewInt(0), params.TxGas, big.NewInt(spec.price), nil), types.HomesteadSigner{}, keys[spec.name])
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		id := fmt.Sprintf("%s%d", spec.name, spec.nonce)
+		named[id], order = tx, append(order, id)
+		time.Sleep(time.Millisecond) // Ensure distinct arrival times
+	}
+	group := func() map[common.Address]types.Transactions {
+		txs := make(map[common.Address]types.Transactions)
+		for _, id := range order {
+			from, _ := types.Sender(types.HomesteadSigner{}, named[id])
+			txs[from] = append(txs[from], named[id])
+		}
+		return txs
+	}
+	return named, group, priority
+}
+
+func TestOrderingPolicies(t *testing.T) {
+	txs, group, priority := orderingTestTxs(t)
+
+	tests := []struct {
+		name   string
+		policy OrderingPolicy
+		want   []string
+	}{
+		{"price", PriceNonceOrdering{}, []string{"B0", "B1", "B2", "C0", "A0", "A1"}},
+		{"fifo", FIFOOrdering{}, []string{"A0", "B0", "A1", "C0", "B1", "B2"}},
+		{"sendercap", SenderCapOrdering{Policy: PriceNonceOrdering{}, Cap: 1}, []string{"B0", "C0", "A0"}},
+		{"priority", NewPriorityOrdering(PriceNonceOrdering{}, []common.Address{priority}), []string{"A0", "A1", "B0", "B1", "B2", "C0"}},
+		{"priority-fifo-capped", SenderCapOrdering{Policy: NewPriorityOrdering(FIFOOrdering{}, []common.Address{priority}), Cap: 2}, []string{"A0", "A1", "B0", "C0", "B1"}},
+	}
+	for _, tt := range tests {
+		set := tt.policy.Order(types.HomesteadSigner{}, group())
+
+		var have []string
+		for tx := set.Peek(); tx != nil; tx = set.Peek() {
+			for id, named := range txs {
+				if named == tx {
+					have = append(have, id)
+				}
+			}
+			set.Shift()
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("%s: ordering mismatch: have %v, want %v", tt.name, have, tt.want)
+		}
+	}
+}
+
+func TestOrderingPolicyPop(t *testing.T) {
+	txs, group, priority := orderingTestTxs(t)
+
+	// Popping a priority transaction must drop the account from its lane only
+	set := NewPriorityOrdering(PriceNonceOrdering{}, []common.Address{priority}).Order(types.HomesteadSigner{}, group())
+	if tx := set.Peek(); tx != txs["A0"] {
+		t.Fatalf("first transaction mismatch: have %x, want %x", tx.Hash(), txs["A0"].Hash())
+	}
+	set.Pop()
+	if tx := set.Peek(); tx != txs["B0"] {
+		t.Fatalf("transaction after pop mismatch: have %x, want %x", tx.Hash(), txs["B0"].Hash())
+	}
+}
+
+func TestMakeOrderingPolicy(t *testing.T) {
+	tests := []struct {
+		config *Config
+		want   OrderingPolicy
+		fail   bool
+	}{
+		{config: &Config{}, want: PriceNonceOrdering{}},
+		{config: &Config{Ordering: OrderingFIFO}, want: FIFOOrdering{}},
+		{config: &Config{Ordering: OrderingFIFO, SenderCap: 3}, want: SenderCapOrdering{Policy: FIFOOrdering{}, Cap: 3}},
+		{config: &Config{Ordering: "random"}, fail: true},
+	}
+	for i, tt := range tests {
+		policy, err := makeOrderingPolicy(tt.config)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.fail)
+			continue
+		}
+		if !reflect.DeepEqual(policy, tt.want) {
+			t.Errorf("test %d: policy mismatch: have %#v, want %#v", i, policy, tt.want)
+		}
+	}
+}
+
+func TestWorkerOrderingPolicy(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+	)
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, db, 0)
+	defer w.close()
+
+	// Cap the bank to a single transaction per block and queue up a second one
+	w.setOrderingPolicy(SenderCapOrdering{Policy: FIFOOrdering{}, Cap: 1})
+	b.txPool.AddLocal(b.newRandomTx(false))
+
+	if task := waitTestTask(t, w); len(task.receipts) != 1 {
+		t.Fatalf("receipt number mismatch: have %d, want %d", len(task.receipts), 1)
+	}
+}
+
+func TestWorkerSenderCapAcrossSets(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+	)
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, db, 0)
+	defer w.close()
+
+	// Fill the block with the bank's pending transaction under a cap of one,
+	// wrapped into another policy
+	w.setOrderingPolicy(NewPriorityOrdering(SenderCapOrdering{Policy: FIFOOrdering{}, Cap: 1}, nil))
+	w.commitNewWork(nil, true, time.Now().Unix())
+	if len(w.current.txs) != 1 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(w.current.txs), 1)
+	}
+	// A later transaction set of the same sender must not bypass the cap
+	tx := signTestTx(types.NewTransaction(1, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil))
+	set := w.orderTransactions(map[common.Address]types.Transactions{testBankAddress: {tx}})
+	w.commitTransactions(set, w.coinbase, nil)
+
+	if len(w.current.txs) != 1 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(w.current.txs), 1)
+	}
+	skipped := w.current.skipped
+	if len(skipped) == 0 || skipped[len(skipped)-1].Tx != tx || skipped[len(skipped)-1].Reason != SkipSenderCap {
+		t.Fatalf("capped transaction not skipped: %v", skipped)
+	}
+}
+
+func TestWorkerSkipReasons(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+	)
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, db, 0)
+	defer w.close()
+
+	// Include the bank's pending transaction under a cap of one
+	w.setOrderingPolicy(SenderCapOrdering{Policy: FIFOOrdering{}, Cap: 1})
+	w.commitNewWork(nil, true, time.Now().Unix())
+	if len(w.current.txs) != 1 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(w.current.txs), 1)
+	}
+	lastSkipped := func() *SkippedTx {
+		if len(w.current.skipped) == 0 {
+			return nil
+		}
+		return w.current.skipped[len(w.current.skipped)-1]
+	}
+	// The transactions of discarded bundles are skipped as a whole
+	bundle := &core.TxBundle{
+		Txs: types.Transactions{
+			signTestTx(types.NewTransaction(1, testUserAddress, big.NewInt(5000), params.TxGas, nil, nil)),
+			signTestTx(types.NewContractCreation(2, big.NewInt(0), testGas, nil, common.FromHex("60006000fd"))),
+		},
+	}
+	w.commitBundles([]*core.TxBundle{bundle}, w.coinbase, nil)
+	if skipped := w.current.skipped; len(skipped) != 2 {
+		t.Fatalf("skipped transaction count mismatch: have %d, want %d", len(skipped), 2)
+	}
+	for i, skip := range w.current.skipped {
+		if skip.Tx != bundle.Txs[i] || skip.From != testBankAddress || skip.Reason != SkipBundleDiscarded {
+			t.Errorf("skipped bundle transaction %d mismatch: %+v", i, skip)
+		}
+	}
+	// Conditional transactions are subject to the sender cap
+	tx := signTestTx(types.NewTransaction(1, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil))
+	w.commitConditional(map[common.Address][]*core.ConditionalTx{
+		testBankAddress: {{Tx: tx, Conditions: new(core.TxConditions)}},
+	}, w.coinbase, nil)
+	if skip := lastSkipped(); skip == nil || skip.Tx != tx || skip.Reason != SkipSenderCap {
+		t.Fatalf("capped conditional transaction not skipped: %+v", skip)
+	}
+	// Transactions not fitting into the block anymore lack block gas
+	w.current.gasPool = new(core.GasPool).AddGas(params.TxGas - 1)
+	w.commitTransactions(w.orderTransactions(map[common.Address]types.Transactions{testBankAddress: {tx}}), w.coinbase, nil)
+	if skip := lastSkipped(); skip == nil || skip.Tx != tx || skip.Reason != SkipInsufficientGas {
+		t.Fatalf("transaction exceeding the block gas not skipped: %+v", skip)
+	}
+	if len(w.current.txs) != 1 {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(w.current.txs), 1)
+	}
+}
@@ -185,6 +185,14 @@ func (api *privateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		Vhosts:             api.node.config.HTTPVirtualHosts,
 		Modules:            api.node.config.HTTPModules,
 	}
+	if err := api.node.configureJWT(api.node.config.HTTPJWTAuth, &config.JWTSecret, &config.JWTClockSkew); err != nil {
+		return false, err
+	}
+	endpoint, err := api.node.rpcEndpointConfig("http")
+	if err != nil {
+		return false, err
+	}
+	config.rpcEndpointConfig = endpoint
 	if cors != nil {
 		config.CorsAllowedOrigins = nil
 		for _, origin := range strings.Split(*cors, ",") {
@@ -245,6 +253,14 @@ func (api *privateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 		Origins: api.node.config.WSOrigins,
 		// ExposeAll: api.node.config.WSExposeAll,
 	}
+	if err := api.node.configureJWT(api.node.config.WSJWTAuth, &config.JWTSecret, &config.JWTClockSkew); err != nil {
+		return false, err
+	}
+	endpoint, err := api.node.rpcEndpointConfig("ws")
+	if err != nil {
+		return false, err
+	}
+	config.rpcEndpointConfig = endpoint
 	if apis != nil {
 		config.Modules = nil
 		for _, m := range strings.Split(*apis, ",") {
@@ -18,6 +18,9 @@ package node
 
 import (
 	"crypto/ecdsa"
+	crand "crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -25,6 +28,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ccm-chain/ccmchain/accounts"
 	"github.com/ccm-chain/ccmchain/accounts/external"
@@ -32,6 +36,7 @@ import (
 	"github.com/ccm-chain/ccmchain/accounts/scwallet"
 	"github.com/ccm-chain/ccmchain/accounts/usbwallet"
 	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/common/hexutil"
 	"github.com/ccm-chain/ccmchain/crypto"
 	"github.com/ccm-chain/ccmchain/log"
 	"github.com/ccm-chain/ccmchain/p2p"
@@ -45,6 +50,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirJWTSecret       = "jwtsecret"          // Path within the datadir to the JWT secret
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -162,6 +168,53 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// JWTSecret is the path of the file containing the hex encoded 32 byte secret
+	// authenticating JSON-RPC requests with JSON web tokens. If empty, the secret
+	// is stored in the data directory. If the file doesn't exist, a new random
+	// secret is generated and stored in it.
+	JWTSecret string `toml:",omitempty"`
+
+	// HTTPJWTAuth requires JSON-RPC requests over HTTP, and requests to the
+	// handlers served along them such as GraphQL, to be authenticated with JSON
+	// web tokens signed with the JWT secret.
+	HTTPJWTAuth bool `toml:",omitempty"`
+
+	// WSJWTAuth requires JSON-RPC connections over WebSocket to be authenticated
+	// with JSON web tokens signed with the JWT secret.
+	WSJWTAuth bool `toml:",omitempty"`
+
+	// JWTClockSkew is the maximum difference tolerated between the issuance time
+	// of JSON web tokens and the local clock.
+	JWTClockSkew time.Duration `toml:",omitempty"`
+
+	// RPCPolicy is the path of a JSON file restricting the methods callable over
+	// the "http", "ws" and "ipc" endpoints, per authenticated identity, and the
+	// rate at which they may be called. See rpc.AccessPolicy for the format of
+	// the policy of each endpoint.
+	RPCPolicy string `toml:",omitempty"`
+
+	// RPCBatchItemLimit is the maximum number of messages in a JSON-RPC batch
+	// served over HTTP, WebSocket and IPC. Zero means unlimited.
+	RPCBatchItemLimit int `toml:",omitempty"`
+
+	// RPCBatchResponseSizeLimit is the maximum total size in bytes of the results
+	// of a JSON-RPC batch. The calls of a batch exceeding it are answered with an
+	// error. Zero means unlimited.
+	RPCBatchResponseSizeLimit int `toml:",omitempty"`
+
+	// RPCCallTimeout is the maximum time spent executing a JSON-RPC method call.
+	// Zero means unlimited.
+	RPCCallTimeout time.Duration `toml:",omitempty"`
+
+	// RPCRequestSizeLimit is the maximum size in bytes of JSON-RPC requests over
+	// HTTP and of messages over WebSocket. Zero keeps the default of 5 MiB.
+	RPCRequestSizeLimit int `toml:",omitempty"`
+
+	// RPCResponseCache is the memory allowance in megabytes of the cache of the
+	// responses to RPC calls whose results only change on chain reorgs, such as
+	// the retrieval of blocks and receipts. Zero disables caching.
+	RPCResponseCache int `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -176,6 +229,23 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLMaxDepth is the maximum nesting depth of the selections of a GraphQL
+	// query. Zero means unlimited.
+	GraphQLMaxDepth int `toml:",omitempty"`
+
+	// GraphQLMaxComplexity is the maximum complexity of a GraphQL query, counting
+	// the fields it resolves and the elements of the lists it requests. Zero
+	// means unlimited.
+	GraphQLMaxComplexity int `toml:",omitempty"`
+
+	// GraphQLMaxResultSize is the maximum size in bytes of the encoded result of
+	// a GraphQL query. Zero means unlimited.
+	GraphQLMaxResultSize int `toml:",omitempty"`
+
+	// GraphQLTimeout is the maximum time spent executing a GraphQL query. Zero
+	// means unlimited.
+	GraphQLTimeout time.Duration `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -373,6 +443,62 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// jwtSecret retrieves the secret authenticating JSON-RPC requests with JSON web
+// tokens. If the secret file doesn't exist, a new secret is generated and stored.
+func (c *Config) jwtSecret() ([]byte, error) {
+	path := c.JWTSecret
+	if path == "" {
+		if c.DataDir == "" {
+			return nil, errors.New("JWT authentication requires a secret file or a data directory")
+		}
+		path = c.ResolvePath(datadirJWTSecret)
+	}
+	if blob, err := ioutil.ReadFile(path); err == nil {
+		secret := common.FromHex(strings.TrimSpace(string(blob)))
+		if len(secret) != jwtSecretLength {
+			return nil, fmt.Errorf("invalid JWT secret in %s: have %d bytes, want %d", path, len(secret), jwtSecretLength)
+		}
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// No secret found, generate and store a new one
+	secret := make([]byte, jwtSecretLength)
+	if _, err := crand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
+// rpcPolicy loads the access policy of the given RPC endpoint ("http", "ws" or
+// "ipc") from the policy file, returning nil if the endpoint is unrestricted.
+func (c *Config) rpcPolicy(endpoint string) (*rpc.AccessPolicy, error) {
+	if c.RPCPolicy == "" {
+		return nil, nil
+	}
+	blob, err := ioutil.ReadFile(c.RPCPolicy)
+	if err != nil {
+		return nil, err
+	}
+	var policies map[string]*rpc.AccessPolicy
+	if err := json.Unmarshal(blob, &policies); err != nil {
+		return nil, fmt.Errorf("invalid RPC policy file %s: %v", c.RPCPolicy, err)
+	}
+	for name := range policies {
+		if name != "http" && name != "ws" && name != "ipc" {
+			return nil, fmt.Errorf("invalid RPC policy file %s: unknown endpoint %q", c.RPCPolicy, name)
+		}
+	}
+	return policies[endpoint], nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*enode.Node {
 	return c.parsePersistentNodes(&c.staticNodesWarning, c.ResolvePath(datadirStaticNodes))
@@ -160,3 +160,75 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that the JWT secret is generated into the data directory if missing, and
+// loaded from it subsequently.
+func TestJWTSecretPersistency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &Config{Name: "unit-test", DataDir: dir}
+	secret1, err := config.jwtSecret()
+	if err != nil {
+		t.Fatalf("failed to generate JWT secret: %v", err)
+	}
+	if len(secret1) != jwtSecretLength {
+		t.Fatalf("generated JWT secret length mismatch: have %d, want %d", len(secret1), jwtSecretLength)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unit-test", datadirJWTSecret)); err != nil {
+		t.Fatalf("JWT secret not persisted to data directory: %v", err)
+	}
+	secret2, err := config.jwtSecret()
+	if err != nil {
+		t.Fatalf("failed to load JWT secret: %v", err)
+	}
+	if !bytes.Equal(secret1, secret2) {
+		t.Fatalf("persisted JWT secret mismatch: have %x, want %x", secret2, secret1)
+	}
+	// Ensure invalid secrets are rejected
+	path := filepath.Join(dir, "invalid")
+	if err := ioutil.WriteFile(path, []byte("0x0102"), 0600); err != nil {
+		t.Fatalf("failed to write invalid JWT secret: %v", err)
+	}
+	config = &Config{JWTSecret: path}
+	if _, err := config.jwtSecret(); err == nil {
+		t.Fatalf("invalid JWT secret loaded")
+	}
+}
+
+// Tests that the access policies of the RPC endpoints are loaded from the policy
+// file, and that invalid files are rejected.
+func TestRPCPolicyLoading(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.json")
+	blob := `{"http": {"allow": ["eth_*"], "identities": {"admin": {}}, "clientRate": {"rate": 10}}}`
+	if err := ioutil.WriteFile(path, []byte(blob), 0600); err != nil {
+		t.Fatalf("failed to write RPC policy: %v", err)
+	}
+	config := &Config{RPCPolicy: path}
+	policy, err := config.rpcPolicy("http")
+	if err != nil {
+		t.Fatalf("failed to load RPC policy: %v", err)
+	}
+	if policy == nil || len(policy.Allow) != 1 || policy.Identities["admin"] == nil || policy.ClientRate.Rate != 10 {
+		t.Fatalf("loaded RPC policy mismatch: %+v", policy)
+	}
+	if policy, err := config.rpcPolicy("ws"); err != nil || policy != nil {
+		t.Fatalf("unrestricted endpoint policy mismatch: have %v (%v), want nil", policy, err)
+	}
+	// Ensure unknown endpoints are rejected
+	if err := ioutil.WriteFile(path, []byte(`{"rest": {}}`), 0600); err != nil {
+		t.Fatalf("failed to write invalid RPC policy: %v", err)
+	}
+	if _, err := config.rpcPolicy("http"); err == nil {
+		t.Fatalf("invalid RPC policy loaded")
+	}
+}
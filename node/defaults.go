@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/ccm-chain/ccmchain/p2p"
 	"github.com/ccm-chain/ccmchain/p2p/nat"
@@ -34,18 +35,28 @@ const (
 	DefaultWSPort      = 8086        // Default TCP port for the websocket RPC server
 	DefaultGraphQLHost = "localhost" // Default host interface for the GraphQL server
 	DefaultGraphQLPort = 8087        // Default TCP port for the GraphQL server
+
+	DefaultJWTClockSkew = 60 * time.Second // Default clock skew tolerated for JSON web tokens
 )
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:             DefaultDataDir(),
-	HTTPPort:            DefaultHTTPPort,
-	HTTPModules:         []string{"net", "web3"},
-	HTTPVirtualHosts:    []string{"localhost"},
-	HTTPTimeouts:        rpc.DefaultHTTPTimeouts,
-	WSPort:              DefaultWSPort,
-	WSModules:           []string{"net", "web3"},
-	GraphQLVirtualHosts: []string{"localhost"},
+	DataDir:                   DefaultDataDir(),
+	HTTPPort:                  DefaultHTTPPort,
+	HTTPModules:               []string{"net", "web3"},
+	HTTPVirtualHosts:          []string{"localhost"},
+	HTTPTimeouts:              rpc.DefaultHTTPTimeouts,
+	WSPort:                    DefaultWSPort,
+	WSModules:                 []string{"net", "web3"},
+	JWTClockSkew:              DefaultJWTClockSkew,
+	RPCBatchItemLimit:         1000,
+	RPCBatchResponseSizeLimit: 25 * 1024 * 1024,
+	RPCRequestSizeLimit:       5 * 1024 * 1024,
+	GraphQLVirtualHosts:       []string{"localhost"},
+	GraphQLMaxDepth:           20,
+	GraphQLMaxComplexity:      100000,
+	GraphQLMaxResultSize:      16 * 1024 * 1024,
+	GraphQLTimeout:            20 * time.Second,
 	P2P: p2p.Config{
 		ListenAddr: ":10101",
 		MaxPeers:   50,
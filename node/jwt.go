@@ -0,0 +1,179 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ccm-chain/ccmchain/rpc"
+)
+
+// jwtSecretLength is the length of the shared secrets signing JSON web tokens.
+const jwtSecretLength = 32
+
+// jwtAlgorithm is the only supported signing algorithm of JSON web tokens.
+const jwtAlgorithm = "HS256"
+
+var (
+	errMissingToken   = errors.New("missing bearer token")
+	errMalformedToken = errors.New("malformed token")
+	errTokenAlgorithm = errors.New("unsupported token signing algorithm")
+	errTokenSignature = errors.New("invalid token signature")
+	errTokenIssuance  = errors.New("token issuance time missing or outside the allowed clock skew")
+	errTokenExpired   = errors.New("token expired")
+)
+
+// jwtEncoding is the encoding of the segments of JSON web tokens.
+var jwtEncoding = base64.RawURLEncoding
+
+// jwtHeader is the header of a JSON web token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// jwtClaims are the claims of a JSON web token verified by the node.
+type jwtClaims struct {
+	IssuedAt *int64 `json:"iat,omitempty"` // Issuance time, required
+	Expiry   *int64 `json:"exp,omitempty"` // Expiration time, optional
+	ID       string `json:"id,omitempty"`  // Identity of the caller, optional
+}
+
+// signJWT creates a JSON web token with the given claims, signed with HMAC-SHA256
+// using the secret.
+func signJWT(secret []byte, claims *jwtClaims) (string, error) {
+	header, err := json.Marshal(&jwtHeader{Alg: jwtAlgorithm, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtEncoding.EncodeToString(header) + "." + jwtEncoding.EncodeToString(payload)
+	return unsigned + "." + jwtEncoding.EncodeToString(jwtSignature(secret, unsigned)), nil
+}
+
+// jwtSignature computes the HMAC-SHA256 signature of the unsigned part of a token.
+func jwtSignature(secret []byte, unsigned string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+// verifyJWT checks that a JSON web token is signed with the secret and was issued
+// within the allowed clock skew of the given time, returning its claims.
+func verifyJWT(secret []byte, token string, skew time.Duration, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedToken
+	}
+	var header jwtHeader
+	if blob, err := jwtEncoding.DecodeString(parts[0]); err != nil {
+		return nil, errMalformedToken
+	} else if err := json.Unmarshal(blob, &header); err != nil {
+		return nil, errMalformedToken
+	}
+	if header.Alg != jwtAlgorithm {
+		return nil, errTokenAlgorithm
+	}
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	if !hmac.Equal(signature, jwtSignature(secret, parts[0]+"."+parts[1])) {
+		return nil, errTokenSignature
+	}
+	var claims jwtClaims
+	if blob, err := jwtEncoding.DecodeString(parts[1]); err != nil {
+		return nil, errMalformedToken
+	} else if err := json.Unmarshal(blob, &claims); err != nil {
+		return nil, errMalformedToken
+	}
+	if claims.IssuedAt == nil {
+		return nil, errTokenIssuance
+	}
+	if issued := time.Unix(*claims.IssuedAt, 0); issued.Before(now.Add(-skew)) || issued.After(now.Add(skew)) {
+		return nil, errTokenIssuance
+	}
+	if claims.Expiry != nil && now.After(time.Unix(*claims.Expiry, 0).Add(skew)) {
+		return nil, errTokenExpired
+	}
+	return &claims, nil
+}
+
+// jwtHandler is a handler which authenticates incoming requests with JSON web
+// tokens passed as bearer tokens in the Authorization header, signed with a
+// shared secret. Tokens must be freshly issued, limiting the time a leaked token
+// may be replayed to the allowed clock skew. The identity claimed by a token is
+// passed on to the RPC server, subjecting the calls to its access rules.
+type jwtHandler struct {
+	secret []byte
+	skew   time.Duration
+	next   http.Handler
+}
+
+// newJWTHandler creates a handler authenticating requests with JSON web tokens
+// signed with the given secret, before passing them to the next handler.
+func newJWTHandler(secret []byte, skew time.Duration, next http.Handler) http.Handler {
+	return &jwtHandler{secret: secret, skew: skew, next: next}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		http.Error(w, errMissingToken.Error(), http.StatusUnauthorized)
+		return
+	}
+	claims, err := verifyJWT(h.secret, strings.TrimPrefix(auth, "Bearer "), h.skew, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if claims.ID != "" {
+		r = r.WithContext(rpc.WithIdentity(r.Context(), claims.ID))
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// NewJWTAuth creates an authentication provider for RPC clients, attaching a
+// freshly issued JSON web token signed with the given secret to every request.
+func NewJWTAuth(secret []byte) rpc.HTTPAuth {
+	return NewJWTIdentityAuth(secret, "")
+}
+
+// NewJWTIdentityAuth creates an authentication provider for RPC clients like
+// NewJWTAuth, with tokens claiming the given identity.
+func NewJWTIdentityAuth(secret []byte, identity string) rpc.HTTPAuth {
+	return func(h http.Header) error {
+		issued := time.Now().Unix()
+		token, err := signJWT(secret, &jwtClaims{IssuedAt: &issued, ID: identity})
+		if err != nil {
+			return err
+		}
+		h.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
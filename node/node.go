@@ -25,6 +25,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ccm-chain/ccmchain/accounts"
 	"github.com/ccm-chain/ccmchain/core/rawdb"
@@ -50,12 +51,13 @@ type Node struct {
 	state         int               // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle        // All registered backends, services, and auxiliary services that have a lifecycle
+	rpcAPIs       []rpc.API          // List of APIs currently provided by the node
+	http          *httpServer        //
+	ws            *httpServer        //
+	ipc           *ipcServer         // Stores information about the ipc http server
+	inprocHandler *rpc.Server        // In-process RPC request handler to process the API requests
+	rpcCache      *rpc.ResponseCache // Cache of the RPC responses served by the endpoints, nil if disabled
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -105,6 +107,10 @@ func New(conf *Config) (*Node, error) {
 		databases:     make(map[*closeTrackingDB]struct{}),
 	}
 
+	if conf.RPCResponseCache > 0 {
+		node.rpcCache = rpc.NewResponseCache(conf.RPCResponseCache * 1024 * 1024)
+	}
+
 	// Register built-in APIs.
 	node.rpcAPIs = append(node.rpcAPIs, node.apis()...)
 
@@ -332,17 +338,29 @@ func (n *Node) startRPC() error {
 
 	// Configure IPC.
 	if n.ipc.endpoint != "" {
-		if err := n.ipc.start(n.rpcAPIs); err != nil {
+		endpoint, err := n.rpcEndpointConfig("ipc")
+		if err != nil {
+			return err
+		}
+		if err := n.ipc.start(n.rpcAPIs, endpoint); err != nil {
 			return err
 		}
 	}
 
 	// Configure HTTP.
 	if n.config.HTTPHost != "" {
+		endpoint, err := n.rpcEndpointConfig("http")
+		if err != nil {
+			return err
+		}
 		config := httpConfig{
 			CorsAllowedOrigins: n.config.HTTPCors,
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
+			rpcEndpointConfig:  endpoint,
+		}
+		if err := n.configureJWT(n.config.HTTPJWTAuth, &config.JWTSecret, &config.JWTClockSkew); err != nil {
+			return err
 		}
 		if err := n.http.setListenAddr(n.config.HTTPHost, n.config.HTTPPort); err != nil {
 			return err
@@ -355,9 +373,17 @@ func (n *Node) startRPC() error {
 	// Configure WebSocket.
 	if n.config.WSHost != "" {
 		server := n.wsServerForPort(n.config.WSPort)
+		endpoint, err := n.rpcEndpointConfig("ws")
+		if err != nil {
+			return err
+		}
 		config := wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
+			Modules:           n.config.WSModules,
+			Origins:           n.config.WSOrigins,
+			rpcEndpointConfig: endpoint,
+		}
+		if err := n.configureJWT(n.config.WSJWTAuth, &config.JWTSecret, &config.JWTClockSkew); err != nil {
+			return err
 		}
 		if err := server.setListenAddr(n.config.WSHost, n.config.WSPort); err != nil {
 			return err
@@ -373,6 +399,40 @@ func (n *Node) startRPC() error {
 	return n.ws.start()
 }
 
+// rpcEndpointConfig assembles the access policy, resource limits and response
+// cache of the given RPC endpoint ("http", "ws" or "ipc").
+func (n *Node) rpcEndpointConfig(endpoint string) (rpcEndpointConfig, error) {
+	policy, err := n.config.rpcPolicy(endpoint)
+	if err != nil {
+		return rpcEndpointConfig{}, err
+	}
+	return rpcEndpointConfig{
+		Policy:                 policy,
+		BatchItemLimit:         n.config.RPCBatchItemLimit,
+		BatchResponseSizeLimit: n.config.RPCBatchResponseSizeLimit,
+		CallTimeout:            n.config.RPCCallTimeout,
+		RequestSizeLimit:       n.config.RPCRequestSizeLimit,
+		Cache:                  n.rpcCache,
+	}, nil
+}
+
+// configureJWT sets the secret and clock skew authenticating an RPC endpoint with
+// JSON web tokens, if authentication is required.
+func (n *Node) configureJWT(required bool, secret *[]byte, skew *time.Duration) error {
+	if !required {
+		return nil
+	}
+	key, err := n.config.jwtSecret()
+	if err != nil {
+		return err
+	}
+	*secret, *skew = key, n.config.JWTClockSkew
+	if *skew == 0 {
+		*skew = DefaultJWTClockSkew
+	}
+	return nil
+}
+
 func (n *Node) wsServerForPort(port int) *httpServer {
 	if n.config.HTTPHost == "" || n.http.port == port {
 		return n.http
@@ -474,6 +534,12 @@ func (n *Node) RPCHandler() (*rpc.Server, error) {
 	return n.inprocHandler, nil
 }
 
+// RPCResponseCache returns the cache of the responses served by the RPC endpoints,
+// or nil if caching is disabled. Backends invalidate it on chain reorgs.
+func (n *Node) RPCResponseCache() *rpc.ResponseCache {
+	return n.rpcCache
+}
+
 // Config returns the configuration of node.
 func (n *Node) Config() *Config {
 	return n.config
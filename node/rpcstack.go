@@ -28,6 +28,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ccm-chain/ccmchain/log"
 	"github.com/ccm-chain/ccmchain/rpc"
@@ -39,17 +40,53 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
+	JWTSecret          []byte        // Secret authenticating requests, nil if unauthenticated
+	JWTClockSkew       time.Duration // Clock skew tolerated for the issuance time of tokens
+	rpcEndpointConfig
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
-	Origins []string
-	Modules []string
+	Origins      []string
+	Modules      []string
+	JWTSecret    []byte        // Secret authenticating connections, nil if unauthenticated
+	JWTClockSkew time.Duration // Clock skew tolerated for the issuance time of tokens
+	rpcEndpointConfig
+}
+
+// rpcEndpointConfig contains the access policy, resource limits and response cache
+// of the calls served by an RPC endpoint. Zero limits are disabled.
+type rpcEndpointConfig struct {
+	Policy                 *rpc.AccessPolicy  // Access policy of the endpoint, nil if unrestricted
+	BatchItemLimit         int                // Maximum number of messages in a batch
+	BatchResponseSizeLimit int                // Maximum total size of the results of a batch
+	CallTimeout            time.Duration      // Maximum execution time of a method call
+	RequestSizeLimit       int                // Maximum size of HTTP request bodies and WebSocket messages
+	Cache                  *rpc.ResponseCache // Cache of the responses, nil if uncached
+}
+
+// setup configures an RPC server with the policy and limits of the endpoint.
+func (c *rpcEndpointConfig) setup(srv *rpc.Server) error {
+	if c.Policy != nil {
+		if err := srv.SetAccessPolicy(c.Policy); err != nil {
+			return err
+		}
+	}
+	srv.SetBatchLimits(c.BatchItemLimit, c.BatchResponseSizeLimit)
+	srv.SetCallTimeout(c.CallTimeout)
+	if c.RequestSizeLimit > 0 {
+		srv.SetRequestSizeLimit(c.RequestSizeLimit)
+	}
+	if c.Cache != nil {
+		srv.SetResponseCache(c.Cache)
+	}
+	return nil
 }
 
 type rpcHandler struct {
 	http.Handler
 	server *rpc.Server
+	mux    http.Handler // handlers registered via Node.RegisterHandler, nil for WebSocket
 }
 
 type httpServer struct {
@@ -185,8 +222,9 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	} else if rpc != nil {
 		// Requests to a path below root are handled by the mux,
 		// which has all the handlers registered via Node.RegisterHandler.
-		// These are made available when RPC is enabled.
-		h.mux.ServeHTTP(w, r)
+		// These are made available when RPC is enabled, behind the same
+		// authentication.
+		rpc.mux.ServeHTTP(w, r)
 		return
 	}
 	w.WriteHeader(404)
@@ -238,10 +276,22 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	if err := config.setup(srv); err != nil {
+		return err
+	}
+	var (
+		handler http.Handler = srv
+		mux     http.Handler = &h.mux
+	)
+	if config.JWTSecret != nil {
+		handler = newJWTHandler(config.JWTSecret, config.JWTClockSkew, handler)
+		mux = newJWTHandler(config.JWTSecret, config.JWTClockSkew, mux)
+	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts),
+		Handler: NewHTTPHandlerStack(handler, config.CorsAllowedOrigins, config.Vhosts),
 		server:  srv,
+		mux:     mux,
 	})
 	return nil
 }
@@ -270,9 +320,16 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	if err := config.setup(srv); err != nil {
+		return err
+	}
+	handler := srv.WebsocketHandler(config.Origins)
+	if config.JWTSecret != nil {
+		handler = newJWTHandler(config.JWTSecret, config.JWTClockSkew, handler)
+	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
-		Handler: srv.WebsocketHandler(config.Origins),
+		Handler: handler,
 		server:  srv,
 	})
 	return nil
@@ -439,14 +496,14 @@ func newIPCServer(log log.Logger, endpoint string) *ipcServer {
 }
 
 // Start starts the httpServer's http.Server
-func (is *ipcServer) start(apis []rpc.API) error {
+func (is *ipcServer) start(apis []rpc.API, config rpcEndpointConfig) error {
 	is.mu.Lock()
 	defer is.mu.Unlock()
 
 	if is.listener != nil {
 		return nil // already running
 	}
-	listener, srv, err := rpc.StartIPCEndpoint(is.endpoint, apis)
+	listener, srv, err := rpc.StartIPCEndpoint(is.endpoint, apis, config.setup)
 	if err != nil {
 		return err
 	}
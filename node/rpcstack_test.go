@@ -18,8 +18,12 @@ package node
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ccm-chain/ccmchain/internal/testlog"
 	"github.com/ccm-chain/ccmchain/log"
@@ -123,3 +127,92 @@ func testRequest(t *testing.T, key, value, host string, srv *httpServer) *http.R
 	}
 	return resp
 }
+
+// TestJWT makes sure requests are authenticated with JSON web tokens on the http
+// and websocket servers.
+func TestJWT(t *testing.T) {
+	var (
+		secret = bytes.Repeat([]byte{0x01}, jwtSecretLength)
+		skew   = 5 * time.Second
+	)
+	srv := createAndStartServer(t, httpConfig{JWTSecret: secret, JWTClockSkew: skew}, true, wsConfig{JWTSecret: secret, JWTClockSkew: skew})
+	defer srv.stop()
+
+	token := func(secret []byte, issued time.Time, expiry *time.Time) string {
+		claims := &jwtClaims{IssuedAt: new(int64)}
+		*claims.IssuedAt = issued.Unix()
+		if expiry != nil {
+			claims.Expiry = new(int64)
+			*claims.Expiry = expiry.Unix()
+		}
+		token, err := signJWT(secret, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	var (
+		now     = time.Now()
+		expired = now.Add(-time.Minute)
+		issued  = now.Unix()
+
+		header, _   = json.Marshal(&jwtHeader{Alg: "none"})
+		payload, _  = json.Marshal(&jwtClaims{IssuedAt: &issued})
+		unsigned, _ = json.Marshal(&jwtClaims{})
+	)
+	tests := []struct {
+		auth   string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{token(secret, now, nil), http.StatusOK},
+		{token(secret, now.Add(-3*time.Second), nil), http.StatusOK},
+		{token(secret, now.Add(3*time.Second), nil), http.StatusOK},
+		{strings.TrimPrefix(token(secret, now, nil), "Bearer "), http.StatusUnauthorized},
+		{token(bytes.Repeat([]byte{0x02}, jwtSecretLength), now, nil), http.StatusUnauthorized},
+		{token(secret, now.Add(-time.Minute), nil), http.StatusUnauthorized},
+		{token(secret, now.Add(time.Minute), nil), http.StatusUnauthorized},
+		{token(secret, now, &expired), http.StatusUnauthorized},
+		{"Bearer " + jwtEncoding.EncodeToString(header) + "." + jwtEncoding.EncodeToString(payload) + ".", http.StatusUnauthorized},
+		{"Bearer " + jwtEncoding.EncodeToString(header) + "." + jwtEncoding.EncodeToString(unsigned) + ".", http.StatusUnauthorized},
+		{"Bearer invalid", http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		resp := testRequest(t, "Authorization", tt.auth, "", srv)
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+	}
+	// Check that clients attach tokens to http requests and websocket connections
+	for _, url := range []string{"http://" + srv.listenAddr(), "ws://" + srv.listenAddr()} {
+		client, err := rpc.DialOptions(context.Background(), url, rpc.WithHTTPAuth(NewJWTAuth(secret)))
+		if err != nil {
+			t.Fatalf("%s: failed to dial: %v", url, err)
+		}
+		if err := client.Call(nil, "rpc_modules"); err != nil {
+			t.Errorf("%s: authenticated call failed: %v", url, err)
+		}
+		client.Close()
+
+		if client, err = rpc.DialOptions(context.Background(), url); err == nil {
+			if err := client.Call(nil, "rpc_modules"); err == nil {
+				t.Errorf("%s: unauthenticated call succeeded", url)
+			}
+			client.Close()
+		}
+	}
+	// Check that the handlers served along JSON-RPC are authenticated too
+	srv.mux.Handle("/test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests[:2] {
+		req, _ := http.NewRequest("GET", "http://"+srv.listenAddr()+"/test", nil)
+		req.Header.Set("Authorization", tt.auth)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("handler: status mismatch: have %d, want %d", resp.StatusCode, tt.status)
+		}
+	}
+}
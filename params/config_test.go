@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ccm-chain/ccmchain/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -79,3 +81,22 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckConfigForkOrderClique(t *testing.T) {
+	signer := common.HexToAddress("0x01")
+	tests := []struct {
+		config  *ChainConfig
+		wantErr bool
+	}{
+		{config: &ChainConfig{Clique: &CliqueConfig{}}},
+		{config: &ChainConfig{CliqueBlock: big.NewInt(0), Clique: &CliqueConfig{}}},
+		{config: &ChainConfig{CliqueBlock: big.NewInt(10), Ethash: new(EthashConfig), Clique: &CliqueConfig{Signers: []common.Address{signer}}}},
+		{config: &ChainConfig{CliqueBlock: big.NewInt(10), Ethash: new(EthashConfig), Clique: &CliqueConfig{}}, wantErr: true},
+		{config: &ChainConfig{CliqueBlock: big.NewInt(10), Ethash: new(EthashConfig)}, wantErr: true},
+	}
+	for i, test := range tests {
+		if err := test.config.CheckConfigForkOrder(); (err != nil) != test.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want error %v", i, err, test.wantErr)
+		}
+	}
+}
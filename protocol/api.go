@@ -35,6 +35,7 @@ import (
 	"github.com/ccm-chain/ccmchain/core/state"
 	"github.com/ccm-chain/ccmchain/core/types"
 	ethapi "github.com/ccm-chain/ccmchain/internal/api"
+	"github.com/ccm-chain/ccmchain/miner"
 	"github.com/ccm-chain/ccmchain/rlp"
 	"github.com/ccm-chain/ccmchain/rpc"
 	"github.com/ccm-chain/ccmchain/trie"
@@ -149,6 +150,111 @@ func (api *PrivateMinerAPI) GetHashrate() uint64 {
 	return api.e.miner.HashRate()
 }
 
+// SimulatedTransaction is a transaction included in the simulated next block,
+// along with the outcome of its execution.
+type SimulatedTransaction struct {
+	Hash     common.Hash     `json:"hash"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	GasUsed  hexutil.Uint64  `json:"gasUsed"`
+	Fee      *hexutil.Big    `json:"fee"`
+	Status   hexutil.Uint64  `json:"status"`
+	Logs     []*types.Log    `json:"logs"`
+}
+
+// SkippedTransaction is a transaction left out of the simulated next block,
+// along with the reason why.
+type SkippedTransaction struct {
+	Hash     common.Hash    `json:"hash"`
+	From     common.Address `json:"from"`
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	GasPrice *hexutil.Big   `json:"gasPrice"`
+	Reason   string         `json:"reason"`
+}
+
+// SimulatedBlock is the block the miner would produce next.
+type SimulatedBlock struct {
+	Number       hexutil.Uint64          `json:"number"`
+	ParentHash   common.Hash             `json:"parentHash"`
+	Coinbase     common.Address          `json:"miner"`
+	Timestamp    hexutil.Uint64          `json:"timestamp"`
+	GasLimit     hexutil.Uint64          `json:"gasLimit"`
+	GasUsed      hexutil.Uint64          `json:"gasUsed"`
+	Fees         *hexutil.Big            `json:"fees"`
+	Transactions []*SimulatedTransaction `json:"transactions"`
+	Skipped      []*SkippedTransaction   `json:"skipped"`
+}
+
+// SimulateNextBlock returns the candidate block the miner is currently building
+// on top of the chain head: the transactions it includes along with their gas
+// usage, receipt status, logs and fees, as well as the transactions the miner
+// left out with the reason why. The queued transactions of the pool, which the
+// miner cannot consider at all, are reported as skipped too.
+func (api *PrivateMinerAPI) SimulateNextBlock() (*SimulatedBlock, error) {
+	block, receipts, skipped := api.e.Miner().Candidate()
+	if block == nil {
+		return nil, errors.New("no pending block available")
+	}
+	var (
+		signer = types.MakeSigner(api.e.blockchain.Config(), block.Number())
+		fees   = new(big.Int)
+		result = &SimulatedBlock{
+			Number:       hexutil.Uint64(block.NumberU64()),
+			ParentHash:   block.ParentHash(),
+			Coinbase:     block.Coinbase(),
+			Timestamp:    hexutil.Uint64(block.Time()),
+			GasLimit:     hexutil.Uint64(block.GasLimit()),
+			GasUsed:      hexutil.Uint64(block.GasUsed()),
+			Transactions: make([]*SimulatedTransaction, 0, len(block.Transactions())),
+			Skipped:      make([]*SkippedTransaction, 0, len(skipped)),
+		}
+	)
+	for i, tx := range block.Transactions() {
+		from, _ := types.Sender(signer, tx)
+		receipt := receipts[i]
+
+		fee := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipt.GasUsed))
+		fees.Add(fees, fee)
+
+		result.Transactions = append(result.Transactions, &SimulatedTransaction{
+			Hash:     tx.Hash(),
+			From:     from,
+			To:       tx.To(),
+			Nonce:    hexutil.Uint64(tx.Nonce()),
+			GasPrice: (*hexutil.Big)(tx.GasPrice()),
+			GasUsed:  hexutil.Uint64(receipt.GasUsed),
+			Fee:      (*hexutil.Big)(fee),
+			Status:   hexutil.Uint64(receipt.Status),
+			Logs:     receipt.Logs,
+		})
+	}
+	result.Fees = (*hexutil.Big)(fees)
+
+	for _, skip := range skipped {
+		result.Skipped = append(result.Skipped, newSkippedTransaction(skip))
+	}
+	pending := false
+	queued, _ := api.e.txPool.Query(core.TxPoolQuery{Pending: &pending})
+	for _, entry := range queued {
+		result.Skipped = append(result.Skipped, newSkippedTransaction(&miner.SkippedTx{Tx: entry.Tx, From: entry.From, Reason: entry.Reason}))
+	}
+	return result, nil
+}
+
+// newSkippedTransaction converts a transaction left out of a block into its RPC
+// representation.
+func newSkippedTransaction(skip *miner.SkippedTx) *SkippedTransaction {
+	return &SkippedTransaction{
+		Hash:     skip.Tx.Hash(),
+		From:     skip.From,
+		Nonce:    hexutil.Uint64(skip.Tx.Nonce()),
+		GasPrice: (*hexutil.Big)(skip.Tx.GasPrice()),
+		Reason:   skip.Reason,
+	}
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -281,9 +387,15 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 		return stateDb.RawDump(false, false, true), nil
 	}
 	var block *types.Block
-	if blockNr == rpc.LatestBlockNumber {
+	switch blockNr {
+	case rpc.LatestBlockNumber:
 		block = api.eth.blockchain.CurrentBlock()
-	} else {
+	case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+		var err error
+		if block, err = api.eth.APIBackend.BlockByNumber(context.Background(), blockNr); err != nil {
+			return state.Dump{}, err
+		}
+	default:
 		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
 	}
 	if block == nil {
@@ -362,9 +474,14 @@ func (api *PublicDebugAPI) AccountRange(blockNrOrHash rpc.BlockNumberOrHash, sta
 			_, stateDb = api.eth.miner.Pending()
 		} else {
 			var block *types.Block
-			if number == rpc.LatestBlockNumber {
+			switch number {
+			case rpc.LatestBlockNumber:
 				block = api.eth.blockchain.CurrentBlock()
-			} else {
+			case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+				if block, err = api.eth.APIBackend.BlockByNumber(context.Background(), number); err != nil {
+					return state.IteratorDump{}, err
+				}
+			default:
 				block = api.eth.blockchain.GetBlockByNumber(uint64(number))
 			}
 			if block == nil {
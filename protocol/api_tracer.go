@@ -110,6 +110,11 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 		from = api.eth.miner.PendingBlock()
 	case rpc.LatestBlockNumber:
 		from = api.eth.blockchain.CurrentBlock()
+	case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+		var err error
+		if from, err = api.eth.APIBackend.BlockByNumber(ctx, start); err != nil {
+			return nil, err
+		}
 	default:
 		from = api.eth.blockchain.GetBlockByNumber(uint64(start))
 	}
@@ -118,6 +123,11 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 		to = api.eth.miner.PendingBlock()
 	case rpc.LatestBlockNumber:
 		to = api.eth.blockchain.CurrentBlock()
+	case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+		var err error
+		if to, err = api.eth.APIBackend.BlockByNumber(ctx, end); err != nil {
+			return nil, err
+		}
 	default:
 		to = api.eth.blockchain.GetBlockByNumber(uint64(end))
 	}
@@ -361,6 +371,11 @@ func (api *PrivateDebugAPI) TraceBlockByNumber(ctx context.Context, number rpc.B
 		block = api.eth.miner.PendingBlock()
 	case rpc.LatestBlockNumber:
 		block = api.eth.blockchain.CurrentBlock()
+	case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+		var err error
+		if block, err = api.eth.APIBackend.BlockByNumber(ctx, number); err != nil {
+			return nil, err
+		}
 	default:
 		block = api.eth.blockchain.GetBlockByNumber(uint64(number))
 	}
@@ -721,7 +736,11 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 		return nil, err
 	}
 	// Trace the transaction and return
-	return api.traceTx(ctx, msg, vmctx, statedb, config)
+	result, err := api.traceTx(ctx, msg, vmctx, statedb, config)
+	if err == nil {
+		rpc.MarkCacheable(ctx, block.NumberU64(), block.Hash())
+	}
+	return result, err
 }
 
 // TraceCall lets you trace a given eth_call. It collects the structured logs created during the execution of EVM
@@ -736,7 +755,14 @@ func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs,
 		if hash, ok := blockNrOrHash.Hash(); ok {
 			block = api.eth.blockchain.GetBlockByHash(hash)
 		} else if number, ok := blockNrOrHash.Number(); ok {
-			block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+			switch number {
+			case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+				if block, err = api.eth.APIBackend.BlockByNumber(ctx, number); err != nil {
+					return nil, err
+				}
+			default:
+				block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+			}
 		}
 		if block == nil {
 			return nil, fmt.Errorf("block %v not found: %v", blockNrOrHash, err)
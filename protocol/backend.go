@@ -26,11 +26,13 @@ import (
 	"sync/atomic"
 
 	"github.com/ccm-chain/ccmchain/accounts"
+	"github.com/ccm-chain/ccmchain/accounts/external"
 	"github.com/ccm-chain/ccmchain/common"
 	"github.com/ccm-chain/ccmchain/common/hexutil"
 	"github.com/ccm-chain/ccmchain/consensus"
 	"github.com/ccm-chain/ccmchain/consensus/clique"
 	"github.com/ccm-chain/ccmchain/consensus/ethash"
+	"github.com/ccm-chain/ccmchain/consensus/transition"
 	"github.com/ccm-chain/ccmchain/core"
 	"github.com/ccm-chain/ccmchain/core/bloombits"
 	"github.com/ccm-chain/ccmchain/core/rawdb"
@@ -76,9 +78,13 @@ type Ethereum struct {
 
 	APIBackend *EthAPIBackend
 
-	miner    *miner.Miner
-	gasPrice *big.Int
-	coinbase common.Address
+	rpcCache    *rpc.ResponseCache // Cache of the RPC responses of the node, nil if disabled
+	rpcCacheSub event.Subscription // Subscription invalidating the RPC response cache on reorgs
+
+	miner        *miner.Miner
+	gasPrice     *big.Int
+	coinbase     common.Address
+	cliqueSigner *external.ExternalSigner // Connection to the external clique signer, reused across mining restarts
 
 	networkID     uint64
 	netRPCService *api.PublicNetAPI
@@ -129,7 +135,7 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 		chainDb:           chainDb,
 		eventMux:          stack.EventMux(),
 		accountManager:    stack.AccountManager(),
-		engine:            CreateConsensusEngine(stack, chainConfig, &config.Ethash, config.Miner.Notify, config.Miner.Noverify, chainDb),
+		engine:            CreateConsensusEngine(stack, chainConfig, config, config.Miner.Notify, config.Miner.Noverify, chainDb),
 		closeBloomHandler: make(chan struct{}),
 		networkID:         config.NetworkId,
 		gasPrice:          config.Miner.GasPrice,
@@ -169,6 +175,7 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 			TrieDirtyDisabled:   config.NoPruning,
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
+			ParallelProcessing:  config.ParallelProcessing,
 		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
@@ -186,6 +193,9 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = stack.ResolvePath(config.TxPool.Snapshot)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -197,15 +207,18 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 	if eth.protocolManager, err = NewProtocolManager(chainConfig, checkpoint, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb, cacheLimit, config.Whitelist); err != nil {
 		return nil, err
 	}
+	eth.protocolManager.txLimiter = core.NewTxRateLimiter(config.TxPool.PeerRateLimit)
+
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
-	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), eth, nil}
+	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), eth, nil, core.NewTxRateLimiter(config.TxPool.ClientRateLimit)}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
 	}
 	eth.APIBackend.gpo = gasprice.NewOracle(eth.APIBackend, gpoParams)
+	eth.rpcCache = stack.RPCResponseCache()
 
 	eth.dialCandidates, err = eth.setupDiscovery(&stack.Config().P2P)
 	if err != nil {
@@ -240,13 +253,31 @@ func makeExtraData(extra []byte) []byte {
 }
 
 // CreateConsensusEngine creates the required type of consensus engine instance for an Ethereum service
-func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, config *ethash.Config, notify []string, noverify bool, db database.Database) consensus.Engine {
-	// If proof-of-authority is requested, set it up
+func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, config *Config, notify []string, noverify bool, db database.Database) consensus.Engine {
+	// If proof-of-authority is requested from genesis, set it up
+	if chainConfig.IsClique(common.Big0) {
+		return createCliqueEngine(chainConfig, config, db)
+	}
+	// Otherwise assume proof-of-work, transitioning to proof-of-authority if requested
+	engine := createEthashEngine(stack, config, notify, noverify)
 	if chainConfig.Clique != nil {
-		return clique.New(chainConfig.Clique, db)
+		log.Info("Transitioning from ethash to clique", "block", chainConfig.CliqueBlock)
+		return transition.New(engine, createCliqueEngine(chainConfig, config, db), chainConfig.CliqueBlock.Uint64())
 	}
-	// Otherwise assume proof-of-work
-	switch config.PowMode {
+	return engine
+}
+
+// createCliqueEngine creates a proof-of-authority consensus engine.
+func createCliqueEngine(chainConfig *params.ChainConfig, config *Config, db database.Database) *clique.Clique {
+	engine := clique.New(chainConfig.Clique, db)
+	engine.SetFinality(config.CliqueSafeSigners, config.CliqueFinalizedSigners)
+	engine.SetSnapshotRetention(config.CliqueSnapshotRetention)
+	return engine
+}
+
+// createEthashEngine creates a proof-of-work consensus engine.
+func createEthashEngine(stack *node.Node, config *Config, notify []string, noverify bool) consensus.Engine {
+	switch config.Ethash.PowMode {
 	case ethash.ModeFake:
 		log.Warn("Ethash used in fake mode")
 		return ethash.NewFaker()
@@ -258,20 +289,32 @@ func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, co
 		return ethash.NewShared()
 	default:
 		engine := ethash.New(ethash.Config{
-			CacheDir:         stack.ResolvePath(config.CacheDir),
-			CachesInMem:      config.CachesInMem,
-			CachesOnDisk:     config.CachesOnDisk,
-			CachesLockMmap:   config.CachesLockMmap,
-			DatasetDir:       config.DatasetDir,
-			DatasetsInMem:    config.DatasetsInMem,
-			DatasetsOnDisk:   config.DatasetsOnDisk,
-			DatasetsLockMmap: config.DatasetsLockMmap,
+			CacheDir:         stack.ResolvePath(config.Ethash.CacheDir),
+			CachesInMem:      config.Ethash.CachesInMem,
+			CachesOnDisk:     config.Ethash.CachesOnDisk,
+			CachesLockMmap:   config.Ethash.CachesLockMmap,
+			DatasetDir:       config.Ethash.DatasetDir,
+			DatasetsInMem:    config.Ethash.DatasetsInMem,
+			DatasetsOnDisk:   config.Ethash.DatasetsOnDisk,
+			DatasetsLockMmap: config.Ethash.DatasetsLockMmap,
+			SafeDepth:        config.Ethash.SafeDepth,
+			FinalizedDepth:   config.Ethash.FinalizedDepth,
 		}, notify, noverify)
 		engine.SetThreads(-1) // Disable CPU mining
 		return engine
 	}
 }
 
+// cliqueEngine returns the clique consensus engine if the chain is sealed with
+// proof-of-authority, either from genesis or after transitioning to it.
+func cliqueEngine(engine consensus.Engine) (*clique.Clique, bool) {
+	if t, ok := engine.(*transition.Engine); ok {
+		_, engine = t.Engines()
+	}
+	c, ok := engine.(*clique.Clique)
+	return c, ok
+}
+
 // APIs return the collection of RPC services the ccmchain package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *Ethereum) APIs() []rpc.API {
@@ -404,7 +447,7 @@ func (s *Ethereum) shouldPreserve(block *types.Block) bool {
 	// is A, F and G sign the block of round5 and reject the block of opponents
 	// and in the round6, the last available signer B is offline, the whole
 	// network is stuck.
-	if _, ok := s.engine.(*clique.Clique); ok {
+	if _, ok := cliqueEngine(s.engine); ok {
 		return false
 	}
 	return s.isLocalBlock(block)
@@ -448,13 +491,13 @@ func (s *Ethereum) StartMining(threads int) error {
 			log.Error("Cannot start mining without coinbase", "err", err)
 			return fmt.Errorf("coinbase missing: %v", err)
 		}
-		if clique, ok := s.engine.(*clique.Clique); ok {
-			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-			if wallet == nil || err != nil {
-				log.Error("Coinbase account unavailable locally", "err", err)
+		if clique, ok := cliqueEngine(s.engine); ok {
+			signFn, err := s.cliqueSignFn(eb)
+			if err != nil {
+				log.Error("Coinbase account unavailable for sealing", "err", err)
 				return fmt.Errorf("signer missing: %v", err)
 			}
-			clique.Authorize(eb, wallet.SignData)
+			clique.Authorize(eb, signFn)
 		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
@@ -465,6 +508,36 @@ func (s *Ethereum) StartMining(threads int) error {
 	return nil
 }
 
+// cliqueSignFn returns the function sealing clique blocks on behalf of signer.
+// If an external clique signer is configured the sealing key never resides on
+// the node, otherwise the account needs to be available in a local wallet.
+func (s *Ethereum) cliqueSignFn(signer common.Address) (clique.SignerFn, error) {
+	account := accounts.Account{Address: signer}
+	if s.config.CliqueSigner == "" {
+		wallet, err := s.accountManager.Find(account)
+		if wallet == nil || err != nil {
+			return nil, err
+		}
+		return wallet.SignData, nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.cliqueSigner == nil {
+		extsigner, err := external.NewExternalSigner(s.config.CliqueSigner)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to clique signer: %v", err)
+		}
+		s.cliqueSigner = extsigner
+	}
+	extsigner := s.cliqueSigner
+	if !extsigner.Contains(account) {
+		return nil, fmt.Errorf("account %x not available in clique signer", signer)
+	}
+	log.Info("Sealing with external clique signer", "url", s.config.CliqueSigner, "signer", signer)
+	return extsigner.SignData, nil
+}
+
 // StopMining terminates the miner, both at the consensus engine level as well as
 // at the block creation level.
 func (s *Ethereum) StopMining() {
@@ -526,6 +599,11 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(maxPeers)
+
+	// Keep the RPC response cache consistent with the chain
+	if s.rpcCache != nil {
+		s.rpcCacheSub = api.InvalidateResponseCache(s.APIBackend, s.rpcCache)
+	}
 	return nil
 }
 
@@ -536,10 +614,16 @@ func (s *Ethereum) Stop() error {
 	s.protocolManager.Stop()
 
 	// Then stop everything else.
+	if s.rpcCacheSub != nil {
+		s.rpcCacheSub.Unsubscribe()
+	}
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Stop()
+	if s.cliqueSigner != nil {
+		s.cliqueSigner.Close()
+	}
 	s.blockchain.Stop()
 	s.engine.Close()
 	s.chainDb.Close()
@@ -120,6 +120,8 @@ type Config struct {
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
+	ParallelProcessing int `toml:",omitempty"` // Number of threads executing block transactions optimistically in parallel (0 = sequential)
+
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
 	// Whitelist of required block number -> hash values to accept
@@ -156,6 +158,20 @@ type Config struct {
 	// Ethash options
 	Ethash ethash.Config
 
+	// Clique finality options, in distinct signers sealing on top of a block
+	// before it is considered safe or finalized (0 = engine defaults)
+	CliqueSafeSigners      int `toml:",omitempty"`
+	CliqueFinalizedSigners int `toml:",omitempty"`
+
+	// CliqueSigner is the endpoint of an external signer (clef, or a remote signer
+	// speaking its account_signData API) holding the clique sealing key. If empty,
+	// blocks are sealed with an unlocked account of the local account manager.
+	CliqueSigner string `toml:",omitempty"`
+
+	// CliqueSnapshotRetention is the depth below the latest checkpoint beyond which
+	// persisted clique vote snapshots are pruned (0 = keep all).
+	CliqueSnapshotRetention uint64 `toml:",omitempty"`
+
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
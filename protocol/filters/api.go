@@ -28,6 +28,7 @@ import (
 	"github.com/ccm-chain/ccmchain"
 	"github.com/ccm-chain/ccmchain/common"
 	"github.com/ccm-chain/ccmchain/common/hexutil"
+	"github.com/ccm-chain/ccmchain/core"
 	"github.com/ccm-chain/ccmchain/core/types"
 	"github.com/ccm-chain/ccmchain/database"
 	"github.com/ccm-chain/ccmchain/event"
@@ -168,6 +169,53 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// DroppedTransaction is the notification sent for a transaction dropped from the
+// transaction pool without being included in a block.
+type DroppedTransaction struct {
+	Hash        common.Hash       `json:"hash"`
+	Reason      core.TxDropReason `json:"reason"`
+	Replacement *common.Hash      `json:"replacement,omitempty"`
+}
+
+// NewDroppedTransactions creates a subscription that is triggered each time a
+// transaction is dropped from the transaction pool, carrying the reason it was
+// dropped for and the hash of the transaction replacing it, if any.
+func (api *PublicFilterAPI) NewDroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan []*core.DroppedTx, 128)
+		droppedTxSub := api.events.SubscribeDroppedTxs(drops)
+
+		for {
+			select {
+			case txs := <-drops:
+				for _, tx := range txs {
+					dropped := &DroppedTransaction{Hash: tx.Hash, Reason: tx.Reason}
+					if tx.Replacement != (common.Hash{}) {
+						replacement := tx.Replacement
+						dropped.Replacement = &replacement
+					}
+					notifier.Notify(rpcSub.ID, dropped)
+				}
+			case <-rpcSub.Err():
+				droppedTxSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				droppedTxSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with ccm_getFilterChanges.
 //
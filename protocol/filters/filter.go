@@ -38,6 +38,7 @@ type Backend interface {
 	GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
 
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeDroppedTxsEvent(chan<- core.DroppedTxsEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
@@ -142,6 +143,21 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
+	// Resolve the safe and finalized tags through the backend
+	if f.begin == rpc.SafeBlockNumber.Int64() || f.begin == rpc.FinalizedBlockNumber.Int64() {
+		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
+		if header == nil || err != nil {
+			return nil, err
+		}
+		f.begin = header.Number.Int64()
+	}
+	if f.end == rpc.SafeBlockNumber.Int64() || f.end == rpc.FinalizedBlockNumber.Int64() {
+		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.end))
+		if header == nil || err != nil {
+			return nil, err
+		}
+		end = header.Number.Uint64()
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
@@ -43,6 +43,7 @@ type testBackend struct {
 	db              database.Database
 	sections        uint64
 	txFeed          event.Feed
+	dropFeed        event.Feed
 	logsFeed        event.Feed
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
@@ -105,6 +106,10 @@ func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Sub
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeDroppedTxsEvent(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	return b.dropFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -264,6 +269,37 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestDroppedTxSubscription tests whether dropped transaction subscriptions
+// retrieve all the drops posted by the transaction pool.
+func TestDroppedTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false)
+
+		drops = []*core.DroppedTx{
+			{Hash: common.HexToHash("0x01"), Reason: core.TxDropReplaced, Replacement: common.HexToHash("0x02")},
+			{Hash: common.HexToHash("0x03"), Reason: core.TxDropNonceTooLow},
+		}
+	)
+	ch := make(chan []*core.DroppedTx)
+	sub := api.events.SubscribeDroppedTxs(ch)
+	defer sub.Unsubscribe()
+
+	backend.dropFeed.Send(core.DroppedTxsEvent{Txs: drops})
+
+	select {
+	case have := <-ch:
+		if !reflect.DeepEqual(have, drops) {
+			t.Errorf("dropped transactions mismatch: have %v, want %v", have, drops)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for dropped transactions")
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -23,6 +23,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DiscoveryURLs           []string
 		NoPruning               bool
 		NoPrefetch              bool
+		ParallelProcessing      int                    `toml:",omitempty"`
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
@@ -45,6 +46,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SnapshotCache           int
 		Miner                   miner.Config
 		Ethash                  ethash.Config
+		CliqueSafeSigners       int    `toml:",omitempty"`
+		CliqueFinalizedSigners  int    `toml:",omitempty"`
+		CliqueSigner            string `toml:",omitempty"`
+		CliqueSnapshotRetention uint64 `toml:",omitempty"`
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
@@ -63,6 +68,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DiscoveryURLs = c.DiscoveryURLs
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.ParallelProcessing = c.ParallelProcessing
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.Whitelist = c.Whitelist
 	enc.LightServ = c.LightServ
@@ -85,6 +91,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
+	enc.CliqueSafeSigners = c.CliqueSafeSigners
+	enc.CliqueFinalizedSigners = c.CliqueFinalizedSigners
+	enc.CliqueSigner = c.CliqueSigner
+	enc.CliqueSnapshotRetention = c.CliqueSnapshotRetention
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
@@ -107,6 +117,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DiscoveryURLs           []string
 		NoPruning               *bool
 		NoPrefetch              *bool
+		ParallelProcessing      *int                   `toml:",omitempty"`
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
@@ -129,6 +140,10 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SnapshotCache           *int
 		Miner                   *miner.Config
 		Ethash                  *ethash.Config
+		CliqueSafeSigners       *int    `toml:",omitempty"`
+		CliqueFinalizedSigners  *int    `toml:",omitempty"`
+		CliqueSigner            *string `toml:",omitempty"`
+		CliqueSnapshotRetention *uint64 `toml:",omitempty"`
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
@@ -162,6 +177,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.ParallelProcessing != nil {
+		c.ParallelProcessing = *dec.ParallelProcessing
+	}
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
@@ -228,6 +246,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
+	if dec.CliqueSafeSigners != nil {
+		c.CliqueSafeSigners = *dec.CliqueSafeSigners
+	}
+	if dec.CliqueFinalizedSigners != nil {
+		c.CliqueFinalizedSigners = *dec.CliqueFinalizedSigners
+	}
+	if dec.CliqueSigner != nil {
+		c.CliqueSigner = *dec.CliqueSigner
+	}
+	if dec.CliqueSnapshotRetention != nil {
+		c.CliqueSnapshotRetention = *dec.CliqueSnapshotRetention
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
@@ -34,6 +34,7 @@ import (
 	"github.com/ccm-chain/ccmchain/database"
 	"github.com/ccm-chain/ccmchain/event"
 	"github.com/ccm-chain/ccmchain/log"
+	"github.com/ccm-chain/ccmchain/metrics"
 	"github.com/ccm-chain/ccmchain/p2p"
 	"github.com/ccm-chain/ccmchain/p2p/enode"
 	"github.com/ccm-chain/ccmchain/params"
@@ -54,6 +55,10 @@ const (
 
 var (
 	syncChallengeTimeout = 15 * time.Second // Time allowance for a node to reply to the sync progress challenge
+
+	// peerTxRateLimitMeter counts the transactions dropped because the peer that
+	// sent them exceeded its admission rate.
+	peerTxRateLimitMeter = metrics.NewRegisteredMeter("txpool/ratelimit/peer", nil)
 )
 
 func errResp(code errCode, format string, v ...interface{}) error {
@@ -78,6 +83,7 @@ type ProtocolManager struct {
 	downloader   *downloader.Downloader
 	blockFetcher *fetcher.BlockFetcher
 	txFetcher    *fetcher.TxFetcher
+	txLimiter    *core.TxRateLimiter // Admission rate limiter of transactions sent by peers
 	peers        *peerSet
 
 	eventMux      *event.TypeMux
@@ -755,6 +761,15 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		for _, hash := range hashes {
 			p.MarkTransaction(hash)
 		}
+		// Drop the announcements exceeding the allowance of the peer, as the
+		// transactions requested for them are delivered regardless of it
+		admitted, err := pm.admitTxs(p, len(hashes))
+		if err != nil {
+			return err
+		}
+		if hashes = hashes[:admitted]; len(hashes) == 0 {
+			break
+		}
 		pm.txFetcher.Notify(p.id, hashes)
 
 	case msg.Code == GetPooledTransactionsMsg && p.version >= eth65:
@@ -810,6 +825,18 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
+		// Drop the broadcast transactions exceeding the allowance of the peer. Pooled
+		// transactions are replies to requests of our own fetcher, which were already
+		// charged when announced, so they are always delivered.
+		if msg.Code == TransactionMsg {
+			admitted, err := pm.admitTxs(p, len(txs))
+			if err != nil {
+				return err
+			}
+			if txs = txs[:admitted]; len(txs) == 0 {
+				break
+			}
+		}
 		pm.txFetcher.Enqueue(p.id, txs, msg.Code == PooledTransactionsMsg)
 
 	default:
@@ -818,6 +845,23 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// admitTxs charges the given number of broadcast or announced transactions to
+// the allowance of a peer, returning the number admitted. The peer is penalized
+// for every message exceeding its allowance, and an error is returned to
+// disconnect it if it floods us persistently.
+func (pm *ProtocolManager) admitTxs(p *peer, n int) (int, error) {
+	admitted := pm.txLimiter.Admit(p.id, n)
+	if admitted == n {
+		p.forgive()
+		return n, nil
+	}
+	peerTxRateLimitMeter.Mark(int64(n - admitted))
+	if p.penalize() {
+		return 0, errResp(ErrTxRateLimit, "%d transactions", n-admitted)
+	}
+	return admitted, nil
+}
+
 // BroadcastBlock will either propagate a block to a subset of its peers, or
 // will only announce its availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ccm-chain/ccmchain/common"
@@ -60,6 +61,10 @@ const (
 	maxQueuedBlockAnns = 4
 
 	handshakeTimeout = 5 * time.Second
+
+	// maxPeerPenalties is the number of outstanding penalties after which a peer
+	// is disconnected for misbehaving.
+	maxPeerPenalties = 16
 )
 
 // max is a helper function which returns the larger of the two given integers.
@@ -76,6 +81,7 @@ type PeerInfo struct {
 	Version    int      `json:"version"`    // Ethereum protocol version negotiated
 	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
 	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	Penalties  int      `json:"penalties"`  // Number of outstanding penalties for misbehaving
 }
 
 // propEvent is a block propagation, waiting for its turn in the broadcast queue.
@@ -106,6 +112,8 @@ type peer struct {
 	txAnnounce  chan []common.Hash                   // Channel used to queue transaction announcement requests
 	getPooledTx func(common.Hash) *types.Transaction // Callback used to retrieve transaction from txpool
 
+	penalties int32 // Number of outstanding penalties, the peer is dropped above maxPeerPenalties (atomic)
+
 	term chan struct{} // Termination channel to stop the broadcaster
 }
 
@@ -291,6 +299,24 @@ func (p *peer) Info() *PeerInfo {
 		Version:    p.version,
 		Difficulty: td,
 		Head:       hash.Hex(),
+		Penalties:  int(atomic.LoadInt32(&p.penalties)),
+	}
+}
+
+// penalize records a penalty for misbehaving and reports whether the peer has
+// accumulated enough outstanding penalties to be disconnected.
+func (p *peer) penalize() bool {
+	return atomic.AddInt32(&p.penalties, 1) > maxPeerPenalties
+}
+
+// forgive revokes one of the outstanding penalties of the peer, if any, for
+// behaving well.
+func (p *peer) forgive() {
+	for {
+		penalties := atomic.LoadInt32(&p.penalties)
+		if penalties == 0 || atomic.CompareAndSwapInt32(&p.penalties, penalties, penalties-1) {
+			return
+		}
 	}
 }
 
@@ -83,6 +83,7 @@ const (
 	ErrForkIDRejected
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
+	ErrTxRateLimit
 )
 
 func (e errCode) String() string {
@@ -100,6 +101,7 @@ var errorToString = map[int]string{
 	ErrForkIDRejected:          "Fork ID rejected",
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
+	ErrTxRateLimit:             "Transaction rate limit exceeded",
 }
 
 type txPool interface {
@@ -19,6 +19,7 @@ package protocol
 import (
 	"fmt"
 	"math/big"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -273,6 +274,124 @@ func testRecvTransactions(t *testing.T, protocol int) {
 	}
 }
 
+// This test checks that transactions received above the per peer rate limit are
+// dropped, and that peers persistently exceeding it are disconnected.
+func TestRecvTransactionsRateLimit(t *testing.T) {
+	txAdded := make(chan []*types.Transaction, maxPeerPenalties+2)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	pm.txLimiter = core.NewTxRateLimiter(0.1)
+	p, errc := newTestPeer("peer", 65, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	// The first transaction fits into the allowance of the peer
+	if err := p2p.Send(p.app, TransactionMsg, []interface{}{newTestTransaction(testAccount, 0, 0)}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case <-txAdded:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no NewTxsEvent received within 2 seconds")
+	}
+	// Any further ones should be dropped until the peer is disconnected
+	for nonce := uint64(1); nonce <= maxPeerPenalties+1; nonce++ {
+		if err := p2p.Send(p.app, TransactionMsg, []interface{}{newTestTransaction(testAccount, nonce, 0)}); err != nil {
+			t.Fatalf("send %d error: %v", nonce, err)
+		}
+	}
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), errCode(ErrTxRateLimit).String()) {
+			t.Fatalf("disconnect error mismatch: have %v, want %v", err, errCode(ErrTxRateLimit))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("flooding peer not disconnected")
+	}
+	select {
+	case added := <-txAdded:
+		t.Fatalf("rate limited transactions added: %v", added)
+	default:
+	}
+}
+
+// This test checks that broadcast batches exceeding the allowance of a peer are
+// admitted partially without disconnecting it, and that transactions requested
+// by the fetcher are delivered regardless of the allowance.
+func TestRecvTransactionsRateLimitBatch(t *testing.T) {
+	txAdded := make(chan []*types.Transaction, 4)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	pm.txLimiter = core.NewTxRateLimiter(0.1)
+	p, errc := newTestPeer("peer", 65, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	// A batch larger than the burst allowance is admitted up to the allowance
+	batch := []*types.Transaction{
+		newTestTransaction(testAccount, 0, 0),
+		newTestTransaction(testAccount, 1, 0),
+		newTestTransaction(testAccount, 2, 0),
+	}
+	if err := p2p.Send(p.app, TransactionMsg, batch); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != batch[0].Hash() {
+			t.Fatalf("admitted transactions mismatch: have %v, want %x", added, batch[0].Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no NewTxsEvent received within 2 seconds")
+	}
+	// Pooled transactions are delivered even though the allowance is used up
+	if err := p2p.Send(p.app, PooledTransactionsMsg, batch[1:2]); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != batch[1].Hash() {
+			t.Fatalf("pooled transactions mismatch: have %v, want %x", added, batch[1].Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no NewTxsEvent received within 2 seconds")
+	}
+	select {
+	case err := <-errc:
+		t.Fatalf("peer disconnected: %v", err)
+	default:
+	}
+}
+
+// This test checks that eth65 peers can't bypass their allowance by announcing
+// transactions instead of broadcasting them, as the transactions retrieved for
+// the announcements are delivered regardless of it.
+func TestRecvTransactionAnnouncementsRateLimit(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	pm.txLimiter = core.NewTxRateLimiter(0.1)
+	p, errc := newTestPeer("peer", 65, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	// The first announcement fits into the allowance of the peer, any further
+	// ones should be dropped until the peer is disconnected
+	for nonce := uint64(0); nonce <= maxPeerPenalties+1; nonce++ {
+		hashes := []common.Hash{newTestTransaction(testAccount, nonce, 0).Hash()}
+		if err := p2p.Send(p.app, NewPooledTransactionHashesMsg, hashes); err != nil {
+			t.Fatalf("send %d error: %v", nonce, err)
+		}
+	}
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), errCode(ErrTxRateLimit).String()) {
+			t.Fatalf("disconnect error mismatch: have %v, want %v", err, errCode(ErrTxRateLimit))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("flooding peer not disconnected")
+	}
+}
+
 // This test checks that pending transactions are sent.
 func TestSendTransactions63(t *testing.T) { testSendTransactions(t, 63) }
 func TestSendTransactions64(t *testing.T) { testSendTransactions(t, 64) }
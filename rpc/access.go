@@ -0,0 +1,200 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/time/rate"
+)
+
+// clientRateLimiterCache is the maximum number of client addresses whose call
+// rate is tracked at the same time. The least recently active ones are forgotten.
+const clientRateLimiterCache = 4096
+
+// MethodRules restricts the methods which may be called. Methods are matched by
+// their full name (e.g. "debug_traceTransaction"), by namespace ("debug_*") or
+// with "*" matching all of them.
+type MethodRules struct {
+	Allow []string `json:"allow,omitempty"` // Methods allowed to be called, all if empty
+	Deny  []string `json:"deny,omitempty"`  // Methods denied, even if allowed
+}
+
+// allowed reports whether the rules allow calling the given method.
+func (r *MethodRules) allowed(method string) bool {
+	if len(r.Allow) > 0 && !matchMethod(r.Allow, method) {
+		return false
+	}
+	return !matchMethod(r.Deny, method)
+}
+
+// matchMethod reports whether a method matches any of the given patterns.
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		switch {
+		case pattern == "*" || pattern == method:
+			return true
+		case strings.HasSuffix(pattern, serviceMethodSeparator+"*") && strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimit is the configuration of a token bucket rate limiter.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`            // Number of calls allowed per second
+	Burst int     `json:"burst,omitempty"` // Number of calls allowed in a burst, one second worth if zero
+}
+
+// limiter creates a token bucket limiter enforcing the rate limit.
+func (l *RateLimit) limiter() *rate.Limiter {
+	burst := l.Burst
+	if burst == 0 {
+		burst = int(math.Max(1, math.Ceil(l.Rate)))
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), burst)
+}
+
+// AccessPolicy restricts the methods callable on a server and the rate at which
+// they may be called. Calls rejected by the policy fail with dedicated error
+// codes, without being executed.
+type AccessPolicy struct {
+	// MethodRules restricts the methods callable by unauthenticated callers and
+	// by authenticated identities without rules of their own.
+	MethodRules
+
+	// Identities restricts the methods callable by authenticated identities,
+	// replacing the default rules.
+	Identities map[string]*MethodRules `json:"identities,omitempty"`
+
+	// ClientRate limits the calls of each client, identified by its IP address.
+	ClientRate *RateLimit `json:"clientRate,omitempty"`
+
+	// MethodRates limits the calls of individual methods, by all clients.
+	MethodRates map[string]*RateLimit `json:"methodRates,omitempty"`
+}
+
+// validate checks the consistency of the policy.
+func (p *AccessPolicy) validate() error {
+	if p.ClientRate != nil && (p.ClientRate.Rate <= 0 || p.ClientRate.Burst < 0) {
+		return fmt.Errorf("invalid client rate limit %v/s, burst %d", p.ClientRate.Rate, p.ClientRate.Burst)
+	}
+	for method, limit := range p.MethodRates {
+		if limit == nil || limit.Rate <= 0 || limit.Burst < 0 {
+			return fmt.Errorf("invalid rate limit for method %s", method)
+		}
+	}
+	return nil
+}
+
+// accessControl enforces an access policy on the calls served by a server.
+type accessControl struct {
+	policy *AccessPolicy
+
+	clients *lru.Cache               // Token buckets of the recently active clients
+	methods map[string]*rate.Limiter // Token buckets of the rate limited methods
+	lock    sync.Mutex               // Lock protecting the creation of client buckets
+}
+
+// newAccessControl creates the enforcer of an access policy.
+func newAccessControl(policy *AccessPolicy) (*accessControl, error) {
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	ac := &accessControl{
+		policy:  policy,
+		methods: make(map[string]*rate.Limiter),
+	}
+	if policy.ClientRate != nil {
+		ac.clients, _ = lru.New(clientRateLimiterCache)
+	}
+	for method, limit := range policy.MethodRates {
+		ac.methods[method] = limit.limiter()
+	}
+	return ac, nil
+}
+
+// check verifies that the caller in the context may call the given method,
+// consuming its rate allowance if so. A nil access control allows everything.
+func (ac *accessControl) check(ctx context.Context, method string) error {
+	if ac == nil {
+		return nil
+	}
+	rules := &ac.policy.MethodRules
+	if identity, ok := IdentityFromContext(ctx); ok {
+		if identityRules, ok := ac.policy.Identities[identity]; ok {
+			rules = identityRules
+		}
+	}
+	if !rules.allowed(method) {
+		rpcDeniedMeter.Mark(1)
+		newRPCRejectionMeter(method, "denied").Mark(1)
+		return &accessDeniedError{method: method}
+	}
+	if ac.clients != nil && !ac.clientLimiter(ctx).Allow() {
+		rpcRateLimitedMeter.Mark(1)
+		newRPCRejectionMeter(method, "ratelimited").Mark(1)
+		return &rateLimitedError{method: method}
+	}
+	if limiter := ac.methods[method]; limiter != nil && !limiter.Allow() {
+		rpcRateLimitedMeter.Mark(1)
+		newRPCRejectionMeter(method, "ratelimited").Mark(1)
+		return &rateLimitedError{method: method}
+	}
+	return nil
+}
+
+// clientLimiter retrieves the token bucket of the client calling in the context,
+// creating it if it's not tracked yet.
+func (ac *accessControl) clientLimiter(ctx context.Context) *rate.Limiter {
+	remote, _ := ctx.Value("remote").(string)
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+
+	if limiter, ok := ac.clients.Get(remote); ok {
+		return limiter.(*rate.Limiter)
+	}
+	limiter := ac.policy.ClientRate.limiter()
+	ac.clients.Add(remote, limiter)
+	return limiter
+}
+
+// identityKey is the context key of the authenticated identity of the caller.
+type identityKey struct{}
+
+// WithIdentity returns a copy of the context carrying the authenticated identity
+// of the caller, whose calls are subject to the rules of that identity.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext retrieves the authenticated identity of the caller from
+// the context, if any.
+func IdentityFromContext(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(identityKey{}).(string)
+	return identity, ok
+}
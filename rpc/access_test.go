@@ -0,0 +1,155 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// errorCode returns the JSON-RPC error code of a call error, or 0 if the call
+// succeeded.
+func errorCode(t *testing.T, err error) int {
+	t.Helper()
+
+	if err == nil {
+		return 0
+	}
+	rpcErr, ok := err.(Error)
+	if !ok {
+		t.Fatalf("unexpected error type %T: %v", err, err)
+	}
+	return rpcErr.ErrorCode()
+}
+
+// Tests that the methods callable on a server are restricted by its access
+// policy, depending on the identity of the caller.
+func TestAccessPolicy(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	err := server.SetAccessPolicy(&AccessPolicy{
+		MethodRules: MethodRules{
+			Allow: []string{"test_*", "rpc_modules"},
+			Deny:  []string{"test_returnError"},
+		},
+		Identities: map[string]*MethodRules{
+			"admin":    {},
+			"readonly": {Allow: []string{"rpc_modules"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to set access policy: %v", err)
+	}
+	// Serve the identity named by a header, standing in for authentication
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if identity := r.Header.Get("identity"); identity != "" {
+				r = r.WithContext(WithIdentity(r.Context(), identity))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	httpsrv := httptest.NewServer(authenticate(server))
+	defer httpsrv.Close()
+	wssrv := httptest.NewServer(authenticate(server.WebsocketHandler([]string{"*"})))
+	defer wssrv.Close()
+
+	tests := []struct {
+		identity string
+		method   string
+		code     int
+	}{
+		{"", "rpc_modules", 0},
+		{"", "test_echo", 0},
+		{"", "test_returnError", -32004},
+		{"", "nftest_subscribe", -32004},
+		{"unknown", "test_echo", 0},
+		{"admin", "test_returnError", 444},
+		{"admin", "nftest_subscribe", 0},
+		{"readonly", "rpc_modules", 0},
+		{"readonly", "test_echo", -32004},
+	}
+	for _, url := range []string{httpsrv.URL, "ws" + strings.TrimPrefix(wssrv.URL, "http")} {
+		for i, tt := range tests {
+			client, err := DialOptions(context.Background(), url, WithHeader("identity", tt.identity))
+			if err != nil {
+				t.Fatalf("%s: failed to dial: %v", url, err)
+			}
+			if tt.method == "nftest_subscribe" {
+				// Subscriptions are only supported over WebSocket
+				if strings.HasPrefix(url, "ws") {
+					sub, err := client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 1)
+					if err == nil {
+						sub.Unsubscribe()
+					}
+					if have := errorCode(t, err); have != tt.code {
+						t.Errorf("%s: test %d: error code mismatch: have %d, want %d (%v)", url, i, have, tt.code, err)
+					}
+				}
+			} else {
+				var args []interface{}
+				if tt.method == "test_echo" {
+					args = []interface{}{"x", 1}
+				}
+				err := client.Call(nil, tt.method, args...)
+				if have := errorCode(t, err); have != tt.code {
+					t.Errorf("%s: test %d: error code mismatch: have %d, want %d (%v)", url, i, have, tt.code, err)
+				}
+			}
+			client.Close()
+		}
+	}
+}
+
+// Tests that the calls of clients and to individual methods are rate limited.
+func TestAccessRateLimits(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	err := server.SetAccessPolicy(&AccessPolicy{
+		ClientRate:  &RateLimit{Rate: 0.001, Burst: 3},
+		MethodRates: map[string]*RateLimit{"test_rets": {Rate: 0.001, Burst: 1}},
+	})
+	if err != nil {
+		t.Fatalf("failed to set access policy: %v", err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	// The first call of the method exhausts its allowance, the second one is
+	// rejected while consuming the allowance of the client
+	if err := client.Call(nil, "test_rets"); err != nil {
+		t.Fatalf("first method call failed: %v", err)
+	}
+	if code := errorCode(t, client.Call(nil, "test_rets")); code != -32005 {
+		t.Fatalf("method rate limit error code mismatch: have %d, want %d", code, -32005)
+	}
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("last allowed client call failed: %v", err)
+	}
+	if code := errorCode(t, client.Call(nil, "test_noArgsRets")); code != -32005 {
+		t.Fatalf("client rate limit error code mismatch: have %d, want %d", code, -32005)
+	}
+	// Invalid limits are refused
+	if err := server.SetAccessPolicy(&AccessPolicy{ClientRate: &RateLimit{}}); err == nil {
+		t.Fatalf("invalid client rate limit accepted")
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/ccm-chain/ccmchain/common"
+	"github.com/ccm-chain/ccmchain/metrics"
+)
+
+// cacheEntryOverhead is the approximate memory used by a cached response on top
+// of its key and result.
+const cacheEntryOverhead = 128
+
+var (
+	rpcCacheHitMeter  = metrics.NewRegisteredMeter("rpc/cache/hit", nil)
+	rpcCacheMissMeter = metrics.NewRegisteredMeter("rpc/cache/miss", nil)
+	rpcCacheSizeGauge = metrics.NewRegisteredGauge("rpc/cache/size", nil)
+)
+
+// ResponseCache is a memory bounded cache of the results of method calls which
+// never change unless the chain is reorganized, such as the retrieval of blocks
+// and receipts. Methods declare their results cacheable with MarkCacheable, and
+// the results are cached per method and parameters until the block they derive
+// from is reorganized out of the canonical chain, or until they get evicted to
+// make room for more recently used ones.
+//
+// Cached responses are checked against the canonical chain set with SetCanonical
+// before being cached and served, so responses derived from side chain blocks are
+// never cached, and cached ones are never served once their block was replaced,
+// even before the cache gets invalidated.
+//
+// A cache may be shared by multiple servers.
+type ResponseCache struct {
+	maxSize int // Maximum memory used by the cached responses
+
+	entries   map[string]*list.Element        // Cached responses by method and parameters
+	lru       *list.List                      // Cached responses, most recently used first
+	size      int                             // Memory used by the cached responses
+	gen       uint64                          // Number of invalidations, to drop results computed across them
+	canonical func(number uint64) common.Hash // Canonical hash of a block number, nil if unchecked
+	lock      sync.Mutex
+}
+
+// cacheEntry is a cached response.
+type cacheEntry struct {
+	key    string
+	result json.RawMessage
+	number uint64      // Block the result derives from
+	hash   common.Hash // Hash of the block the result derives from
+}
+
+// size returns the approximate memory used by the entry.
+func (e *cacheEntry) size() int {
+	return len(e.key) + len(e.result) + cacheEntryOverhead
+}
+
+// NewResponseCache creates a response cache using up to maxSize bytes of memory.
+func NewResponseCache(maxSize int) *ResponseCache {
+	return &ResponseCache{
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Len returns the number of cached responses.
+func (c *ResponseCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.lru.Len()
+}
+
+// SetCanonical sets the function resolving the hash of the canonical block with
+// a given number, or the zero hash if unknown. Cached responses are only served
+// while the block they derive from is canonical.
+func (c *ResponseCache) SetCanonical(canonical func(number uint64) common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.canonical = canonical
+}
+
+// Invalidate drops the cached responses derived from the block with the given
+// hash, after it was reorganized out of the canonical chain. The descendants of
+// the block are invalidated on their own, as they were reorganized out too.
+func (c *ResponseCache) Invalidate(hash common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if entry := elem.Value.(*cacheEntry); entry.hash == hash {
+			c.remove(elem)
+		}
+		elem = next
+	}
+	rpcCacheSizeGauge.Update(int64(c.size))
+}
+
+// get retrieves the cached result of a method call. If the block the result
+// derives from is no longer canonical, the result is dropped instead.
+func (c *ResponseCache) get(key string) (json.RawMessage, bool) {
+	c.lock.Lock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.lock.Unlock()
+		return nil, false
+	}
+	entry, canonical := elem.Value.(*cacheEntry), c.canonical
+	c.lock.Unlock()
+
+	// Check the block outside the lock, as it may hit the database
+	if canonical != nil && canonical(entry.number) != entry.hash {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if c.entries[key] == elem {
+			c.remove(elem)
+			rpcCacheSizeGauge.Update(int64(c.size))
+		}
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.lru.MoveToFront(elem) // No-op if evicted meanwhile
+	rpcCacheHitMeter.Mark(1)
+	return entry.result, true
+}
+
+// generation returns the number of invalidations so far, which has to be passed
+// to add when caching a result computed after the call.
+func (c *ResponseCache) generation() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.gen
+}
+
+// add caches the result of a method call derived from the given block. Results
+// derived from blocks which aren't canonical, or computed while the cache got
+// invalidated, are dropped, as they may derive from a side chain block.
+func (c *ResponseCache) add(key string, result json.RawMessage, number uint64, hash common.Hash, gen uint64) {
+	rpcCacheMissMeter.Mark(1)
+
+	c.lock.Lock()
+	canonical := c.canonical
+	c.lock.Unlock()
+
+	// Check the block outside the lock, as it may hit the database
+	if canonical != nil && canonical(number) != hash {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	entry := &cacheEntry{key: key, result: result, number: number, hash: hash}
+	if entry.size() > c.maxSize {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	for c.size+entry.size() > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += entry.size()
+	rpcCacheSizeGauge.Update(int64(c.size))
+}
+
+// remove drops a cached response.
+func (c *ResponseCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size()
+}
+
+// cacheKey returns the cache key of a method call, ignoring the formatting of
+// its parameters.
+func cacheKey(method string, params json.RawMessage) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, params); err == nil {
+		params = compact.Bytes()
+	}
+	return method + "\x00" + string(params)
+}
+
+// cacheableKey is the context key of the cacheability of a call's result.
+type cacheableKey struct{}
+
+// cacheable records whether a method declared the result of a call cacheable.
+type cacheable struct {
+	number uint64      // Block the result derives from
+	hash   common.Hash // Hash of the block the result derives from
+	set    bool        // Whether the result was declared cacheable
+}
+
+// MarkCacheable declares the result of the method call in the context cacheable.
+// It may only be called if the result is fully determined by the parameters of
+// the call and the canonical chain up to the given block, so that it remains
+// valid until that block is reorganized out of the chain. It does nothing if the
+// server serving the call doesn't cache responses, or if the call fails.
+func MarkCacheable(ctx context.Context, number uint64, hash common.Hash) {
+	if c, ok := ctx.Value(cacheableKey{}).(*cacheable); ok {
+		c.number, c.hash, c.set = number, hash, true
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ccm-chain/ccmchain/common"
+)
+
+// cacheTestService is a service with a method declaring its results cacheable.
+type cacheTestService struct {
+	calls uint64
+}
+
+// Cacheable returns a distinct value on every call, declaring the result
+// cacheable as derived from the given block.
+func (s *cacheTestService) Cacheable(ctx context.Context, number uint64) uint64 {
+	MarkCacheable(ctx, number, cacheTestHash(number))
+	return atomic.AddUint64(&s.calls, 1)
+}
+
+// cacheTestHash is the hash of the block with the given number the results of
+// the cache test service derive from.
+func cacheTestHash(number uint64) common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(number + 1))
+}
+
+// Tests that the results of methods declared cacheable are served from the
+// cache, until the blocks they derive from are invalidated.
+func TestResponseCache(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	if err := server.RegisterName("cache", new(cacheTestService)); err != nil {
+		t.Fatalf("failed to register cache test service: %v", err)
+	}
+	cache := NewResponseCache(1024 * 1024)
+	server.SetResponseCache(cache)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	call := func(method string, args ...interface{}) uint64 {
+		t.Helper()
+
+		var result uint64
+		if err := client.Call(&result, method, args...); err != nil {
+			t.Fatalf("call to %s failed: %v", method, err)
+		}
+		return result
+	}
+	// Cacheable results are only computed once per method and parameters
+	first, second := call("cache_cacheable", 1), call("cache_cacheable", 2)
+	if first == second {
+		t.Fatalf("results of different parameters are identical")
+	}
+	if have := call("cache_cacheable", 1); have != first {
+		t.Errorf("cached result mismatch: have %d, want %d", have, first)
+	}
+	if have := call("cache_cacheable", 2); have != second {
+		t.Errorf("cached result mismatch: have %d, want %d", have, second)
+	}
+	// Results of methods not declaring them cacheable are not cached
+	call("test_noArgsRets")
+	if have := cache.Len(); have != 2 {
+		t.Errorf("cached response count mismatch: have %d, want %d", have, 2)
+	}
+	// Invalidating a block drops the results derived from it
+	cache.Invalidate(cacheTestHash(2))
+	if have := call("cache_cacheable", 1); have != first {
+		t.Errorf("cached result mismatch after invalidation: have %d, want %d", have, first)
+	}
+	if have := call("cache_cacheable", 2); have == second {
+		t.Errorf("invalidated result served from cache")
+	}
+}
+
+// Tests that results derived from side chain blocks are not cached, and that
+// cached results are not served once the block they derive from is no longer
+// canonical, even if the cache wasn't invalidated.
+func TestResponseCacheCanonical(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	if err := server.RegisterName("cache", new(cacheTestService)); err != nil {
+		t.Fatalf("failed to register cache test service: %v", err)
+	}
+	var (
+		lock  sync.Mutex
+		head  = uint64(2)
+		cache = NewResponseCache(1024 * 1024)
+	)
+	cache.SetCanonical(func(number uint64) common.Hash {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if number == 0 || number > head { // Results of block 0 derive from a side block
+			return common.Hash{}
+		}
+		return cacheTestHash(number)
+	})
+	server.SetResponseCache(cache)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	call := func(number uint64) uint64 {
+		t.Helper()
+
+		var result uint64
+		if err := client.Call(&result, "cache_cacheable", number); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+		return result
+	}
+	first, second := call(1), call(2)
+	if have := call(2); have != second {
+		t.Errorf("cached result mismatch: have %d, want %d", have, second)
+	}
+	// Side chain results are neither cached nor drop the canonical ones
+	if call(0) == call(0) {
+		t.Errorf("side chain result served from cache")
+	}
+	if have := cache.Len(); have != 2 {
+		t.Errorf("cached response count mismatch: have %d, want %d", have, 2)
+	}
+	// Rewind the chain without invalidating the cache
+	lock.Lock()
+	head = 1
+	lock.Unlock()
+
+	if have := call(2); have == second {
+		t.Errorf("result of rewound block served from cache")
+	}
+	if have := call(1); have != first {
+		t.Errorf("cached result mismatch after rewind: have %d, want %d", have, first)
+	}
+	if have := cache.Len(); have != 1 {
+		t.Errorf("cached response count mismatch after rewind: have %d, want %d", have, 1)
+	}
+}
+
+// Tests that the cache is bounded by its memory allowance, evicting the least
+// recently used responses first.
+func TestResponseCacheEviction(t *testing.T) {
+	result := json.RawMessage(`"0x01"`)
+	key1, key2, key3 := cacheKey("a", []byte(`[1]`)), cacheKey("a", []byte(`[ 2 ]`)), cacheKey("a", []byte(`[3]`))
+	if key2 != cacheKey("a", []byte(`[2]`)) {
+		t.Fatalf("cache key depends on parameter formatting")
+	}
+	entry := &cacheEntry{key: key1, result: result}
+
+	cache := NewResponseCache(2 * entry.size())
+	cache.add(key1, result, 1, common.Hash{}, cache.generation())
+	cache.add(key2, result, 1, common.Hash{}, cache.generation())
+	if _, ok := cache.get(key1); !ok {
+		t.Fatalf("cached response missing")
+	}
+	cache.add(key3, result, 1, common.Hash{}, cache.generation())
+	if _, ok := cache.get(key2); ok {
+		t.Errorf("least recently used response not evicted")
+	}
+	if _, ok := cache.get(key1); !ok {
+		t.Errorf("recently used response evicted")
+	}
+	// Results computed across invalidations are not cached
+	gen := cache.generation()
+	cache.Invalidate(common.Hash{})
+	cache.add(key2, result, 1, common.Hash{}, gen)
+	if _, ok := cache.get(key2); ok {
+		t.Errorf("result computed across invalidation cached")
+	}
+}
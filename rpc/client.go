@@ -77,6 +77,7 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	conn     *connConfig // settings of connections served by a server, nil for clients
 
 	idCounter uint32
 
@@ -109,9 +110,27 @@ type clientConn struct {
 	handler *handler
 }
 
+// connConfig contains the settings of the handlers of connections served by a
+// server, as opposed to the ones dialed by clients.
+type connConfig struct {
+	ctx    context.Context // Context of the connection, carrying the caller identity
+	access *accessControl  // Access policy enforced on the incoming calls
+	limits handlerLimits   // Limits of the incoming calls and batches
+	cache  *ResponseCache  // Cache of the results of the incoming calls
+}
+
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
-	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
+	ctx := context.Background()
+	if c.conn != nil {
+		ctx = c.conn.ctx
+	}
+	ctx = context.WithValue(ctx, clientContextKey{}, c)
 	handler := newHandler(ctx, conn, c.idgen, c.services)
+	if c.conn != nil {
+		handler.access = c.conn.access
+		handler.limits = c.conn.limits
+		handler.cache = c.conn.cache
+	}
 	return &clientConn{conn, handler}
 }
 
@@ -167,15 +186,29 @@ func Dial(rawurl string) (*Client, error) {
 // The context is used to cancel or time out the initial connection establishment. It does
 // not affect subsequent interactions with the client.
 func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	return DialOptions(ctx, rawurl)
+}
+
+// DialOptions creates a new RPC client for the given URL, configured with the
+// given options. Options which don't apply to the transport of the URL are
+// ignored.
+//
+// The context is used to cancel or time out the initial connection establishment. It does
+// not affect subsequent interactions with the client.
+func DialOptions(ctx context.Context, rawurl string, options ...ClientOption) (*Client, error) {
 	u, err := url.Parse(rawurl)
 	if err != nil {
 		return nil, err
 	}
+	cfg := new(clientConfig)
+	for _, opt := range options {
+		opt.applyOption(cfg)
+	}
 	switch u.Scheme {
 	case "http", "https":
-		return DialHTTP(rawurl)
+		return dialHTTP(rawurl, cfg)
 	case "ws", "wss":
-		return DialWebsocket(ctx, rawurl, "")
+		return dialWebsocket(ctx, rawurl, "", cfg)
 	case "stdio":
 		return DialStdIO(ctx)
 	case "":
@@ -197,17 +230,18 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), nil)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, cfg *connConfig) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		conn:        cfg,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
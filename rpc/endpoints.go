@@ -22,10 +22,16 @@ import (
 	"github.com/ccm-chain/ccmchain/log"
 )
 
-// StartIPCEndpoint starts an IPC endpoint.
-func StartIPCEndpoint(ipcEndpoint string, apis []API) (net.Listener, *Server, error) {
+// StartIPCEndpoint starts an IPC endpoint. If setup is not nil, it's called to
+// configure the server (e.g. its access policy and limits) before serving.
+func StartIPCEndpoint(ipcEndpoint string, apis []API, setup func(*Server) error) (net.Listener, *Server, error) {
 	// Register all the APIs exposed by the services.
 	handler := NewServer()
+	if setup != nil {
+		if err := setup(handler); err != nil {
+			return nil, nil, err
+		}
+	}
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return nil, nil, err
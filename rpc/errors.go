@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 var (
 	_ Error = new(methodNotFoundError)
@@ -25,6 +28,11 @@ var (
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
+	_ Error = new(accessDeniedError)
+	_ Error = new(rateLimitedError)
+	_ Error = new(batchTooLargeError)
+	_ Error = new(responseTooLargeError)
+	_ Error = new(timeoutError)
 )
 
 const defaultErrorCode = -32000
@@ -72,3 +80,51 @@ type invalidParamsError struct{ message string }
 func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
+
+// the access policy of the server denies calling the method
+type accessDeniedError struct{ method string }
+
+func (e *accessDeniedError) ErrorCode() int { return -32004 }
+
+func (e *accessDeniedError) Error() string {
+	return fmt.Sprintf("access to method %s denied", e.method)
+}
+
+// the caller or the method exceeded its rate limit
+type rateLimitedError struct{ method string }
+
+func (e *rateLimitedError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for method %s", e.method)
+}
+
+// the batch contains more calls than the server accepts
+type batchTooLargeError struct{ size, limit int }
+
+func (e *batchTooLargeError) ErrorCode() int { return -32600 }
+
+func (e *batchTooLargeError) Error() string {
+	return fmt.Sprintf("batch of %d messages exceeds limit of %d", e.size, e.limit)
+}
+
+// the results of the batch exceed the size limit, the remaining calls are skipped
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("batch response exceeds size limit of %d bytes", e.limit)
+}
+
+// the method didn't return within the call timeout of the server
+type timeoutError struct {
+	method  string
+	timeout time.Duration
+}
+
+func (e *timeoutError) ErrorCode() int { return -32002 }
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("method %s exceeded call timeout of %v", e.method, e.timeout)
+}
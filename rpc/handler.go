@@ -61,17 +61,32 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	access         *accessControl // access policy of served calls, nil if unrestricted
+	limits         handlerLimits  // limits of served calls and batches
+	cache          *ResponseCache // cache of the results of served calls, nil if uncached
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
 }
 
+// handlerLimits bounds the resources consumed by the calls served by a handler.
+// Zero values disable the respective limit.
+type handlerLimits struct {
+	batchItems        int           // Maximum number of messages in a batch
+	batchResponseSize int           // Maximum total size of the results of a batch
+	callTimeout       time.Duration // Maximum execution time of a method call
+}
+
 type callProc struct {
 	ctx       context.Context
 	notifiers []*Notifier
 }
 
 func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+	// Expose the remote address of persistent connections the same way as for HTTP
+	if connCtx.Value("remote") == nil && conn.remoteAddr() != "" {
+		connCtx = context.WithValue(connCtx, "remote", conn.remoteAddr())
+	}
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -102,6 +117,14 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		return
 	}
 
+	// Reject oversized batches as a whole, answering all calls with an error:
+	if h.limits.batchItems > 0 && len(msgs) > h.limits.batchItems {
+		rpcBatchTooLargeMeter.Mark(1)
+		h.startCallProc(func(cp *callProc) {
+			h.conn.writeJSON(cp.ctx, batchErrorResponses(msgs, &batchTooLargeError{len(msgs), h.limits.batchItems}))
+		})
+		return
+	}
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
 	for _, msg := range msgs {
@@ -114,11 +137,31 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
-		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
-				answers = append(answers, answer)
+		var (
+			answers = make([]*jsonrpcMessage, 0, len(msgs))
+			size    int
+		)
+		for i, msg := range calls {
+			answer := h.handleCallMsg(cp, msg)
+			if answer == nil {
+				continue
 			}
+			// Stop executing the batch once its results grow too large, answering
+			// the offending call and the remaining ones with an error
+			if limit := h.limits.batchResponseSize; limit > 0 {
+				if size += len(answer.Result); size > limit {
+					rpcTruncatedMeter.Mark(1)
+					err := &responseTooLargeError{limit}
+					answers = append(answers, msg.errorResponse(err))
+					for _, msg := range calls[i+1:] {
+						if msg.isCall() {
+							answers = append(answers, msg.errorResponse(err))
+						}
+					}
+					break
+				}
+			}
+			answers = append(answers, answer)
 		}
 		h.addSubscriptions(cp.notifiers)
 		if len(answers) > 0 {
@@ -130,6 +173,21 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	})
 }
 
+// batchErrorResponses answers all calls of a batch with the given error. If the
+// batch doesn't contain any calls, a single error without ID is returned.
+func batchErrorResponses(msgs []*jsonrpcMessage, err error) []*jsonrpcMessage {
+	answers := make([]*jsonrpcMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.isCall() {
+			answers = append(answers, msg.errorResponse(err))
+		}
+	}
+	if len(answers) == 0 {
+		answers = append(answers, errorMessage(err))
+	}
+	return answers
+}
+
 // handleMsg handles a single message.
 func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	if ok := h.handleImmediate(msg); ok {
@@ -329,12 +387,22 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if callb == nil {
 		return msg.errorResponse(&methodNotFoundError{method: msg.Method})
 	}
+	if callb != h.unsubscribeCb {
+		if err := h.access.check(cp.ctx, msg.Method); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	args, err := parsePositionalArguments(msg.Params, callb.argTypes)
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args)
+	var answer *jsonrpcMessage
+	if h.cache != nil && callb != h.unsubscribeCb {
+		answer = h.runMethodCached(cp.ctx, msg, callb, args)
+	} else {
+		answer = h.runMethodTimeout(cp.ctx, msg, callb, args)
+	}
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -367,6 +435,9 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	if callb == nil {
 		return msg.errorResponse(&subscriptionNotFoundError{namespace, name})
 	}
+	if err := h.access.check(cp.ctx, msg.Method); err != nil {
+		return msg.errorResponse(err)
+	}
 
 	// Parse subscription name arg too, but remove it before calling the callback.
 	argTypes := append([]reflect.Type{stringType}, callb.argTypes...)
@@ -393,6 +464,51 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	return msg.response(result)
 }
 
+// runMethodTimeout runs the Go callback for an RPC method like runMethod, but
+// answers with an error if it doesn't return within the call timeout. The context
+// of the method is cancelled on timeout, yet it keeps running until it notices.
+func (h *handler) runMethodTimeout(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
+	if h.limits.callTimeout == 0 {
+		return h.runMethod(ctx, msg, callb, args)
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.limits.callTimeout)
+	defer cancel()
+
+	answerCh := make(chan *jsonrpcMessage, 1)
+	go func() {
+		answerCh <- h.runMethod(ctx, msg, callb, args)
+	}()
+	select {
+	case answer := <-answerCh:
+		return answer
+	case <-ctx.Done():
+		if ctx.Err() != context.DeadlineExceeded {
+			return msg.errorResponse(ctx.Err())
+		}
+		rpcTimeoutMeter.Mark(1)
+		return msg.errorResponse(&timeoutError{method: msg.Method, timeout: h.limits.callTimeout})
+	}
+}
+
+// runMethodCached runs the Go callback for an RPC method like runMethodTimeout,
+// unless its result is cached. The result is added to the cache if the method
+// declares it cacheable.
+func (h *handler) runMethodCached(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
+	key := cacheKey(msg.Method, msg.Params)
+	if result, ok := h.cache.get(key); ok {
+		return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: result}
+	}
+	var (
+		mark = new(cacheable)
+		gen  = h.cache.generation()
+	)
+	answer := h.runMethodTimeout(context.WithValue(ctx, cacheableKey{}, mark), msg, callb, args)
+	if answer.Error == nil && mark.set {
+		h.cache.add(key, answer.Result, mark.number, mark.hash, gen)
+	}
+	return answer
+}
+
 // unsubscribe is the callback function for all *_unsubscribe calls.
 func (h *handler) unsubscribe(ctx context.Context, id ID) (bool, error) {
 	h.subLock.Lock()
@@ -27,6 +27,11 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedReqeustGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	rpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+	rpcDeniedMeter         = metrics.NewRegisteredMeter("rpc/rejected/denied", nil)
+	rpcRateLimitedMeter    = metrics.NewRegisteredMeter("rpc/rejected/ratelimited", nil)
+	rpcBatchTooLargeMeter  = metrics.NewRegisteredMeter("rpc/rejected/batchsize", nil)
+	rpcTruncatedMeter      = metrics.NewRegisteredMeter("rpc/rejected/responsesize", nil)
+	rpcTimeoutMeter        = metrics.NewRegisteredMeter("rpc/rejected/timeout", nil)
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
@@ -37,3 +42,10 @@ func newRPCServingTimer(method string, valid bool) metrics.Timer {
 	m := fmt.Sprintf("rpc/duration/%s/%s", method, flag)
 	return metrics.GetOrRegisterTimer(m, nil)
 }
+
+// newRPCRejectionMeter returns the meter of the calls to a method rejected by the
+// access policy for the given reason.
+func newRPCRejectionMeter(method string, reason string) metrics.Meter {
+	m := fmt.Sprintf("rpc/rejected/%s/%s", method, reason)
+	return metrics.GetOrRegisterMeter(m, nil)
+}
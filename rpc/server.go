@@ -20,6 +20,7 @@ import (
 	"context"
 	"io"
 	"sync/atomic"
+	"time"
 
 	"github.com/ccm-chain/ccmchain/log"
 	mapset "github.com/deckarep/golang-set"
@@ -46,11 +47,16 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	access   *accessControl
+	limits   handlerLimits
+	cache    *ResponseCache
+
+	requestSizeLimit int // Maximum size of HTTP request bodies and WebSocket messages
 }
 
 // NewServer creates a new server instance with no registered handlers.
 func NewServer() *Server {
-	server := &Server{idgen: randomIDGenerator(), codecs: mapset.NewSet(), run: 1}
+	server := &Server{idgen: randomIDGenerator(), codecs: mapset.NewSet(), run: 1, requestSizeLimit: defaultRequestSizeLimit}
 	// Register the default service providing meta information about the RPC service such
 	// as the services and methods it offers.
 	rpcService := &RPCService{server}
@@ -66,12 +72,61 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetAccessPolicy restricts the methods callable on the server and the rate at
+// which they may be called. It must be called before the server starts serving.
+func (s *Server) SetAccessPolicy(policy *AccessPolicy) error {
+	access, err := newAccessControl(policy)
+	if err != nil {
+		return err
+	}
+	s.access = access
+	return nil
+}
+
+// SetBatchLimits limits the number of messages in a JSON-RPC batch and the total
+// size in bytes of the results of its calls. Oversized batches are rejected, with
+// an error answering each of their calls. Once the results of a batch exceed the
+// size limit, its remaining calls are answered with an error without being
+// executed. Zero values disable the respective limit. It must be called before
+// the server starts serving.
+func (s *Server) SetBatchLimits(itemLimit, responseSizeLimit int) {
+	s.limits.batchItems = itemLimit
+	s.limits.batchResponseSize = responseSizeLimit
+}
+
+// SetCallTimeout limits the time spent executing a method call, answering calls
+// exceeding it with an error. Zero disables the limit. It must be called before
+// the server starts serving.
+func (s *Server) SetCallTimeout(timeout time.Duration) {
+	s.limits.callTimeout = timeout
+}
+
+// SetRequestSizeLimit limits the size in bytes of the request bodies served over
+// HTTP and of the messages received over WebSocket. It must be called before the
+// server starts serving.
+func (s *Server) SetRequestSizeLimit(limit int) {
+	s.requestSizeLimit = limit
+}
+
+// SetResponseCache caches the results of the calls served by the server, which
+// their methods declare cacheable. It must be called before the server starts
+// serving.
+func (s *Server) SetResponseCache(cache *ResponseCache) {
+	s.cache = cache
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec)
+}
+
+// serveCodec serves a codec like ServeCodec, deriving the context of the calls
+// from the given connection context.
+func (s *Server) serveCodec(ctx context.Context, codec ServerCodec) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -83,7 +138,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, &connConfig{ctx: ctx, access: s.access, limits: s.limits, cache: s.cache})
 	<-codec.closed()
 	c.Close()
 }
@@ -99,6 +154,9 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 
 	h := newHandler(ctx, codec, s.idgen, &s.services)
 	h.allowSubscribe = false
+	h.access = s.access
+	h.limits = s.limits
+	h.cache = s.cache
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -22,6 +22,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"net/http/httptest"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -150,3 +151,93 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+// Tests that oversized batches are rejected, and that batches with oversized
+// results are truncated, over both persistent connections and HTTP.
+func TestServerBatchLimits(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetBatchLimits(4, 300)
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	clients := map[string]*Client{"inproc": DialInProc(server)}
+	httpclient, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatalf("failed to dial HTTP: %v", err)
+	}
+	clients["http"] = httpclient
+
+	arg := strings.Repeat("x", 100)
+	for name, client := range clients {
+		// Batches within the item limit are served, until their results get too large
+		batch := make([]BatchElem, 4)
+		for i := range batch {
+			batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{arg, i}, Result: new(echoResult)}
+		}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("%s: batch call failed: %v", name, err)
+		}
+		for i, elem := range batch {
+			code := 0
+			if i >= 2 {
+				code = -32003
+			}
+			if have := errorCode(t, elem.Error); have != code {
+				t.Errorf("%s: batch element %d: error code mismatch: have %d, want %d (%v)", name, i, have, code, elem.Error)
+			}
+		}
+		// Batches exceeding the item limit are rejected as a whole
+		batch = append(batch, BatchElem{Method: "test_noArgsRets"})
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("%s: batch call failed: %v", name, err)
+		}
+		for i, elem := range batch {
+			if have := errorCode(t, elem.Error); have != -32600 {
+				t.Errorf("%s: oversized batch element %d: error code mismatch: have %d, want %d (%v)", name, i, have, -32600, elem.Error)
+			}
+		}
+		client.Close()
+	}
+}
+
+// Tests that calls exceeding the call timeout are answered with an error.
+func TestServerCallTimeout(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetCallTimeout(50 * time.Millisecond)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	if err := client.Call(nil, "test_sleep", time.Millisecond); err != nil {
+		t.Fatalf("short call failed: %v", err)
+	}
+	err := client.Call(nil, "test_block")
+	if have := errorCode(t, err); have != -32002 {
+		t.Fatalf("timed out call error code mismatch: have %d, want %d (%v)", have, -32002, err)
+	}
+}
+
+// Tests that HTTP requests larger than the request size limit are refused.
+func TestServerRequestSizeLimit(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetRequestSizeLimit(1024)
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatalf("failed to dial HTTP: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Call(nil, "test_echo", strings.Repeat("x", 512), 1); err != nil {
+		t.Fatalf("small request failed: %v", err)
+	}
+	if err := client.Call(nil, "test_echo", strings.Repeat("x", 2048), 1); err == nil {
+		t.Fatalf("oversized request served")
+	}
+}
@@ -58,8 +58,14 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			log.Debug("WebSocket upgrade failed", "err", err)
 			return
 		}
+		// Carry the authenticated identity of the caller over to the connection
+		ctx := context.Background()
+		if identity, ok := IdentityFromContext(r.Context()); ok {
+			ctx = WithIdentity(ctx, identity)
+		}
 		codec := newWebsocketCodec(conn)
-		s.ServeCodec(codec, 0)
+		conn.SetReadLimit(int64(s.requestSizeLimit))
+		s.serveCodec(ctx, codec)
 	})
 }
 
@@ -123,11 +129,43 @@ func (e wsHandshakeError) Error() string {
 // DialWebsocketWithDialer creates a new RPC client that communicates with a JSON-RPC server
 // that is listening on the given endpoint using the provided dialer.
 func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, dialer websocket.Dialer) (*Client, error) {
+	return dialWebsocket(ctx, endpoint, origin, &clientConfig{wsDialer: &dialer})
+}
+
+// DialWebsocket creates a new RPC client that communicates with a JSON-RPC server
+// that is listening on the given endpoint.
+//
+// The context is used for the initial connection establishment. It does not
+// affect subsequent interactions with the client.
+func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) {
+	return dialWebsocket(ctx, endpoint, origin, new(clientConfig))
+}
+
+// dialWebsocket creates a new RPC client that communicates with a JSON-RPC server
+// over WebSocket, configured with the given client options.
+func dialWebsocket(ctx context.Context, endpoint, origin string, cfg *clientConfig) (*Client, error) {
+	dialer := cfg.wsDialer
+	if dialer == nil {
+		dialer = &websocket.Dialer{
+			ReadBufferSize:  wsReadBuffer,
+			WriteBufferSize: wsWriteBuffer,
+			WriteBufferPool: wsBufferPool,
+		}
+	}
 	endpoint, header, err := wsClientHeaders(endpoint, origin)
 	if err != nil {
 		return nil, err
 	}
+	for key, values := range cfg.httpHeaders {
+		header[key] = values
+	}
 	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		header := header.Clone()
+		if cfg.httpAuth != nil {
+			if err := cfg.httpAuth(header); err != nil {
+				return nil, err
+			}
+		}
 		conn, resp, err := dialer.DialContext(ctx, endpoint, header)
 		if err != nil {
 			hErr := wsHandshakeError{err: err}
@@ -140,20 +178,6 @@ func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, diale
 	})
 }
 
-// DialWebsocket creates a new RPC client that communicates with a JSON-RPC server
-// that is listening on the given endpoint.
-//
-// The context is used for the initial connection establishment. It does not
-// affect subsequent interactions with the client.
-func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) {
-	dialer := websocket.Dialer{
-		ReadBufferSize:  wsReadBuffer,
-		WriteBufferSize: wsWriteBuffer,
-		WriteBufferPool: wsBufferPool,
-	}
-	return DialWebsocketWithDialer(ctx, endpoint, origin, dialer)
-}
-
 func wsClientHeaders(endpoint, origin string) (string, http.Header, error) {
 	endpointURL, err := url.Parse(endpoint)
 	if err != nil {
@@ -180,12 +204,13 @@ type websocketCodec struct {
 }
 
 func newWebsocketCodec(conn *websocket.Conn) ServerCodec {
-	conn.SetReadLimit(maxRequestContentLength)
+	conn.SetReadLimit(defaultRequestSizeLimit)
 	wc := &websocketCodec{
 		jsonCodec: NewFuncCodec(conn, conn.WriteJSON, conn.ReadJSON).(*jsonCodec),
 		conn:      conn,
 		pingReset: make(chan struct{}, 1),
 	}
+	wc.jsonCodec.remote = conn.RemoteAddr().String()
 	wc.wg.Add(1)
 	go wc.pingLoop()
 	return wc
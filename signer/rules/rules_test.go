@@ -625,3 +625,38 @@ function ApproveSignData(r){
 		t.Fatalf("Expected approved")
 	}
 }
+
+func TestSignCliqueHeader(t *testing.T) {
+	js := `function ApproveSignData(r){
+    if (r.content_type == "application/x-clique-header" &&
+        r.address.toLowerCase() == "0x694267f14675d7e1b9494fd8d72fefe1755710fa") {
+        return "Approve"
+    }
+    return "Reject"
+}`
+	r, err := initRuleEngine(js)
+	if err != nil {
+		t.Fatalf("Couldn't create evaluator %v", err)
+	}
+	addr, _ := mixAddr("0x694267f14675d7e1b9494fd8d72fefe1755710fa")
+	for _, tt := range []struct {
+		contentType string
+		approved    bool
+	}{
+		{accounts.MimetypeClique, true},
+		{accounts.MimetypeTextPlain, false},
+		{accounts.MimetypeTypedData, false},
+	} {
+		resp, err := r.ApproveSignData(&core.SignDataRequest{
+			ContentType: tt.contentType,
+			Address:     *addr,
+			Meta:        core.Metadata{Remote: "remoteip", Local: "localip", Scheme: "inproc"},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if resp.Approved != tt.approved {
+			t.Errorf("content type %s: approval mismatch: have %v, want %v", tt.contentType, resp.Approved, tt.approved)
+		}
+	}
+}